@@ -11,43 +11,122 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/graphql-go/handler"
 	"github.com/raimundocoelho-ti/sabiosystem-api/config"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/auth"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/backup"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/agent"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/category"
-	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/product" // <-- ADICIONADO
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/product"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/user"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/events"
 	gql "github.com/raimundocoelho-ti/sabiosystem-api/internal/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/imaging"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/persisted"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/storage"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/trash"
 )
 
 func main() {
 	config.LoadConfig()
 	database.ConnectDB()
+	database.Migrate(
+		&agent.Agent{}, &user.User{}, &category.Category{}, &product.Product{},
+		&plan.Plan{}, &plan.StorageUsage{}, &backup.Job{}, &persisted.Query{},
+		&asset.Asset{}, &asset.AssetUsage{}, &product.ProductImage{},
+		&product.OptionGroup{}, &product.Option{}, &product.Variant{}, &product.StockMovement{},
+	)
+	database.MigrateSQL(product.SearchMigrations...)
+	database.MigrateSQL(product.GalleryMigrations...)
+	database.MigrateSQL(product.PriceMigrations...)
+	database.MigrateSQL(product.CodeMigrations...)
+
+	// Armazenamento dos arquivos enviados, escolhido em STORAGE_DRIVER
+	fileStore, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Falha ao configurar o armazenamento de arquivos: %v", err)
+	}
+
+	// Tamanho máximo dos arquivos enviados (multipart, /upload e envio direto)
+	maxUploadBytes, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_BYTES"), 10, 64)
+	if err != nil || maxUploadBytes <= 0 {
+		maxUploadBytes = 10 << 20
+	}
+	// Maior lado aceito nas imagens enviadas, em pixels
+	maxImageSide, err := strconv.Atoi(os.Getenv("IMAGE_MAX_DIMENSION"))
+	if err != nil || maxImageSide <= 0 {
+		maxImageSide = 8000
+	}
+	imaging.SetLimits(maxUploadBytes, maxImageSide)
 
 	// 1. Instanciar todos os repositórios e serviços
+	eventBus := events.NewBus() // Mudanças no catálogo, entregues pelas subscriptions
+	planRepo := plan.NewRepository(database.DB)
+	planService := plan.NewService(planRepo)
+	assetService := asset.NewService(asset.NewRepository(database.DB), planService, fileStore, maxUploadBytes)
 	categoryRepo := category.NewRepository(database.DB)
-	categoryService := category.NewService(categoryRepo)
-	productRepo := product.NewRepository(database.DB) // <-- ADICIONADO
-	productService := product.NewService(productRepo) // <-- ADICIONADO
+	categoryService := category.NewService(categoryRepo, planService, eventBus, assetService)
+	productRepo := product.NewRepository(database.DB)
+	productService := product.NewService(productRepo, planService, eventBus, assetService, categoryService)
 	userRepo := user.NewRepository(database.DB)
-	userService := user.NewService(userRepo)
+	userService := user.NewService(userRepo, planService)
 	agentRepo := agent.NewRepository(database.DB)
-	agentService := agent.NewService(agentRepo)
+	// Ao remover um agente, os dados saem na ordem: registros, assets e, por último, o contador de armazenamento.
+	agentService := agent.NewService(agentRepo, productService, categoryService, userService, assetService, planService)
 	authRepo := auth.NewRepository(database.DB)
 	authService := auth.NewService(authRepo)
+	backupDir := os.Getenv("BACKUP_DIR")
+	if backupDir == "" {
+		backupDir = "backups"
+	}
+	backupService := backup.NewService(backup.NewRepository(database.DB), backupDir)
+
+	// Limpeza periódica da lixeira (soft delete)
+	retentionDays, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || retentionDays <= 0 {
+		retentionDays = 30
+	}
+	trash.StartPurgeJob(time.Duration(retentionDays)*24*time.Hour, time.Hour, map[string]trash.Purger{
+		"products":   productService,
+		"categories": categoryService,
+		"users":      userService,
+		"agents":     agentService,
+	})
+
+	// Coleta dos assets da biblioteca de mídia que ficaram sem uso
+	assetRetentionDays, err := strconv.Atoi(os.Getenv("ASSET_RETENTION_DAYS"))
+	if err != nil || assetRetentionDays <= 0 {
+		assetRetentionDays = 7
+	}
+	trash.StartPurgeJob(time.Duration(assetRetentionDays)*24*time.Hour, time.Hour, map[string]trash.Purger{
+		"assets": assetService,
+	})
+
+	// Tamanho de página das listas: padrão e máximo que o cliente pode pedir em first/last
+	defaultPageSize, _ := strconv.Atoi(os.Getenv("PAGE_SIZE_DEFAULT"))
+	maxPageSize, _ := strconv.Atoi(os.Getenv("PAGE_SIZE_MAX"))
+	pagination.SetLimits(defaultPageSize, maxPageSize)
 
 	// 2. Agrupar todos os serviços para o montador de schema
 	schemaServices := gql.SchemaServices{
 		CategorySvc: categoryService,
-		ProductSvc:  productService, // <-- ADICIONADO
+		ProductSvc:  productService,
 		UserSvc:     userService,
 		AgentSvc:    agentService,
 		AuthSvc:     authService,
+		PlanSvc:     planService,
+		BackupSvc:   backupService,
+		AssetSvc:    assetService,
+		Events:      eventBus,
 	}
 
 	// 3. Criar o schema a partir dos serviços agrupados
@@ -56,19 +135,60 @@ func main() {
 		log.Fatalf("Falha ao criar o schema GraphQL: %v", err)
 	}
 
+	// Em produção o playground e a introspecção ficam desligados, salvo se pedidos explicitamente
+	production := os.Getenv("APP_ENV") == "production"
+	playground, err := strconv.ParseBool(os.Getenv("GRAPHQL_PLAYGROUND"))
+	if err != nil {
+		playground = !production
+	}
+	introspection, err := strconv.ParseBool(os.Getenv("GRAPHQL_INTROSPECTION"))
+	if err != nil {
+		introspection = !production
+	}
+	maxDepth, err := strconv.Atoi(os.Getenv("GRAPHQL_MAX_DEPTH"))
+	if err != nil || maxDepth <= 0 {
+		maxDepth = 10
+	}
+	maxCost, err := strconv.Atoi(os.Getenv("GRAPHQL_MAX_COST"))
+	if err != nil || maxCost <= 0 {
+		maxCost = 5000
+	}
+	limits := gql.Limits{
+		MaxDepth:        maxDepth,
+		MaxCost:         maxCost,
+		DefaultListSize: pagination.DefaultSize(),
+		Introspection:   introspection,
+	}
+
+	// Operações persistidas dos aplicativos; em modo estrito só o manifesto pode ser executado
+	strictQueries, _ := strconv.ParseBool(os.Getenv("PERSISTED_QUERIES_STRICT"))
+	persistedService := persisted.NewService(persisted.NewRepository(database.DB), strictQueries)
+	if manifest := os.Getenv("PERSISTED_QUERIES_MANIFEST"); manifest != "" {
+		loaded, err := persistedService.LoadManifest(manifest)
+		if err != nil {
+			log.Fatalf("Falha ao carregar o manifesto de operações: %v", err)
+		}
+		log.Printf("%d operações carregadas do manifesto %s", loaded, manifest)
+	}
+
 	// 4. Criar o handler GraphQL
 	gqlHandler := handler.New(&handler.Config{
 		Schema:     &schema,
-		Pretty:     true,
-		Playground: true,
+		Pretty:     !production,
+		Playground: playground && introspection,
 	})
 
 	// 5. Iniciar e configurar o Fiber
-	app := fiber.New()
+	app := fiber.New(fiber.Config{BodyLimit: int(maxUploadBytes) + 1<<20})
 	app.Use(logger.New())
-	app.All("/graphql", adaptor.HTTPHandler(gqlHandler))
+	app.Get("/graphql", gql.SubscriptionsHandler(&schema, limits, persistedService))
+	app.All("/graphql", adaptor.HTTPHandler(gql.RequestMiddleware(auth.Middleware(gql.MultipartMiddleware(maxUploadBytes)(gql.PersistedQueriesMiddleware(persistedService, &schema, limits)(gql.LimitsMiddleware(&schema, limits)(gql.RawVariablesMiddleware(gql.LoadersMiddleware(schemaServices)(gqlHandler)))))))))
 
-	app.Post("/upload", product.UploadImageHandler)
+	app.Post("/upload", auth.FiberMiddleware, product.NewUploadImageHandler(assetService))
+	if local, ok := fileStore.(*storage.Local); ok {
+		app.Put(storage.LocalRoute+"/*", adaptor.HTTPHandler(local.UploadHandler()))
+		app.Static(storage.LocalRoute, local.Dir())
+	}
 
 	port := os.Getenv("API_PORT")
 	if port == "" {
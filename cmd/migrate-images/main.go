@@ -0,0 +1,48 @@
+/*
+|------------------------------------------------
+| File: cmd/migrate-images/main.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/config"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/product"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/storage"
+)
+
+// Move as imagens de produtos gravadas em products/... para agents/<agente>/products/...,
+// reescreve as URLs no banco e registra as imagens na biblioteca de mídia. Usa o mesmo
+// STORAGE_DRIVER da API, que deve ter rodado antes ao menos uma vez para criar as colunas
+// e tabelas novas.
+//
+// Uso: migrate-images [-dry-run] [-batch 500]
+func main() {
+	dryRun := flag.Bool("dry-run", false, "só lista o que seria copiado, reescrito e apagado")
+	batch := flag.Int("batch", 500, "produtos lidos por consulta")
+	flag.Parse()
+
+	config.LoadConfig()
+	database.ConnectDB()
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Falha ao configurar o armazenamento: %v", err)
+	}
+
+	assets := asset.NewService(asset.NewRepository(database.DB), plan.NewService(plan.NewRepository(database.DB)), store, 0)
+	migration := product.NewImageMigration(product.NewRepository(database.DB), assets, store, *dryRun)
+	result, err := migration.Run(*batch)
+	if err != nil {
+		log.Fatalf("Falha ao migrar as imagens: %v", err)
+	}
+	log.Printf("%d produtos reescritos, %d objetos copiados, %d apagados, %d ligados a assets, %d produtos com erro",
+		result.Products, result.Copied, result.Deleted, result.Adopted, result.Failed)
+}
@@ -0,0 +1,153 @@
+/*
+|------------------------------------------------
+| File: internal/apperror/apperror.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package apperror
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Códigos enviados em "extensions.code". Os aplicativos devem decidir pelo código,
+// nunca pelo texto da mensagem, que muda com o idioma.
+const (
+	CodeNotFound        = "NOT_FOUND"
+	CodeValidation      = "VALIDATION"
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	CodeForbidden       = "FORBIDDEN"
+	CodeConflict        = "CONFLICT"
+	CodeQuotaExceeded   = "QUOTA_EXCEEDED"
+	CodeInternal        = "INTERNAL"
+	CodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+
+	CodePersistedQueryNotFound = "PERSISTED_QUERY_NOT_FOUND"
+)
+
+// ErrVersionMismatch é retornado pelos repositórios quando o UPDATE condicionado à versão
+// não encontra a linha, ou seja, outra pessoa salvou o registro antes.
+var ErrVersionMismatch = errors.New("versão do registro desatualizada")
+
+// Error é um erro que pode ser mostrado ao cliente. A mensagem vem do catálogo
+// (messages.go) e é traduzida por Localize.
+type Error struct {
+	Code    string
+	Key     string
+	Args    []interface{}
+	Fields  []FieldError
+	Details map[string]interface{} // Vão para "extensions" junto com o código.
+	cause   error
+	lang    Language
+}
+
+// FieldError descreve o problema de um campo específico da entrada.
+type FieldError struct {
+	Field string
+	Key   string
+	Args  []interface{}
+}
+
+// New cria um erro com o código e a mensagem do catálogo.
+func New(code, key string, args ...interface{}) *Error {
+	return &Error{Code: code, Key: key, Args: args}
+}
+
+func NotFound() *Error {
+	return New(CodeNotFound, MsgNotFound)
+}
+
+// Validation reúne os erros de todos os campos inválidos.
+func Validation(fields ...FieldError) *Error {
+	return &Error{Code: CodeValidation, Key: MsgValidation, Fields: fields}
+}
+
+// Invalid cria um erro de validação de um único campo.
+func Invalid(field, key string, args ...interface{}) *Error {
+	return Validation(FieldError{Field: field, Key: key, Args: args})
+}
+
+// Conflict indica que o registro mudou desde que o cliente o leu. current é o estado
+// atual, para o cliente decidir como mesclar.
+func Conflict(current interface{}) *Error {
+	err := New(CodeConflict, MsgConflict)
+	err.Details = map[string]interface{}{"current": current}
+	return err
+}
+
+// QuotaExceeded indica que a operação ultrapassaria o limite do plano do agente.
+func QuotaExceeded(resource string, limit, used int64) *Error {
+	err := New(CodeQuotaExceeded, MsgQuotaExceeded, resource, used, limit)
+	err.Details = map[string]interface{}{"resource": resource, "limit": limit, "used": used}
+	return err
+}
+
+// Internal encapsula um erro inesperado. A causa só vai para o log, nunca ao cliente.
+func Internal(cause error) *Error {
+	return &Error{Code: CodeInternal, Key: MsgInternal, cause: cause}
+}
+
+// From converte qualquer erro num *Error. Erros conhecidos ganham o código adequado;
+// os demais viram INTERNAL.
+func From(err error) *Error {
+	var appErr *Error
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound().WithCause(err)
+	default:
+		return Internal(err)
+	}
+}
+
+// HasCode informa se err é um *Error com o código informado.
+func HasCode(err error, code string) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Code == code
+}
+
+func (e *Error) Error() string {
+	return Translate(e.lang, e.Key, e.Args...)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// WithCause guarda o erro original, para log e errors.Is.
+func (e *Error) WithCause(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+// Localize retorna uma cópia do erro com as mensagens no idioma informado.
+func (e *Error) Localize(lang Language) *Error {
+	copied := *e
+	copied.lang = lang
+	return &copied
+}
+
+// Extensions expõe o código, os detalhes e os erros por campo para o GraphQL.
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	for key, value := range e.Details {
+		extensions[key] = value
+	}
+	if len(e.Fields) > 0 {
+		fields := make([]map[string]interface{}, 0, len(e.Fields))
+		for _, field := range e.Fields {
+			fields = append(fields, map[string]interface{}{
+				"field":   field.Field,
+				"code":    field.Key,
+				"message": Translate(e.lang, field.Key, field.Args...),
+			})
+		}
+		extensions["fields"] = fields
+	}
+	return extensions
+}
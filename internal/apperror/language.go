@@ -0,0 +1,70 @@
+/*
+|------------------------------------------------
+| File: internal/apperror/language.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package apperror
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Language é um idioma com mensagens no catálogo.
+type Language string
+
+const (
+	PtBR Language = "pt-BR"
+	En   Language = "en"
+)
+
+// DefaultLanguage é usado quando o cliente não pede nenhum idioma suportado.
+const DefaultLanguage = PtBR
+
+// ParseAcceptLanguage escolhe, pelo cabeçalho Accept-Language, o idioma suportado de
+// maior preferência. Qualquer variante de português ou inglês é aceita.
+func ParseAcceptLanguage(header string) Language {
+	best, bestQuality := DefaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		var lang Language
+		switch primary, _, _ := strings.Cut(strings.ToLower(tag), "-"); primary {
+		case "pt":
+			lang = PtBR
+		case "en":
+			lang = En
+		default:
+			continue
+		}
+		if quality > bestQuality {
+			best, bestQuality = lang, quality
+		}
+	}
+	return best
+}
+
+type languageKey struct{}
+
+// WithLanguage retorna um contexto carregando o idioma da requisição.
+func WithLanguage(ctx context.Context, lang Language) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// LanguageFrom retorna o idioma da requisição, ou DefaultLanguage.
+func LanguageFrom(ctx context.Context) Language {
+	if ctx != nil {
+		if lang, ok := ctx.Value(languageKey{}).(Language); ok {
+			return lang
+		}
+	}
+	return DefaultLanguage
+}
@@ -0,0 +1,339 @@
+/*
+|------------------------------------------------
+| File: internal/apperror/messages.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package apperror
+
+import "fmt"
+
+// Chaves do catálogo de mensagens. A chave também é enviada como código dos erros por campo.
+const (
+	MsgNotFound               = "not_found"
+	MsgValidation             = "validation"
+	MsgUnauthenticated        = "unauthenticated"
+	MsgForbidden              = "forbidden"
+	MsgConflict               = "conflict"
+	MsgQuotaExceeded          = "quota_exceeded"
+	MsgInternal               = "internal"
+	MsgInvalidCredentials     = "invalid_credentials"
+	MsgInvalidAgent           = "invalid_agent"
+	MsgInvalidRefreshToken    = "invalid_refresh_token"
+	MsgFieldNotNull           = "field_not_null"
+	MsgInvalidDate            = "invalid_date"
+	MsgInvalidCursor          = "invalid_cursor"
+	MsgFirstAndLast           = "first_and_last"
+	MsgPageSize               = "page_size"
+	MsgEmptySearch            = "empty_search"
+	MsgBackupSourceJob        = "backup_source_job"
+	MsgRequired               = "required"
+	MsgMaxLength              = "max_length"
+	MsgMinLength              = "min_length"
+	MsgInvalidURL             = "invalid_url"
+	MsgInvalidEmail           = "invalid_email"
+	MsgInvalidDomain          = "invalid_domain"
+	MsgPositiveAmount         = "positive_amount"
+	MsgNonNegative            = "non_negative"
+	MsgOneOf                  = "one_of"
+	MsgMaxItems               = "max_items"
+	MsgQueryTooDeep           = "query_too_deep"
+	MsgQueryTooCostly         = "query_too_costly"
+	MsgIntrospectionDisabled  = "introspection_disabled"
+	MsgPersistedQueryNotFound = "persisted_query_not_found"
+	MsgPersistedQueryVersion  = "persisted_query_version"
+	MsgPersistedQueryHash     = "persisted_query_hash"
+	MsgOperationNotAllowed    = "operation_not_allowed"
+	MsgInvalidMultipart       = "invalid_multipart"
+	MsgUploadTooLarge         = "upload_too_large"
+	MsgUnsupportedImage       = "unsupported_image"
+	MsgImageDimensions        = "image_dimensions"
+	MsgEmptyUpload            = "empty_upload"
+	MsgUploadNotFound         = "upload_not_found"
+	MsgInvalidUploadKey       = "invalid_upload_key"
+	MsgDirectUploadDisabled   = "direct_upload_disabled"
+	MsgAssetInUse             = "asset_in_use"
+	MsgExclusiveWith          = "exclusive_with"
+	MsgImageInGallery         = "image_in_gallery"
+	MsgGalleryOrder           = "gallery_order"
+	MsgProductHasGallery      = "product_has_gallery"
+	MsgInvalidMoney           = "invalid_money"
+	MsgUnknownCurrency        = "unknown_currency"
+	MsgMoneyDecimals          = "money_decimals"
+	MsgCurrencyMismatch       = "currency_mismatch"
+	MsgSelectionRange         = "selection_range"
+	MsgSingleSelection        = "single_selection"
+	MsgVariantRequired        = "variant_required"
+	MsgInvalidChoice          = "invalid_choice"
+	MsgSelectionMin           = "selection_min"
+	MsgSelectionMax           = "selection_max"
+	MsgInsufficientStock      = "insufficient_stock"
+	MsgStockNotTracked        = "stock_not_tracked"
+	MsgInvalidBarcode         = "invalid_barcode"
+	MsgSKUTaken               = "sku_taken"
+)
+
+var messages = map[string]map[Language]string{
+	MsgNotFound: {
+		PtBR: "Registro não encontrado.",
+		En:   "Record not found.",
+	},
+	MsgValidation: {
+		PtBR: "Há campos inválidos.",
+		En:   "Some fields are invalid.",
+	},
+	MsgUnauthenticated: {
+		PtBR: "Autenticação necessária.",
+		En:   "Authentication required.",
+	},
+	MsgForbidden: {
+		PtBR: "Acesso negado.",
+		En:   "Access denied.",
+	},
+	MsgConflict: {
+		PtBR: "O registro foi alterado por outra pessoa; recarregue e tente novamente.",
+		En:   "The record was changed by someone else; reload it and try again.",
+	},
+	MsgQuotaExceeded: {
+		PtBR: "Limite do plano excedido para %s (%d de %d).",
+		En:   "Plan limit exceeded for %s (%d of %d).",
+	},
+	MsgInternal: {
+		PtBR: "Erro interno. Se o problema continuar, informe o código %s ao suporte.",
+		En:   "Internal error. If the problem persists, give code %s to support.",
+	},
+	MsgInvalidCredentials: {
+		PtBR: "Email ou senha inválidos.",
+		En:   "Invalid email or password.",
+	},
+	MsgInvalidAgent: {
+		PtBR: "Agente inválido ou não encontrado.",
+		En:   "Invalid or unknown agent.",
+	},
+	MsgInvalidRefreshToken: {
+		PtBR: "Refresh token inválido ou expirado.",
+		En:   "Invalid or expired refresh token.",
+	},
+	MsgFieldNotNull: {
+		PtBR: "O campo não pode ser nulo.",
+		En:   "This field cannot be null.",
+	},
+	MsgInvalidDate: {
+		PtBR: "Data inválida; use o formato RFC 3339.",
+		En:   "Invalid date; use the RFC 3339 format.",
+	},
+	MsgInvalidCursor: {
+		PtBR: "Cursor inválido.",
+		En:   "Invalid cursor.",
+	},
+	MsgFirstAndLast: {
+		PtBR: "Use first ou last, não os dois.",
+		En:   "Use either first or last, not both.",
+	},
+	MsgPageSize: {
+		PtBR: "O tamanho da página deve estar entre 1 e %d.",
+		En:   "The page size must be between 1 and %d.",
+	},
+	MsgEmptySearch: {
+		PtBR: "Informe o texto da busca.",
+		En:   "Enter the search text.",
+	},
+	MsgBackupSourceJob: {
+		PtBR: "O job de origem precisa ser uma exportação concluída.",
+		En:   "The source job must be a finished export.",
+	},
+	MsgRequired: {
+		PtBR: "Campo obrigatório.",
+		En:   "This field is required.",
+	},
+	MsgMaxLength: {
+		PtBR: "Use no máximo %d caracteres.",
+		En:   "Use at most %d characters.",
+	},
+	MsgMinLength: {
+		PtBR: "Use pelo menos %d caracteres.",
+		En:   "Use at least %d characters.",
+	},
+	MsgInvalidURL: {
+		PtBR: "Informe um endereço http ou https válido.",
+		En:   "Enter a valid http or https URL.",
+	},
+	MsgInvalidEmail: {
+		PtBR: "Email inválido.",
+		En:   "Invalid email address.",
+	},
+	MsgInvalidDomain: {
+		PtBR: "Domínio inválido; use algo como loja.com.br.",
+		En:   "Invalid domain; use something like store.com.",
+	},
+	MsgPositiveAmount: {
+		PtBR: "O valor deve ser maior que zero.",
+		En:   "The amount must be greater than zero.",
+	},
+	MsgNonNegative: {
+		PtBR: "O valor não pode ser negativo.",
+		En:   "The value cannot be negative.",
+	},
+	MsgOneOf: {
+		PtBR: "Valor inválido; use um destes: %s.",
+		En:   "Invalid value; use one of: %s.",
+	},
+	MsgMaxItems: {
+		PtBR: "Informe no máximo %d itens.",
+		En:   "Enter at most %d items.",
+	},
+	MsgQueryTooDeep: {
+		PtBR: "A consulta tem %d níveis de profundidade; o máximo é %d.",
+		En:   "The query is %d levels deep; the maximum is %d.",
+	},
+	MsgQueryTooCostly: {
+		PtBR: "A consulta custa %d pontos; o máximo por requisição é %d. Peça menos itens por página ou menos campos aninhados.",
+		En:   "The query costs %d points; the maximum per request is %d. Request fewer items per page or fewer nested fields.",
+	},
+	MsgIntrospectionDisabled: {
+		PtBR: "A introspecção do schema está desativada neste ambiente.",
+		En:   "Schema introspection is disabled in this environment.",
+	},
+	MsgPersistedQueryNotFound: {
+		PtBR: "Operação persistida não encontrada; reenvie a consulta completa.",
+		En:   "Persisted query not found; resend the full query.",
+	},
+	MsgPersistedQueryVersion: {
+		PtBR: "Versão de operação persistida não suportada.",
+		En:   "Unsupported persisted query version.",
+	},
+	MsgPersistedQueryHash: {
+		PtBR: "O hash informado não corresponde ao texto da consulta.",
+		En:   "The provided hash does not match the query text.",
+	},
+	MsgOperationNotAllowed: {
+		PtBR: "Esta operação não está registrada para execução neste endpoint.",
+		En:   "This operation is not registered to run on this endpoint.",
+	},
+	MsgInvalidMultipart: {
+		PtBR: "Requisição multipart inválida: confira os campos operations e map.",
+		En:   "Invalid multipart request: check the operations and map fields.",
+	},
+	MsgUploadTooLarge: {
+		PtBR: "O envio passa do limite de %d bytes.",
+		En:   "The upload exceeds the %d byte limit.",
+	},
+	MsgUnsupportedImage: {
+		PtBR: "Envie uma imagem JPEG, PNG ou WebP válida.",
+		En:   "Send a valid JPEG, PNG or WebP image.",
+	},
+	MsgImageDimensions: {
+		PtBR: "A imagem pode ter no máximo %d x %d pixels.",
+		En:   "The image can be at most %d x %d pixels.",
+	},
+	MsgEmptyUpload: {
+		PtBR: "O arquivo está vazio.",
+		En:   "The file is empty.",
+	},
+	MsgUploadNotFound: {
+		PtBR: "Nenhum arquivo foi enviado para esta chave.",
+		En:   "No file was uploaded to this key.",
+	},
+	MsgInvalidUploadKey: {
+		PtBR: "Chave de upload inválida.",
+		En:   "Invalid upload key.",
+	},
+	MsgDirectUploadDisabled: {
+		PtBR: "O armazenamento configurado não aceita envio direto.",
+		En:   "The configured storage does not accept direct uploads.",
+	},
+	MsgAssetInUse: {
+		PtBR: "O arquivo está em uso em %d registro(s).",
+		En:   "The file is used by %d record(s).",
+	},
+	MsgExclusiveWith: {
+		PtBR: "Não informe junto com %s.",
+		En:   "Do not send together with %s.",
+	},
+	MsgImageInGallery: {
+		PtBR: "A imagem já está na galeria do produto.",
+		En:   "The image is already in the product gallery.",
+	},
+	MsgGalleryOrder: {
+		PtBR: "Informe todas as imagens do produto, cada uma uma única vez.",
+		En:   "List every image of the product exactly once.",
+	},
+	MsgProductHasGallery: {
+		PtBR: "O produto tem galeria de imagens; use uma imagem da biblioteca de mídia ou setProductCoverImage.",
+		En:   "The product has an image gallery; use an image from the media library or setProductCoverImage.",
+	},
+	MsgInvalidMoney: {
+		PtBR: "Informe o valor como decimal com ponto, ex.: 10.90.",
+		En:   "Send the amount as a decimal with a dot, e.g. 10.90.",
+	},
+	MsgUnknownCurrency: {
+		PtBR: "Moeda não suportada; use uma destas: %s.",
+		En:   "Unsupported currency; use one of: %s.",
+	},
+	MsgMoneyDecimals: {
+		PtBR: "A moeda %s admite no máximo %d casas decimais.",
+		En:   "The %s currency allows at most %d decimal places.",
+	},
+	MsgCurrencyMismatch: {
+		PtBR: "Use a moeda do produto (%s).",
+		En:   "Use the product currency (%s).",
+	},
+	MsgSelectionRange: {
+		PtBR: "O mínimo de escolhas não pode passar do máximo.",
+		En:   "The minimum number of choices cannot exceed the maximum.",
+	},
+	MsgSingleSelection: {
+		PtBR: "Grupos de escolha única aceitam no máximo uma opção.",
+		En:   "Single choice groups accept at most one option.",
+	},
+	MsgVariantRequired: {
+		PtBR: "Escolha uma das variações do produto.",
+		En:   "Choose one of the product variants.",
+	},
+	MsgInvalidChoice: {
+		PtBR: "Escolha inexistente, repetida ou indisponível.",
+		En:   "Choice does not exist, is repeated or is unavailable.",
+	},
+	MsgSelectionMin: {
+		PtBR: "Escolha pelo menos %d opção(ões) em %s.",
+		En:   "Choose at least %d option(s) in %s.",
+	},
+	MsgSelectionMax: {
+		PtBR: "Escolha no máximo %d opção(ões) em %s.",
+		En:   "Choose at most %d option(s) in %s.",
+	},
+	MsgInsufficientStock: {
+		PtBR: "Estoque insuficiente: há %d unidade(s).",
+		En:   "Not enough stock: %d unit(s) available.",
+	},
+	MsgStockNotTracked: {
+		PtBR: "O produto não controla estoque.",
+		En:   "The product does not track stock.",
+	},
+	MsgInvalidBarcode: {
+		PtBR: "Código de barras inválido: use um EAN/GTIN de 8, 12, 13 ou 14 dígitos com o dígito verificador correto.",
+		En:   "Invalid barcode: use an 8, 12, 13 or 14 digit EAN/GTIN with a valid check digit.",
+	},
+	MsgSKUTaken: {
+		PtBR: "Já existe um produto com este SKU.",
+		En:   "A product with this SKU already exists.",
+	},
+}
+
+// Translate retorna a mensagem da chave no idioma pedido, caindo para o idioma padrão.
+// Chaves fora do catálogo são devolvidas como estão.
+func Translate(lang Language, key string, args ...interface{}) string {
+	translations, ok := messages[key]
+	if !ok {
+		return key
+	}
+	format, ok := translations[lang]
+	if !ok {
+		format = translations[DefaultLanguage]
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -8,9 +8,10 @@
 package auth
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/agent"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/user"
 	"golang.org/x/crypto/bcrypt"
@@ -47,37 +48,37 @@ func GetMutationFields(authSvc Service, userSvc user.Service, agentSvc agent.Ser
 				// 1. Encontrar o Agente pelo domínio
 				agents, err := agentSvc.SearchAgents("", agentDomain)
 				if err != nil || len(agents) != 1 {
-					return nil, errors.New("agente inválido ou não encontrado")
+					return nil, apperror.New(apperror.CodeUnauthenticated, apperror.MsgInvalidAgent)
 				}
 				targetAgent := agents[0]
 
 				// 2. Encontrar o Usuário pelo email DENTRO daquele agente
 				users, err := userSvc.SearchUsers(targetAgent.ID, "", email)
 				if err != nil || len(users) != 1 {
-					return nil, errors.New("email ou senha inválidos")
+					return nil, apperror.New(apperror.CodeUnauthenticated, apperror.MsgInvalidCredentials)
 				}
 				targetUser := users[0]
 
 				// 3. Verificar a senha
 				err = bcrypt.CompareHashAndPassword([]byte(targetUser.Password), []byte(password))
 				if err != nil {
-					return nil, errors.New("email ou senha inválidos")
+					return nil, apperror.New(apperror.CodeUnauthenticated, apperror.MsgInvalidCredentials)
 				}
 
 				// 4. Gerar os tokens
 				accessToken, err := GenerateAccessToken(targetUser)
 				if err != nil {
-					return nil, errors.New("falha ao gerar token de acesso")
+					return nil, apperror.Internal(fmt.Errorf("gerar access token: %w", err))
 				}
 				refreshToken, err := GenerateRefreshToken(targetUser)
 				if err != nil {
-					return nil, errors.New("falha ao gerar refresh token")
+					return nil, apperror.Internal(fmt.Errorf("gerar refresh token: %w", err))
 				}
 
 				// 5. Salvar o refresh token no banco
 				_, err = authSvc.StoreRefreshToken(refreshToken, targetUser.ID)
 				if err != nil {
-					return nil, errors.New("falha ao salvar sessão")
+					return nil, apperror.Internal(fmt.Errorf("salvar refresh token: %w", err))
 				}
 
 				// 6. Retornar o payload
@@ -100,20 +101,20 @@ func GetMutationFields(authSvc Service, userSvc user.Service, agentSvc agent.Ser
 				// 1. Validar o refresh token
 				storedToken, err := authSvc.ValidateRefreshToken(tokenString)
 				if err != nil {
-					return nil, errors.New("refresh token inválido ou expirado")
+					return nil, apperror.New(apperror.CodeUnauthenticated, apperror.MsgInvalidRefreshToken)
 				}
 
 				// 2. Encontrar o usuário associado ao token
 				// Precisamos passar o agentId do token para o serviço de usuário
 				user, err := userSvc.GetUserByID(storedToken.UserID, storedToken.UserID)
 				if err != nil {
-					return nil, errors.New("usuário do token não encontrado")
+					return nil, apperror.New(apperror.CodeUnauthenticated, apperror.MsgInvalidRefreshToken)
 				}
 
 				// 3. Gerar um novo access token
 				newAccessToken, err := GenerateAccessToken(user)
 				if err != nil {
-					return nil, errors.New("falha ao gerar novo token de acesso")
+					return nil, apperror.Internal(fmt.Errorf("gerar access token: %w", err))
 				}
 
 				// Nota: Para segurança máxima, poderíamos gerar um novo refresh token também
@@ -8,6 +8,7 @@
 package auth
 
 import (
+	"errors"
 	"os"
 	"time"
 
@@ -20,6 +21,7 @@ type JWTClaims struct {
 	UserID  uint   `json:"user_id"`
 	AgentID uint   `json:"agent_id"`
 	Name    string `json:"name"`
+	Role    string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -33,6 +35,7 @@ func GenerateAccessToken(appUser user.User) (string, error) {
 		UserID:  appUser.ID,
 		AgentID: appUser.AgentID,
 		Name:    appUser.Name,
+		Role:    appUser.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -68,3 +71,18 @@ func GenerateRefreshToken(appUser user.User) (string, error) {
 
 	return token.SignedString([]byte(jwtSecret))
 }
+
+// ParseAccessToken valida a assinatura e a expiração de um token de acesso e retorna seus dados.
+func ParseAccessToken(tokenString string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_ACCESS_SECRET")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token inválido")
+	}
+	return claims, nil
+}
@@ -0,0 +1,50 @@
+/*
+|------------------------------------------------
+| File: internal/auth/middleware.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+// Middleware lê o header "Authorization: Bearer <token>" e, se o token for válido,
+// coloca a identidade do usuário no contexto da requisição. Requisições sem token
+// seguem anônimas; cabe a cada resolver exigir autenticação.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity, ok := IdentityFromHeader(r.Header.Get("Authorization")); ok {
+			r = r.WithContext(session.WithIdentity(r.Context(), identity))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IdentityFromHeader extrai a identidade de um valor de header Authorization.
+func IdentityFromHeader(header string) (session.Identity, bool) {
+	tokenString, found := strings.CutPrefix(header, "Bearer ")
+	if !found || tokenString == "" {
+		return session.Identity{}, false
+	}
+	claims, err := ParseAccessToken(tokenString)
+	if err != nil {
+		return session.Identity{}, false
+	}
+	return session.Identity{UserID: claims.UserID, AgentID: claims.AgentID, Role: claims.Role}, true
+}
+
+// FiberMiddleware faz o mesmo que Middleware nas rotas REST do Fiber, deixando a
+// identidade em c.UserContext().
+func FiberMiddleware(c *fiber.Ctx) error {
+	if identity, ok := IdentityFromHeader(c.Get(fiber.HeaderAuthorization)); ok {
+		c.SetUserContext(session.WithIdentity(c.UserContext(), identity))
+	}
+	return c.Next()
+}
@@ -0,0 +1,254 @@
+/*
+|------------------------------------------------
+| File: internal/backup/archive.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/product"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+)
+
+// ArchiveVersion é a versão atual do formato do backup. Deve ser incrementada
+// sempre que um campo existente mudar de significado.
+//
+// Versão 2: o preço do produto passou de número em reais para {amount, currency}.
+// Versão 3: produtos com SKU, código de barras, referências externas, grupos de opções,
+// variações e estoque.
+const ArchiveVersion = 3
+
+// Format é o formato de serialização do arquivo de backup.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatZIP  Format = "zip"
+)
+
+// Archive é o conteúdo completo de um agente.
+type Archive struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Agent      AgentRecord      `json:"agent"`
+	Categories []CategoryRecord `json:"categories"`
+	Products   []ProductRecord  `json:"products"`
+	Users      []UserRecord     `json:"users"`
+}
+
+// AgentRecord guarda os dados e configurações do agente de origem.
+type AgentRecord struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Domain string `json:"domain"`
+	PlanID *uint  `json:"plan_id,omitempty"`
+	Locale string `json:"locale,omitempty"`
+}
+
+type CategoryRecord struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ProductRecord struct {
+	ID           uint                 `json:"id"`
+	CategoryID   uint                 `json:"category_id"`
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	SKU          string               `json:"sku,omitempty"`
+	Barcode      string               `json:"barcode,omitempty"`
+	ExternalRefs product.ExternalRefs `json:"external_refs,omitempty"`
+	Price        money.Money          `json:"price"`
+	ImageURL     string               `json:"image_url"`
+	IsActive     bool                 `json:"is_active"`
+	Position     int                  `json:"position"`
+	Tags         []string             `json:"tags,omitempty"`
+	// O histórico de movimentações não vai no backup: na importação, o estoque entra
+	// como um ajuste, para que o saldo do histórico bata com ele.
+	TrackStock        bool                `json:"track_stock"`
+	Stock             int                 `json:"stock"`
+	LowStockThreshold int                 `json:"low_stock_threshold"`
+	AutoDeactivate    bool                `json:"auto_deactivate"`
+	OptionGroups      []OptionGroupRecord `json:"option_groups,omitempty"`
+	Variants          []VariantRecord     `json:"variants,omitempty"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+}
+
+// OptionGroupRecord é um grupo de opções do produto, com as opções dentro.
+type OptionGroupRecord struct {
+	ID        uint           `json:"id"`
+	Name      string         `json:"name"`
+	Multiple  bool           `json:"multiple"`
+	MinSelect int            `json:"min_select"`
+	MaxSelect int            `json:"max_select"`
+	Required  bool           `json:"required"`
+	Position  int            `json:"position"`
+	Options   []OptionRecord `json:"options"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type OptionRecord struct {
+	ID          uint        `json:"id"`
+	Name        string      `json:"name"`
+	PriceDelta  money.Money `json:"price_delta"`
+	IsAvailable bool        `json:"is_available"`
+	Position    int         `json:"position"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+type VariantRecord struct {
+	ID        uint        `json:"id"`
+	Name      string      `json:"name"`
+	SKU       string      `json:"sku,omitempty"`
+	Price     money.Money `json:"price"`
+	Stock     int         `json:"stock"`
+	IsActive  bool        `json:"is_active"`
+	Position  int         `json:"position"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// UnmarshalJSON aceita também o preço da versão 1, um número em reais.
+func (r *ProductRecord) UnmarshalJSON(data []byte) error {
+	type plain ProductRecord
+	var record struct {
+		plain
+		Price json.RawMessage `json:"price"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	*r = ProductRecord(record.plain)
+	var legacy float64
+	if err := json.Unmarshal(record.Price, &legacy); err == nil {
+		price, err := money.FromFloat(legacy, money.DefaultCurrency)
+		r.Price = price
+		return err
+	}
+	return json.Unmarshal(record.Price, &r.Price)
+}
+
+// UserRecord só carrega PasswordHash quando a exportação for feita com hashes.
+type UserRecord struct {
+	ID           uint      `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Nomes dos arquivos dentro do ZIP.
+const (
+	zipManifest   = "manifest.json"
+	zipCategories = "categories.json"
+	zipProducts   = "products.json"
+	zipUsers      = "users.json"
+)
+
+// manifest é o cabeçalho do ZIP, com tudo que não é lista de registros.
+type manifest struct {
+	Version    int         `json:"version"`
+	ExportedAt time.Time   `json:"exported_at"`
+	Agent      AgentRecord `json:"agent"`
+}
+
+// Write serializa o backup no formato informado.
+func Write(w io.Writer, archive Archive, format Format) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(archive)
+	case FormatZIP:
+		zw := zip.NewWriter(w)
+		entries := []struct {
+			name string
+			data interface{}
+		}{
+			{zipManifest, manifest{Version: archive.Version, ExportedAt: archive.ExportedAt, Agent: archive.Agent}},
+			{zipCategories, archive.Categories},
+			{zipProducts, archive.Products},
+			{zipUsers, archive.Users},
+		}
+		for _, entry := range entries {
+			f, err := zw.Create(entry.name)
+			if err != nil {
+				return err
+			}
+			if err := json.NewEncoder(f).Encode(entry.data); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}
+	return fmt.Errorf("formato de backup desconhecido: %s", format)
+}
+
+// Read lê um backup no formato informado e valida sua versão.
+func Read(r io.ReaderAt, size int64, format Format) (Archive, error) {
+	var archive Archive
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(io.NewSectionReader(r, 0, size)).Decode(&archive); err != nil {
+			return Archive{}, err
+		}
+	case FormatZIP:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return Archive{}, err
+		}
+		var m manifest
+		targets := map[string]interface{}{
+			zipManifest:   &m,
+			zipCategories: &archive.Categories,
+			zipProducts:   &archive.Products,
+			zipUsers:      &archive.Users,
+		}
+		for name, target := range targets {
+			f, err := zr.Open(name)
+			if err != nil {
+				return Archive{}, fmt.Errorf("backup incompleto, %s: %w", name, err)
+			}
+			err = json.NewDecoder(f).Decode(target)
+			f.Close()
+			if err != nil {
+				return Archive{}, fmt.Errorf("backup inválido, %s: %w", name, err)
+			}
+		}
+		archive.Version = m.Version
+		archive.ExportedAt = m.ExportedAt
+		archive.Agent = m.Agent
+	default:
+		return Archive{}, fmt.Errorf("formato de backup desconhecido: %s", format)
+	}
+
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return Archive{}, fmt.Errorf("versão de backup não suportada: %d", archive.Version)
+	}
+	return archive, nil
+}
+
+// FormatFromPath deduz o formato pela extensão do arquivo.
+func FormatFromPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return FormatZIP
+	}
+	return FormatJSON
+}
@@ -0,0 +1,88 @@
+/*
+|------------------------------------------------
+| File: internal/backup/graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package backup
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+var backupJobType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "BackupJob",
+		Fields: graphql.Fields{
+			"id":                      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"kind":                    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"status":                  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"agent_id":                &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"source_job_id":           &graphql.Field{Type: graphql.Int},
+			"include_password_hashes": &graphql.Field{Type: graphql.Boolean},
+			"error":                   &graphql.Field{Type: graphql.String},
+			"created_at":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"finished_at":             &graphql.Field{Type: graphql.String},
+		},
+	},
+)
+
+func GetQueryFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"backupJob": &graphql.Field{
+			Type:        backupJobType,
+			Description: "Obtém o andamento de uma exportação ou importação de agente. Apenas administradores.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := session.RequireAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(int)
+				return service.GetJob(uint(id))
+			},
+		},
+	}
+}
+
+func GetMutationFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"exportAgent": &graphql.Field{
+			Type:        backupJobType,
+			Description: "Inicia a exportação de todos os dados de um agente. Apenas administradores.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":               &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"includePasswordHashes": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				admin, err := session.RequireAdmin(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				agentId, _ := p.Args["agentId"].(int)
+				withHashes, _ := p.Args["includePasswordHashes"].(bool)
+				return service.StartExport(admin.UserID, uint(agentId), ExportOptions{IncludePasswordHashes: withHashes})
+			},
+		},
+		"importAgent": &graphql.Field{
+			Type:        backupJobType,
+			Description: "Importa, em outro agente, o arquivo gerado por uma exportação concluída. Apenas administradores.",
+			Args: graphql.FieldConfigArgument{
+				"targetAgentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"sourceJobId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				admin, err := session.RequireAdmin(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				targetAgentId, _ := p.Args["targetAgentId"].(int)
+				sourceJobId, _ := p.Args["sourceJobId"].(int)
+				return service.StartImport(admin.UserID, uint(targetAgentId), uint(sourceJobId))
+			},
+		},
+	}
+}
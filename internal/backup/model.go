@@ -0,0 +1,53 @@
+/*
+|------------------------------------------------
+| File: internal/backup/model.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package backup
+
+import "time"
+
+const (
+	JobKindExport = "export"
+	JobKindImport = "import"
+
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// Job é uma exportação ou importação executada em segundo plano.
+type Job struct {
+	ID                    uint       `gorm:"primaryKey" json:"id"`
+	Kind                  string     `gorm:"not null" json:"kind"`
+	Status                string     `gorm:"not null;default:pending" json:"status"`
+	AgentID               uint       `gorm:"not null;index" json:"agent_id"` // Agente exportado ou agente de destino da importação.
+	SourceJobID           *uint      `json:"source_job_id"`                  // Exportação usada como origem de uma importação.
+	IncludePasswordHashes bool       `gorm:"not null;default:false" json:"include_password_hashes"`
+	FilePath              string     `json:"file_path"`
+	Error                 string     `json:"error"`
+	RequestedBy           uint       `json:"requested_by"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	FinishedAt            *time.Time `json:"finished_at"`
+}
+
+// TableName evita o nome genérico "jobs".
+func (Job) TableName() string {
+	return "backup_jobs"
+}
+
+// ExportOptions controla o que entra no backup.
+type ExportOptions struct {
+	IncludePasswordHashes bool
+}
+
+// ImportResult resume os registros criados por uma importação.
+type ImportResult struct {
+	Categories int `json:"categories"`
+	Products   int `json:"products"`
+	Users      int `json:"users"`
+}
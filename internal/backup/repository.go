@@ -0,0 +1,372 @@
+/*
+|------------------------------------------------
+| File: internal/backup/repository.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package backup
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/agent"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/category"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/product"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/user"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	LoadArchive(agentID uint, opts ExportOptions) (Archive, error)
+	ImportArchive(targetAgentID uint, archive Archive) (ImportResult, error)
+	CreateJob(job Job) (Job, error)
+	UpdateJob(job Job) (Job, error)
+	FindJobByID(id uint) (Job, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// LoadArchive lê todos os dados de um agente.
+func (r *repository) LoadArchive(agentID uint, opts ExportOptions) (Archive, error) {
+	var source agent.Agent
+	if err := r.db.First(&source, agentID).Error; err != nil {
+		return Archive{}, err
+	}
+
+	var categories []category.Category
+	if err := r.db.Where("agent_id = ?", agentID).Order("id asc").Find(&categories).Error; err != nil {
+		return Archive{}, err
+	}
+	var products []product.Product
+	if err := r.db.Where("agent_id = ?", agentID).Order("id asc").Find(&products).Error; err != nil {
+		return Archive{}, err
+	}
+	var groups []product.OptionGroup
+	err := r.db.Where("agent_id = ?", agentID).Order("product_id, position, id").
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Find(&groups).Error
+	if err != nil {
+		return Archive{}, err
+	}
+	var variants []product.Variant
+	if err := r.db.Where("agent_id = ?", agentID).Order("product_id, position, id").Find(&variants).Error; err != nil {
+		return Archive{}, err
+	}
+	var users []user.User
+	if err := r.db.Where("agent_id = ?", agentID).Order("id asc").Find(&users).Error; err != nil {
+		return Archive{}, err
+	}
+
+	groupsByProduct := make(map[uint][]OptionGroupRecord)
+	for _, g := range groups {
+		record := OptionGroupRecord{
+			ID:        g.ID,
+			Name:      g.Name,
+			Multiple:  g.Multiple,
+			MinSelect: g.MinSelect,
+			MaxSelect: g.MaxSelect,
+			Required:  g.Required,
+			Position:  g.Position,
+			Options:   make([]OptionRecord, 0, len(g.Options)),
+			CreatedAt: g.CreatedAt,
+			UpdatedAt: g.UpdatedAt,
+		}
+		for _, o := range g.Options {
+			record.Options = append(record.Options, OptionRecord{
+				ID:          o.ID,
+				Name:        o.Name,
+				PriceDelta:  o.PriceDelta,
+				IsAvailable: o.IsAvailable,
+				Position:    o.Position,
+				CreatedAt:   o.CreatedAt,
+				UpdatedAt:   o.UpdatedAt,
+			})
+		}
+		groupsByProduct[g.ProductID] = append(groupsByProduct[g.ProductID], record)
+	}
+	variantsByProduct := make(map[uint][]VariantRecord)
+	for _, v := range variants {
+		variantsByProduct[v.ProductID] = append(variantsByProduct[v.ProductID], VariantRecord{
+			ID:        v.ID,
+			Name:      v.Name,
+			SKU:       v.SKU,
+			Price:     v.Price,
+			Stock:     v.Stock,
+			IsActive:  v.IsActive,
+			Position:  v.Position,
+			CreatedAt: v.CreatedAt,
+			UpdatedAt: v.UpdatedAt,
+		})
+	}
+
+	archive := Archive{
+		Agent: AgentRecord{
+			ID:     source.ID,
+			Name:   source.Name,
+			Domain: source.Domain,
+			PlanID: source.PlanID,
+			Locale: source.Locale,
+		},
+		Categories: make([]CategoryRecord, 0, len(categories)),
+		Products:   make([]ProductRecord, 0, len(products)),
+		Users:      make([]UserRecord, 0, len(users)),
+	}
+	for _, c := range categories {
+		archive.Categories = append(archive.Categories, CategoryRecord{
+			ID:        c.ID,
+			Name:      c.Name,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+		})
+	}
+	for _, p := range products {
+		archive.Products = append(archive.Products, ProductRecord{
+			ID:                p.ID,
+			CategoryID:        p.CategoryID,
+			Name:              p.Name,
+			Description:       p.Description,
+			SKU:               p.SKU,
+			Barcode:           p.Barcode,
+			ExternalRefs:      p.ExternalRefs,
+			Price:             p.Price,
+			ImageURL:          p.ImageURL,
+			IsActive:          p.IsActive,
+			Position:          p.Position,
+			Tags:              p.Tags,
+			TrackStock:        p.TrackStock,
+			Stock:             p.Stock,
+			LowStockThreshold: p.LowStockThreshold,
+			AutoDeactivate:    p.AutoDeactivate,
+			OptionGroups:      groupsByProduct[p.ID],
+			Variants:          variantsByProduct[p.ID],
+			CreatedAt:         p.CreatedAt,
+			UpdatedAt:         p.UpdatedAt,
+		})
+	}
+	for _, u := range users {
+		record := UserRecord{
+			ID:        u.ID,
+			Name:      u.Name,
+			Email:     u.Email,
+			Role:      u.Role,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+		}
+		if opts.IncludePasswordHashes {
+			record.PasswordHash = u.Password
+		}
+		archive.Users = append(archive.Users, record)
+	}
+	return archive, nil
+}
+
+// ImportArchive cria os registros do backup no agente de destino, numa única transação,
+// remapeando os IDs de categoria referenciados pelos produtos e os de produto e grupo das
+// opções e variações.
+func (r *repository) ImportArchive(targetAgentID uint, archive Archive) (ImportResult, error) {
+	var result ImportResult
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var target agent.Agent
+		if err := tx.First(&target, targetAgentID).Error; err != nil {
+			return err
+		}
+
+		categoryIDs := make(map[uint]uint, len(archive.Categories))
+		for _, record := range archive.Categories {
+			c := category.Category{
+				AgentID:   targetAgentID,
+				Name:      record.Name,
+				CreatedAt: record.CreatedAt,
+				UpdatedAt: record.UpdatedAt,
+			}
+			if err := tx.Create(&c).Error; err != nil {
+				return err
+			}
+			categoryIDs[record.ID] = c.ID
+		}
+		result.Categories = len(archive.Categories)
+
+		for _, record := range archive.Products {
+			categoryID, ok := categoryIDs[record.CategoryID]
+			if !ok {
+				return fmt.Errorf("produto %d referencia a categoria %d, que não está no backup", record.ID, record.CategoryID)
+			}
+			p := product.Product{
+				AgentID:           targetAgentID,
+				CategoryID:        categoryID,
+				Name:              record.Name,
+				Description:       record.Description,
+				SKU:               record.SKU,
+				Barcode:           record.Barcode,
+				ExternalRefs:      record.ExternalRefs,
+				Price:             record.Price,
+				ImageURL:          record.ImageURL,
+				IsActive:          record.IsActive,
+				Position:          record.Position,
+				Tags:              record.Tags,
+				TrackStock:        record.TrackStock,
+				Stock:             record.Stock,
+				LowStockThreshold: record.LowStockThreshold,
+				AutoDeactivate:    record.AutoDeactivate,
+				Version:           1,
+				CreatedAt:         record.CreatedAt,
+				UpdatedAt:         record.UpdatedAt,
+			}
+			// Select("*") grava também is_active = false, que o default da coluna ignoraria.
+			if err := tx.Select("*").Omit("id").Create(&p).Error; err != nil {
+				return err
+			}
+			if err := importStock(tx, targetAgentID, p.ID, nil, p.Stock); err != nil {
+				return err
+			}
+			if err := importOptions(tx, targetAgentID, p.ID, record); err != nil {
+				return err
+			}
+		}
+		result.Products = len(archive.Products)
+
+		for _, record := range archive.Users {
+			u := user.User{
+				AgentID:   targetAgentID,
+				Name:      record.Name,
+				Email:     record.Email,
+				Role:      record.Role,
+				CreatedAt: record.CreatedAt,
+				UpdatedAt: record.UpdatedAt,
+			}
+			if u.Role == "" {
+				u.Role = session.RoleMember
+			}
+			create := tx
+			if record.PasswordHash != "" {
+				// O hash já vem pronto; pular o BeforeSave evita gerar um hash do hash.
+				u.Password = record.PasswordHash
+				create = tx.Session(&gorm.Session{SkipHooks: true})
+			} else {
+				// Sem hash, o usuário recebe uma senha aleatória e precisa redefini-la.
+				password, err := randomPassword()
+				if err != nil {
+					return err
+				}
+				u.Password = password
+			}
+			if err := create.Create(&u).Error; err != nil {
+				return err
+			}
+		}
+		result.Users = len(archive.Users)
+		return nil
+	})
+	return result, err
+}
+
+// importOptions cria os grupos de opções e as variações de um produto importado, ligados
+// ao novo ID do produto e dos grupos.
+func importOptions(tx *gorm.DB, agentID, productID uint, record ProductRecord) error {
+	for _, groupRecord := range record.OptionGroups {
+		group := product.OptionGroup{
+			AgentID:   agentID,
+			ProductID: productID,
+			Name:      groupRecord.Name,
+			Multiple:  groupRecord.Multiple,
+			MinSelect: groupRecord.MinSelect,
+			MaxSelect: groupRecord.MaxSelect,
+			Required:  groupRecord.Required,
+			Position:  groupRecord.Position,
+			CreatedAt: groupRecord.CreatedAt,
+			UpdatedAt: groupRecord.UpdatedAt,
+		}
+		if err := tx.Select("*").Omit("id", "Options").Create(&group).Error; err != nil {
+			return err
+		}
+		for _, optionRecord := range groupRecord.Options {
+			option := product.Option{
+				AgentID:     agentID,
+				GroupID:     group.ID,
+				Name:        optionRecord.Name,
+				PriceDelta:  optionRecord.PriceDelta,
+				IsAvailable: optionRecord.IsAvailable,
+				Position:    optionRecord.Position,
+				CreatedAt:   optionRecord.CreatedAt,
+				UpdatedAt:   optionRecord.UpdatedAt,
+			}
+			if err := tx.Select("*").Omit("id").Create(&option).Error; err != nil {
+				return err
+			}
+		}
+	}
+	for _, variantRecord := range record.Variants {
+		variant := product.Variant{
+			AgentID:   agentID,
+			ProductID: productID,
+			Name:      variantRecord.Name,
+			SKU:       variantRecord.SKU,
+			Price:     variantRecord.Price,
+			Stock:     variantRecord.Stock,
+			IsActive:  variantRecord.IsActive,
+			Position:  variantRecord.Position,
+			CreatedAt: variantRecord.CreatedAt,
+			UpdatedAt: variantRecord.UpdatedAt,
+		}
+		if err := tx.Select("*").Omit("id").Create(&variant).Error; err != nil {
+			return err
+		}
+		if err := importStock(tx, agentID, productID, &variant.ID, variant.Stock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importStock lança o estoque importado como um ajuste, já que o histórico de
+// movimentações não vai no backup. Sem estoque, não há o que lançar.
+func importStock(tx *gorm.DB, agentID, productID uint, variantID *uint, stock int) error {
+	if stock == 0 {
+		return nil
+	}
+	movement := product.StockMovement{
+		AgentID:   agentID,
+		ProductID: productID,
+		VariantID: variantID,
+		Kind:      product.MovementAdjustment,
+		Quantity:  stock,
+		Delta:     stock,
+		Balance:   stock,
+		Reason:    "Importação de backup",
+	}
+	return tx.Create(&movement).Error
+}
+
+func (r *repository) CreateJob(job Job) (Job, error) {
+	err := r.db.Create(&job).Error
+	return job, err
+}
+
+func (r *repository) UpdateJob(job Job) (Job, error) {
+	err := r.db.Save(&job).Error
+	return job, err
+}
+
+func (r *repository) FindJobByID(id uint) (Job, error) {
+	var job Job
+	err := r.db.First(&job, id).Error
+	return job, err
+}
+
+func randomPassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
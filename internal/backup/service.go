@@ -0,0 +1,166 @@
+/*
+|------------------------------------------------
+| File: internal/backup/service.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package backup
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+)
+
+type Service interface {
+	Export(agentID uint, opts ExportOptions) (Archive, error)
+	Import(targetAgentID uint, archive Archive) (ImportResult, error)
+	StartExport(requestedBy, agentID uint, opts ExportOptions) (Job, error)
+	StartImport(requestedBy, targetAgentID, sourceJobID uint) (Job, error)
+	GetJob(id uint) (Job, error)
+}
+
+type service struct {
+	repo Repository
+	dir  string
+}
+
+// NewService cria o serviço de backup. Os arquivos das exportações assíncronas ficam em dir.
+func NewService(repo Repository, dir string) Service {
+	return &service{repo: repo, dir: dir}
+}
+
+func (s *service) Export(agentID uint, opts ExportOptions) (Archive, error) {
+	archive, err := s.repo.LoadArchive(agentID, opts)
+	if err != nil {
+		return Archive{}, err
+	}
+	archive.Version = ArchiveVersion
+	archive.ExportedAt = time.Now().UTC()
+	return archive, nil
+}
+
+func (s *service) Import(targetAgentID uint, archive Archive) (ImportResult, error) {
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return ImportResult{}, fmt.Errorf("versão de backup não suportada: %d", archive.Version)
+	}
+	return s.repo.ImportArchive(targetAgentID, archive)
+}
+
+// StartExport registra o job e gera o ZIP em segundo plano.
+func (s *service) StartExport(requestedBy, agentID uint, opts ExportOptions) (Job, error) {
+	job, err := s.repo.CreateJob(Job{
+		Kind:                  JobKindExport,
+		Status:                JobStatusPending,
+		AgentID:               agentID,
+		IncludePasswordHashes: opts.IncludePasswordHashes,
+		RequestedBy:           requestedBy,
+	})
+	if err != nil {
+		return Job{}, err
+	}
+	go s.run(job, func(job *Job) error {
+		archive, err := s.Export(job.AgentID, opts)
+		if err != nil {
+			return err
+		}
+		path, err := s.writeFile(job, archive)
+		if err != nil {
+			return err
+		}
+		job.FilePath = path
+		return nil
+	})
+	return job, nil
+}
+
+// StartImport importa, em segundo plano, o arquivo de uma exportação concluída.
+func (s *service) StartImport(requestedBy, targetAgentID, sourceJobID uint) (Job, error) {
+	source, err := s.repo.FindJobByID(sourceJobID)
+	if err != nil {
+		return Job{}, err
+	}
+	if source.Kind != JobKindExport || source.Status != JobStatusDone {
+		return Job{}, apperror.Invalid("sourceJobId", apperror.MsgBackupSourceJob)
+	}
+	job, err := s.repo.CreateJob(Job{
+		Kind:        JobKindImport,
+		Status:      JobStatusPending,
+		AgentID:     targetAgentID,
+		SourceJobID: &source.ID,
+		RequestedBy: requestedBy,
+	})
+	if err != nil {
+		return Job{}, err
+	}
+	go s.run(job, func(job *Job) error {
+		archive, err := ReadFile(source.FilePath)
+		if err != nil {
+			return err
+		}
+		_, err = s.Import(job.AgentID, archive)
+		return err
+	})
+	return job, nil
+}
+
+func (s *service) GetJob(id uint) (Job, error) {
+	return s.repo.FindJobByID(id)
+}
+
+// run executa o trabalho do job e registra o resultado final.
+func (s *service) run(job Job, work func(job *Job) error) {
+	job.Status = JobStatusRunning
+	if updated, err := s.repo.UpdateJob(job); err == nil {
+		job = updated
+	}
+
+	err := work(&job)
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = JobStatusDone
+	if err != nil {
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+		log.Printf("Backup job %d (%s) falhou: %v", job.ID, job.Kind, err)
+	}
+	if _, err := s.repo.UpdateJob(job); err != nil {
+		log.Printf("Falha ao atualizar backup job %d: %v", job.ID, err)
+	}
+}
+
+func (s *service) writeFile(job *Job, archive Archive) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("agent-%d-job-%d.zip", job.AgentID, job.ID))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if err := Write(f, archive, FormatZIP); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// ReadFile abre um arquivo de backup, deduzindo o formato pela extensão.
+func ReadFile(path string) (Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Archive{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Archive{}, err
+	}
+	return Read(f, info.Size(), FormatFromPath(path))
+}
@@ -0,0 +1,80 @@
+/*
+|------------------------------------------------
+| File: internal/database/array.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// StringArray mapeia uma coluna text[] do Postgres.
+type StringArray []string
+
+// GormDataType faz o AutoMigrate criar a coluna como text[].
+func (StringArray) GormDataType() string {
+	return "text[]"
+}
+
+// Value gera o literal de array do Postgres, com todos os elementos entre aspas.
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	quoted := make([]string, len(a))
+	for i, item := range a {
+		item = strings.ReplaceAll(item, `\`, `\\`)
+		quoted[i] = `"` + strings.ReplaceAll(item, `"`, `\"`) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+// Scan lê o literal de array unidimensional devolvido pelo Postgres.
+func (a *StringArray) Scan(src interface{}) error {
+	var text string
+	switch value := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	default:
+		return fmt.Errorf("StringArray: tipo não suportado %T", src)
+	}
+	if len(text) < 2 || text[0] != '{' || text[len(text)-1] != '}' {
+		return fmt.Errorf("StringArray: literal inválido %q", text)
+	}
+
+	items := StringArray{}
+	body := text[1 : len(text)-1]
+	for i := 0; i < len(body); {
+		var item strings.Builder
+		if body[i] == '"' {
+			i++
+			for i < len(body) && body[i] != '"' {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				item.WriteByte(body[i])
+				i++
+			}
+			i++ // aspas de fechamento
+		} else {
+			for i < len(body) && body[i] != ',' {
+				item.WriteByte(body[i])
+				i++
+			}
+		}
+		items = append(items, item.String())
+		i++ // vírgula
+	}
+	*a = items
+	return nil
+}
@@ -0,0 +1,11 @@
+package database
+
+import "log"
+
+// Migrate cria ou atualiza as tabelas dos modelos informados.
+func Migrate(models ...interface{}) {
+	if err := DB.AutoMigrate(models...); err != nil {
+		log.Fatal("Failed to migrate the database: ", err)
+	}
+	log.Println("Database migration successful.")
+}
@@ -24,7 +24,9 @@ func ConnectDB() {
 		os.Getenv("DB_SSLMODE"),
 	)
 
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError converte violações de unicidade em gorm.ErrDuplicatedKey, que os
+	// repositórios traduzem para o campo repetido.
+	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect to the database: ", err)
 	}
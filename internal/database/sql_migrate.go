@@ -0,0 +1,58 @@
+/*
+|------------------------------------------------
+| File: internal/database/sql_migrate.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package database
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SQLMigration é uma alteração que o AutoMigrate não sabe fazer (extensões, índices
+// GIN, colunas geradas). Cada uma roda uma única vez, identificada pelo ID.
+type SQLMigration struct {
+	ID  string
+	SQL string
+}
+
+type schemaMigration struct {
+	ID        string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrateSQL aplica, na ordem, as migrações ainda não registradas em schema_migrations.
+// Deve rodar depois de Migrate, porque as migrações alteram as tabelas dos modelos.
+func MigrateSQL(migrations ...SQLMigration) {
+	if err := DB.AutoMigrate(&schemaMigration{}); err != nil {
+		log.Fatal("Failed to create schema_migrations: ", err)
+	}
+	for _, migration := range migrations {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			var applied int64
+			if err := tx.Model(&schemaMigration{}).Where("id = ?", migration.ID).Count(&applied).Error; err != nil {
+				return err
+			}
+			if applied > 0 {
+				return nil
+			}
+			if err := tx.Exec(migration.SQL).Error; err != nil {
+				return err
+			}
+			log.Printf("Migration %s applied.", migration.ID)
+			return tx.Create(&schemaMigration{ID: migration.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			log.Fatalf("Failed to apply migration %s: %v", migration.ID, err)
+		}
+	}
+}
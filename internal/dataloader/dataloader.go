@@ -0,0 +1,73 @@
+/*
+|------------------------------------------------
+| File: internal/dataloader/dataloader.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package dataloader
+
+import "sync"
+
+// BatchFunc busca de uma vez os valores de várias chaves. Uma chave ausente do mapa
+// resulta no valor zero de V.
+type BatchFunc[K comparable, V any] func(keys []K) (map[K]V, error)
+
+// Loader agrupa as buscas feitas pelos resolvers de uma mesma requisição. Cada Load
+// só registra a chave e devolve um thunk; o graphql-go resolve todos os campos irmãos
+// antes de chamar os thunks, então o primeiro thunk chamado busca o lote inteiro.
+// Um Loader guarda cache dos resultados e deve viver apenas durante uma requisição.
+type Loader[K comparable, V any] struct {
+	batch   BatchFunc[K, V]
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	loaded  map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+func New[K comparable, V any](batch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		batch:  batch,
+		queued: map[K]bool{},
+		loaded: map[K]bool{},
+		values: map[K]V{},
+		errs:   map[K]error{},
+	}
+}
+
+// Load agenda a chave para o próximo lote e retorna o thunk que entrega seu valor.
+func (l *Loader[K, V]) Load(key K) func() (V, error) {
+	l.mu.Lock()
+	if !l.loaded[key] && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !l.loaded[key] {
+			l.dispatch()
+		}
+		return l.values[key], l.errs[key]
+	}
+}
+
+// dispatch busca todas as chaves pendentes. Deve ser chamado com l.mu travado.
+func (l *Loader[K, V]) dispatch() {
+	keys := l.pending
+	l.pending = nil
+	values, err := l.batch(keys)
+	for _, key := range keys {
+		delete(l.queued, key)
+		l.loaded[key] = true
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		l.values[key] = values[key]
+	}
+}
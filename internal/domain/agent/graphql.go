@@ -7,49 +7,113 @@
 */
 package agent
 
-import "github.com/graphql-go/graphql"
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
 
-var agentType = graphql.NewObject(
+// AgentType é o tipo GraphQL para a entidade Agent, exportado para os campos de relacionamento.
+var AgentType = graphql.NewObject(
 	graphql.ObjectConfig{
 		Name: "Agent",
 		Fields: graphql.Fields{
 			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
 			"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 			"domain":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"plan_id":    &graphql.Field{Type: graphql.Int},
+			"locale":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Localidade usada para formatar valores, como pt-BR."},
+			"version":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
 			"created_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 			"updated_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"deleted_at": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if m, ok := p.Source.(Agent); ok && m.DeletedAt.Valid {
+						return m.DeletedAt.Time, nil
+					}
+					return nil, nil
+				},
+			},
 		},
 	},
 )
 
-var paginatedAgentsType = graphql.NewObject(
-	graphql.ObjectConfig{
-		Name: "PaginatedAgents",
-		Fields: graphql.Fields{
-			"data":        &graphql.Field{Type: graphql.NewList(agentType)},
-			"total":       &graphql.Field{Type: graphql.Int},
-			"page":        &graphql.Field{Type: graphql.Int},
-			"per_page":    &graphql.Field{Type: graphql.Int},
-			"total_pages": &graphql.Field{Type: graphql.Int},
+// updateAgentInputType tem todos os campos opcionais: o que não for enviado não muda.
+var updateAgentInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UpdateAgentInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"domain": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"locale": &graphql.InputObjectFieldConfig{Type: graphql.String},
 		},
 	},
 )
 
+var agentFilterInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "AgentFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"text":      &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Procura no nome e no domínio."},
+			"planId":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"createdAt": &graphql.InputObjectFieldConfig{Type: pagination.TimeRangeInput},
+		},
+	},
+)
+
+var agentSortInputType = pagination.SortInput("Agent", graphql.EnumValueConfigMap{
+	"NAME":       &graphql.EnumValueConfig{Value: "name"},
+	"DOMAIN":     &graphql.EnumValueConfig{Value: "domain"},
+	"CREATED_AT": &graphql.EnumValueConfig{Value: "created_at"},
+})
+
+// filterFromArgs lê o argumento filter da query agents.
+func filterFromArgs(args map[string]interface{}) (AgentFilter, error) {
+	var filter AgentFilter
+	input, ok := args["filter"].(map[string]interface{})
+	if !ok {
+		return filter, nil
+	}
+	filter.Text, _ = input["text"].(string)
+	if v, ok := input["planId"].(int); ok {
+		planID := uint(v)
+		filter.PlanID = &planID
+	}
+	createdAt, err := pagination.TimeRangeFromArgs(input["createdAt"])
+	if err != nil {
+		return filter, err
+	}
+	filter.CreatedAt = createdAt
+	return filter, nil
+}
+
+var agentsConnectionType = pagination.ConnectionType("Agent", AgentType)
+
 func GetQueryFields(service Service) graphql.Fields {
 	return graphql.Fields{
 		"agents": &graphql.Field{
-			Type:        paginatedAgentsType,
+			Type:        agentsConnectionType,
 			Description: "Obtém uma lista paginada de agentes.",
-			Args: graphql.FieldConfigArgument{
-				"page": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
-			},
+			Args: pagination.Args(graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{Type: agentFilterInputType},
+				"sort":   &graphql.ArgumentConfig{Type: agentSortInputType},
+			}),
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				page, _ := p.Args["page"].(int)
-				return service.GetAllAgents(page)
+				page, err := pagination.PageFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				filter, err := filterFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return service.GetAllAgents(filter, pagination.SortFromArgs(p.Args), page)
 			},
 		},
 		"agent": &graphql.Field{
-			Type:        agentType,
+			Type:        AgentType,
 			Description: "Obtém um único agente pelo seu ID.",
 			Args: graphql.FieldConfigArgument{
 				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
@@ -60,8 +124,9 @@ func GetQueryFields(service Service) graphql.Fields {
 			},
 		},
 		"searchAgents": &graphql.Field{
-			Type:        graphql.NewList(agentType),
-			Description: "Busca agentes por nome e/ou domínio.",
+			Type:              graphql.NewList(AgentType),
+			Description:       "Busca agentes por nome e/ou domínio.",
+			DeprecationReason: "Use agents com filter.text.",
 			Args: graphql.FieldConfigArgument{
 				"name":   &graphql.ArgumentConfig{Type: graphql.String},
 				"domain": &graphql.ArgumentConfig{Type: graphql.String},
@@ -78,28 +143,36 @@ func GetQueryFields(service Service) graphql.Fields {
 func GetMutationFields(service Service) graphql.Fields {
 	return graphql.Fields{
 		"createAgent": &graphql.Field{
-			Type:        agentType,
+			Type:        AgentType,
 			Description: "Cria um novo agente.",
 			Args: graphql.FieldConfigArgument{
 				"name":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 				"domain": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"locale": &graphql.ArgumentConfig{Type: graphql.String, Description: "Padrão: pt-BR."},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				dto := CreateAgentDTO{Name: p.Args["name"].(string), Domain: p.Args["domain"].(string)}
+				dto.Locale, _ = p.Args["locale"].(string)
 				return service.CreateAgent(dto)
 			},
 		},
 		"updateAgent": &graphql.Field{
-			Type:        agentType,
-			Description: "Atualiza um agente existente.",
+			Type:        AgentType,
+			Description: "Atualiza parcialmente um agente existente.",
 			Args: graphql.FieldConfigArgument{
-				"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"name":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-				"domain": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"version": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int), Description: "Versão lida pelo cliente."},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateAgentInputType)},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				id := p.Args["id"].(int)
-				dto := UpdateAgentDTO{Name: p.Args["name"].(string), Domain: p.Args["domain"].(string)}
+				input := patch.InputFromArgs(p, "input")
+				dto := UpdateAgentDTO{
+					Version: uint(p.Args["version"].(int)),
+					Name:    patch.Get[string](input, "name"),
+					Domain:  patch.Get[string](input, "domain"),
+					Locale:  patch.Get[string](input, "locale"),
+				}
 				return service.UpdateAgent(uint(id), dto)
 			},
 		},
@@ -121,5 +194,19 @@ func GetMutationFields(service Service) graphql.Fields {
 				return map[string]interface{}{"deletedId": id, "success": true}, nil
 			},
 		},
+		"restoreAgent": &graphql.Field{
+			Type:        AgentType,
+			Description: "Restaura um agente excluído.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := session.RequireAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(int)
+				return service.RestoreAgent(uint(id))
+			},
+		},
 	}
 }
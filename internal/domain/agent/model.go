@@ -7,34 +7,46 @@
 */
 package agent
 
-import "time"
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"gorm.io/gorm"
+)
 
 // Agent representa a entidade no banco de dados.
 type Agent struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"unique;not null" json:"name"`
-	Domain    string    `gorm:"unique;not null" json:"domain"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"unique;not null" json:"name"`
+	Domain    string         `gorm:"unique;not null" json:"domain"`
+	PlanID    *uint          `gorm:"index" json:"plan_id"`
+	Locale    string         `gorm:"size:10;not null;default:'pt-BR'" json:"locale"` // Usada para formatar valores.
+	Version   uint           `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 }
 
 // CreateAgentDTO é o Data Transfer Object para a criação de um agent.
 type CreateAgentDTO struct {
 	Name   string `json:"name"`
 	Domain string `json:"domain"`
+	Locale string `json:"locale"` // Vazio usa money.DefaultLocale.
 }
 
 // UpdateAgentDTO é o Data Transfer Object para a atualização de um agent.
+// Campos não enviados ficam como estão.
 type UpdateAgentDTO struct {
-	Name   string `json:"name"`
-	Domain string `json:"domain"`
+	Version uint                `json:"version"`
+	Name    patch.Field[string] `json:"name"`
+	Domain  patch.Field[string] `json:"domain"`
+	Locale  patch.Field[string] `json:"locale"`
 }
 
-// PaginatedAgents é a estrutura de resposta para a lista paginada de agents.
-type PaginatedAgents struct {
-	Data       []Agent `json:"data"`
-	Total      int64   `json:"total"`
-	Page       int     `json:"page"`
-	PerPage    int     `json:"per_page"`
-	TotalPages int     `json:"total_pages"`
+// AgentFilter restringe a lista de agentes. Campos vazios não filtram.
+type AgentFilter struct {
+	Text      string // Procura no nome e no domínio.
+	PlanID    *uint
+	CreatedAt pagination.TimeRange
 }
@@ -7,16 +7,27 @@
 */
 package agent
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"gorm.io/gorm"
+)
 
 // Repository define a interface para as operações de banco de dados.
 type Repository interface {
-	FindAll(page, perPage int) ([]Agent, int64, error)
+	FindAll(filter AgentFilter, sort pagination.Sort, page pagination.Page) ([]Agent, error)
+	Count(filter AgentFilter) (int64, error)
 	FindByID(id uint) (Agent, error)
+	FindByIDs(ids []uint) ([]Agent, error)
 	Search(name, domain string) ([]Agent, error)
 	Create(agent Agent) (Agent, error)
-	Update(agent Agent) (Agent, error)
+	Update(id, version uint, changes map[string]interface{}) (Agent, error)
 	Delete(id uint) error
+	Restore(id uint) error
+	FindExpired(before time.Time) ([]uint, error)
+	Purge(ids []uint) (int64, error)
 }
 
 type repository struct {
@@ -27,15 +38,31 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) FindAll(page, perPage int) ([]Agent, int64, error) {
+func (r *repository) FindAll(filter AgentFilter, sort pagination.Sort, page pagination.Page) ([]Agent, error) {
 	var agents []Agent
+	sort = sort.Allowed("name", "domain", "created_at")
+	err := r.db.Scopes(filterScope(filter), page.Scope(sort)).Find(&agents).Error
+	return agents, err
+}
+
+func (r *repository) Count(filter AgentFilter) (int64, error) {
 	var total int64
-	if err := r.db.Model(&Agent{}).Count(&total).Error; err != nil {
-		return nil, 0, err
+	err := r.db.Model(&Agent{}).Scopes(filterScope(filter)).Count(&total).Error
+	return total, err
+}
+
+// filterScope traduz o filtro em condições parametrizadas.
+func filterScope(filter AgentFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Text != "" {
+			pattern := pagination.ContainsPattern(filter.Text)
+			db = db.Where("(name ILIKE ? OR domain ILIKE ?)", pattern, pattern)
+		}
+		if filter.PlanID != nil {
+			db = db.Where("plan_id = ?", *filter.PlanID)
+		}
+		return db.Scopes(filter.CreatedAt.Scope("created_at"))
 	}
-	offset := (page - 1) * perPage
-	err := r.db.Limit(perPage).Offset(offset).Order("id asc").Find(&agents).Error
-	return agents, total, err
 }
 
 func (r *repository) FindByID(id uint) (Agent, error) {
@@ -44,6 +71,13 @@ func (r *repository) FindByID(id uint) (Agent, error) {
 	return agent, err
 }
 
+// FindByIDs busca vários agentes numa única consulta.
+func (r *repository) FindByIDs(ids []uint) ([]Agent, error) {
+	var agents []Agent
+	err := r.db.Where("id IN ?", ids).Find(&agents).Error
+	return agents, err
+}
+
 func (r *repository) Search(name, domain string) ([]Agent, error) {
 	var agents []Agent
 	query := r.db
@@ -62,11 +96,49 @@ func (r *repository) Create(agent Agent) (Agent, error) {
 	return agent, err
 }
 
-func (r *repository) Update(agent Agent) (Agent, error) {
-	err := r.db.Save(&agent).Error
-	return agent, err
+// Update altera só as colunas em changes, e somente se a versão no banco ainda for version,
+// incrementando-a. Se outra atualização chegou antes, retorna apperror.ErrVersionMismatch.
+func (r *repository) Update(id, version uint, changes map[string]interface{}) (Agent, error) {
+	changes["version"] = gorm.Expr("version + 1")
+	result := r.db.Model(&Agent{}).Where("id = ? AND version = ?", id, version).Updates(changes)
+	if result.Error != nil {
+		return Agent{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Agent{}, apperror.ErrVersionMismatch
+	}
+	return r.FindByID(id)
 }
 
 func (r *repository) Delete(id uint) error {
 	return r.db.Delete(&Agent{}, id).Error
 }
+
+// Restore tira um agente da lixeira.
+func (r *repository) Restore(id uint) error {
+	result := r.db.Unscoped().Model(&Agent{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindExpired lista os agentes excluídos antes de before.
+func (r *repository) FindExpired(before time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Unscoped().Model(&Agent{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Order("id asc").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Purge remove definitivamente os agentes informados que continuam na lixeira. Os dados
+// deles nos outros domínios são apagados antes, pelo serviço.
+func (r *repository) Purge(ids []uint) (int64, error) {
+	result := r.db.Unscoped().Where("id IN ? AND deleted_at IS NOT NULL", ids).Delete(&Agent{})
+	return result.RowsAffected, result.Error
+}
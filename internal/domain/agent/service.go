@@ -7,66 +7,131 @@
 */
 package agent
 
-import "math"
+import (
+	"errors"
+	"time"
 
-const PageSize = 8
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
 
 type Service interface {
-	GetAllAgents(page int) (PaginatedAgents, error)
+	GetAllAgents(filter AgentFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[Agent], error)
 	GetAgentByID(id uint) (Agent, error)
+	GetAgentsByIDs(ids []uint) ([]Agent, error)
 	SearchAgents(name, domain string) ([]Agent, error)
 	CreateAgent(dto CreateAgentDTO) (Agent, error)
 	UpdateAgent(id uint, dto UpdateAgentDTO) (Agent, error)
 	DeleteAgent(id uint) error
+	RestoreAgent(id uint) (Agent, error)
+	PurgeTrash(before time.Time) (int64, error)
+}
+
+// DataPurger apaga os registros de um domínio que pertencem aos agentes removidos.
+type DataPurger interface {
+	PurgeAgents(agentIDs []uint) error
 }
 
 type service struct {
-	repo Repository
+	repo    Repository
+	purgers []DataPurger
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// NewService cria o serviço. Ao remover definitivamente um agente, cada um dos purgers
+// apaga, na ordem, os dados do agente no seu domínio.
+func NewService(repo Repository, purgers ...DataPurger) Service {
+	return &service{repo: repo, purgers: purgers}
 }
 
-func (s *service) GetAllAgents(page int) (PaginatedAgents, error) {
-	agents, total, err := s.repo.FindAll(page, PageSize)
+func (s *service) GetAllAgents(filter AgentFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[Agent], error) {
+	agents, err := s.repo.FindAll(filter, sort, page)
 	if err != nil {
-		return PaginatedAgents{}, err
-	}
-	totalPages := int(math.Ceil(float64(total) / float64(PageSize)))
-	return PaginatedAgents{
-		Data:       agents,
-		Total:      total,
-		Page:       page,
-		PerPage:    PageSize,
-		TotalPages: totalPages,
-	}, nil
+		return pagination.Connection[Agent]{}, err
+	}
+	conn := pagination.NewConnection(agents, page, func(item Agent) pagination.Cursor {
+		cursor := sort.Cursor(item.ID)
+		switch sort.Column {
+		case "name":
+			cursor.Value = item.Name
+		case "domain":
+			cursor.Value = item.Domain
+		case "created_at":
+			cursor.Value = item.CreatedAt
+		}
+		return cursor
+	})
+	return conn.WithCount(func() (int64, error) { return s.repo.Count(filter) }), nil
 }
 
 func (s *service) GetAgentByID(id uint) (Agent, error) {
 	return s.repo.FindByID(id)
 }
 
+func (s *service) GetAgentsByIDs(ids []uint) ([]Agent, error) {
+	return s.repo.FindByIDs(ids)
+}
+
 func (s *service) SearchAgents(name, domain string) ([]Agent, error) {
 	return s.repo.Search(name, domain)
 }
 
 func (s *service) CreateAgent(dto CreateAgentDTO) (Agent, error) {
+	if err := dto.Validate(); err != nil {
+		return Agent{}, err
+	}
 	agent := Agent{
 		Name:   dto.Name,
 		Domain: dto.Domain,
+		Locale: dto.Locale,
+	}
+	if agent.Locale == "" {
+		agent.Locale = money.DefaultLocale
 	}
 	return s.repo.Create(agent)
 }
 
 func (s *service) UpdateAgent(id uint, dto UpdateAgentDTO) (Agent, error) {
+	if err := dto.Validate(); err != nil {
+		return Agent{}, err
+	}
 	agentToUpdate, err := s.repo.FindByID(id)
 	if err != nil {
 		return Agent{}, err
 	}
-	agentToUpdate.Name = dto.Name
-	agentToUpdate.Domain = dto.Domain
-	return s.repo.Update(agentToUpdate)
+	if agentToUpdate.Version != dto.Version {
+		return Agent{}, apperror.Conflict(agentToUpdate)
+	}
+
+	changes := patch.Changes{}
+	if err := patch.Required(changes, "name", dto.Name); err != nil {
+		return Agent{}, err
+	}
+	if err := patch.Required(changes, "domain", dto.Domain); err != nil {
+		return Agent{}, err
+	}
+	if err := patch.Required(changes, "locale", dto.Locale); err != nil {
+		return Agent{}, err
+	}
+	if len(changes) == 0 {
+		return agentToUpdate, nil
+	}
+
+	updated, err := s.repo.Update(id, dto.Version, changes)
+	if errors.Is(err, apperror.ErrVersionMismatch) {
+		return s.conflict(id)
+	}
+	return updated, err
+}
+
+// conflict relê o agente para devolver ao cliente o estado que venceu a disputa.
+func (s *service) conflict(id uint) (Agent, error) {
+	current, err := s.repo.FindByID(id)
+	if err != nil {
+		return Agent{}, err
+	}
+	return Agent{}, apperror.Conflict(current)
 }
 
 func (s *service) DeleteAgent(id uint) error {
@@ -76,3 +141,26 @@ func (s *service) DeleteAgent(id uint) error {
 	}
 	return s.repo.Delete(id)
 }
+
+func (s *service) RestoreAgent(id uint) (Agent, error) {
+	if err := s.repo.Restore(id); err != nil {
+		return Agent{}, err
+	}
+	return s.repo.FindByID(id)
+}
+
+// PurgeTrash remove os agentes excluídos antes de before. Os dados dos domínios saem antes
+// do agente: se algum purger falhar, o agente fica na lixeira e a limpeza é refeita na
+// próxima execução.
+func (s *service) PurgeTrash(before time.Time) (int64, error) {
+	ids, err := s.repo.FindExpired(before)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	for _, purger := range s.purgers {
+		if err := purger.PurgeAgents(ids); err != nil {
+			return 0, err
+		}
+	}
+	return s.repo.Purge(ids)
+}
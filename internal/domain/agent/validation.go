@@ -0,0 +1,39 @@
+/*
+|------------------------------------------------
+| File: internal/domain/agent/validation.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package agent
+
+import (
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+)
+
+const maxNameLength = 120
+
+// optionalLocale aceita vazio, que no cadastro vira a localidade padrão.
+func optionalLocale(value string) *validate.Failure {
+	if value == "" {
+		return nil
+	}
+	return validate.OneOf(money.Locales()...)(value)
+}
+
+func (dto CreateAgentDTO) Validate() error {
+	return validate.All(
+		validate.Field("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Field("domain", dto.Domain, validate.Required, validate.Domain),
+		validate.Field("locale", dto.Locale, optionalLocale),
+	)
+}
+
+func (dto UpdateAgentDTO) Validate() error {
+	return validate.All(
+		validate.Patch("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Patch("domain", dto.Domain, validate.Required, validate.Domain),
+		validate.Patch("locale", dto.Locale, validate.OneOf(money.Locales()...)),
+	)
+}
@@ -0,0 +1,297 @@
+/*
+|------------------------------------------------
+| File: internal/domain/asset/graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package asset
+
+import (
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+var kindEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "UploadKind",
+	Description: "Finalidade do arquivo enviado, que define os tipos aceitos.",
+	Values: graphql.EnumValueConfigMap{
+		"PRODUCT_IMAGE":  &graphql.EnumValueConfig{Value: KindProductImage, Description: "Imagem de produto (JPEG, PNG ou WebP)."},
+		"CATEGORY_IMAGE": &graphql.EnumValueConfig{Value: KindCategoryImage, Description: "Imagem de categoria (JPEG, PNG ou WebP)."},
+	},
+})
+
+var variantType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "AssetVariant",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"key":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"url":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	},
+)
+
+// AssetType é exportado para os campos de relacionamento (Product.image_asset, ...).
+// Os tamanhos usam Float porque graphql.Int é limitado a 32 bits.
+var AssetType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Asset",
+		Fields: graphql.Fields{
+			"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"agent_id":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"kind":         &graphql.Field{Type: graphql.NewNonNull(kindEnum)},
+			"key":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"url":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"content_type": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"size":         &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "Bytes do original e das variantes."},
+			"width":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"height":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"alt_text":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"uploaded_by":  &graphql.Field{Type: graphql.Int, Description: "ID do usuário que enviou o arquivo."},
+			"usage_count":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Quantidade de registros que usam o asset."},
+			"unused_since": &graphql.Field{Type: graphql.String, Description: "Desde quando o asset está sem uso; ele é apagado depois do prazo de retenção."},
+			"created_at":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"updated_at":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"variants": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(variantType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var asset Asset
+					switch source := p.Source.(type) {
+					case Asset:
+						asset = source
+					case *Asset:
+						asset = *source
+					}
+					variants := make([]map[string]interface{}, 0, len(asset.Variants))
+					for name, variant := range asset.Variants {
+						variants = append(variants, map[string]interface{}{"name": name, "key": variant.Key, "url": variant.URL})
+					}
+					sort.Slice(variants, func(i, j int) bool {
+						return variants[i]["name"].(string) < variants[j]["name"].(string)
+					})
+					return variants, nil
+				},
+			},
+		},
+	},
+)
+
+var assetConnectionType = pagination.ConnectionType("Asset", AssetType)
+
+var assetFilterInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "AssetFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"kind":      &graphql.InputObjectFieldConfig{Type: kindEnum},
+			"unused":    &graphql.InputObjectFieldConfig{Type: graphql.Boolean, Description: "true lista só os assets sem uso; false, só os em uso."},
+			"createdAt": &graphql.InputObjectFieldConfig{Type: pagination.TimeRangeInput},
+		},
+	},
+)
+
+var assetSortInputType = pagination.SortInput("Asset", graphql.EnumValueConfigMap{
+	"CREATED_AT": &graphql.EnumValueConfig{Value: "created_at"},
+	"SIZE":       &graphql.EnumValueConfig{Value: "size"},
+})
+
+// updateAssetInputType tem todos os campos opcionais: o que não for enviado não muda.
+var updateAssetInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UpdateAssetInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"altText": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	},
+)
+
+// filterFromArgs lê o argumento filter da query assets.
+func filterFromArgs(args map[string]interface{}) (AssetFilter, error) {
+	var filter AssetFilter
+	input, ok := args["filter"].(map[string]interface{})
+	if !ok {
+		return filter, nil
+	}
+	filter.Kind, _ = input["kind"].(Kind)
+	if v, ok := input["unused"].(bool); ok {
+		filter.Unused = &v
+	}
+	createdAt, err := pagination.TimeRangeFromArgs(input["createdAt"])
+	if err != nil {
+		return filter, err
+	}
+	filter.CreatedAt = createdAt
+	return filter, nil
+}
+
+func GetQueryFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"assets": &graphql.Field{
+			Type:        assetConnectionType,
+			Description: "Lista os arquivos da biblioteca de mídia de um agente.",
+			Args: pagination.Args(graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"filter":  &graphql.ArgumentConfig{Type: assetFilterInputType},
+				"sort":    &graphql.ArgumentConfig{Type: assetSortInputType},
+			}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				page, err := pagination.PageFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				filter, err := filterFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return service.GetAssets(uint(agentId), filter, pagination.SortFromArgs(p.Args), page)
+			},
+		},
+		"asset": &graphql.Field{
+			Type:        AssetType,
+			Description: "Obtém um arquivo da biblioteca de mídia pelo ID, dentro de um agente.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(int)
+				return service.GetAssetByID(uint(agentId), uint(id))
+			},
+		},
+	}
+}
+
+var httpHeaderType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "HttpHeader",
+		Fields: graphql.Fields{
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	},
+)
+
+var uploadTicketType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name:        "UploadTicket",
+		Description: "Requisição que o cliente deve fazer para enviar o arquivo direto ao armazenamento.",
+		Fields: graphql.Fields{
+			"key":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"url":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"method": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"headers": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(httpHeaderType))),
+				Description: "Headers que devem ser enviados exatamente como estão.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					ticket, _ := p.Source.(UploadTicket)
+					headers := make([]map[string]interface{}, 0, len(ticket.Headers))
+					for name, value := range ticket.Headers {
+						headers = append(headers, map[string]interface{}{"name": name, "value": value})
+					}
+					sort.Slice(headers, func(i, j int) bool {
+						return headers[i]["name"].(string) < headers[j]["name"].(string)
+					})
+					return headers, nil
+				},
+			},
+			"expires_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	},
+)
+
+func GetMutationFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"requestUploadUrl": &graphql.Field{
+			Type:        uploadTicketType,
+			Description: "Gera uma URL assinada para enviar um arquivo direto ao armazenamento. Depois do envio, chame confirmUpload com a chave.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"kind":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(kindEnum)},
+				"contentType": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"size":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float), Description: "Tamanho exato do arquivo, em bytes."},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				kind, _ := p.Args["kind"].(Kind)
+				contentType, _ := p.Args["contentType"].(string)
+				size, _ := p.Args["size"].(float64)
+				return service.RequestUpload(uint(agentId), RequestUploadDTO{Kind: kind, ContentType: contentType, Size: int64(size)})
+			},
+		},
+		"confirmUpload": &graphql.Field{
+			Type:        AssetType,
+			Description: "Confere o arquivo enviado pela URL de requestUploadUrl e o registra como asset do agente.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"key":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"altText": &graphql.ArgumentConfig{Type: graphql.String, Description: "Texto alternativo, para acessibilidade."},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				identity, err := session.RequireAgent(p.Context, uint(agentId))
+				if err != nil {
+					return nil, err
+				}
+				dto := ConfirmUploadDTO{UploadedBy: &identity.UserID}
+				dto.Key, _ = p.Args["key"].(string)
+				dto.AltText, _ = p.Args["altText"].(string)
+				return service.ConfirmUpload(uint(agentId), dto)
+			},
+		},
+		"updateAsset": &graphql.Field{
+			Type:        AssetType,
+			Description: "Altera os dados editáveis de um arquivo da biblioteca de mídia.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateAssetInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(int)
+				input := patch.InputFromArgs(p, "input")
+				return service.UpdateAsset(uint(agentId), uint(id), UpdateAssetDTO{AltText: patch.Get[string](input, "altText")})
+			},
+		},
+		"deleteAsset": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name:   "DeleteAssetPayload",
+				Fields: graphql.Fields{"deletedId": &graphql.Field{Type: graphql.Int}, "success": &graphql.Field{Type: graphql.Boolean}},
+			}),
+			Description: "Apaga um arquivo sem uso da biblioteca de mídia.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(int)
+				if err := service.DeleteAsset(uint(agentId), uint(id)); err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"deletedId": id, "success": true}, nil
+			},
+		},
+	}
+}
@@ -0,0 +1,92 @@
+/*
+|------------------------------------------------
+| File: internal/domain/asset/images.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package asset
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/imaging"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/storage"
+)
+
+// imageNamespace é o namespace dos UUIDs (v5) das imagens, derivados do conteúdo: o
+// mesmo arquivo enviado duas vezes pelo agente cai na mesma chave e é gravado uma vez só.
+var imageNamespace = uuid.MustParse("b5554536-3c56-4e10-a029-3ca687496e68")
+
+// imageFolders é a pasta de cada finalidade dentro da pasta do agente.
+var imageFolders = map[Kind]string{
+	KindProductImage:  "products",
+	KindCategoryImage: "categories",
+}
+
+// ImagePrefix é a pasta das imagens do agente com a finalidade informada.
+func ImagePrefix(agentID uint, kind Kind) string {
+	return fmt.Sprintf("agents/%d/%s/", agentID, imageFolders[kind])
+}
+
+// ImageKey monta a chave de uma versão da imagem: agents/<agente>/<pasta>/<uuid>.<ext>
+// para o original e <uuid>-<variante>.<ext> para as demais.
+func ImageKey(agentID uint, kind Kind, name uuid.UUID, variant, ext string) string {
+	if variant == "original" {
+		return ImagePrefix(agentID, kind) + name.String() + ext
+	}
+	return ImagePrefix(agentID, kind) + name.String() + "-" + variant + ext
+}
+
+// ImageName deriva o nome da imagem do conteúdo do original.
+func ImageName(original []byte) uuid.UUID {
+	return uuid.NewSHA1(imageNamespace, original)
+}
+
+// storedImage é o resultado de storeImage.
+type storedImage struct {
+	asset   Asset    // Ainda sem ID, dono nem texto alternativo.
+	written []string // Chaves gravadas nesta chamada; vazio se a imagem já existia.
+}
+
+// storeImage grava o original e as variantes processadas na pasta do agente. Versões que
+// já estão no store não são regravadas. Se uma gravação falha, as feitas nesta chamada
+// são desfeitas.
+func storeImage(store storage.Store, agentID uint, kind Kind, images []imaging.Image) (storedImage, error) {
+	name := ImageName(images[0].Data)
+	result := storedImage{asset: Asset{Kind: kind, Variants: Variants{}}}
+	for _, img := range images {
+		key := ImageKey(agentID, kind, name, img.Name, img.Ext)
+		exists, err := store.Exists(key)
+		if err == nil && !exists {
+			err = store.Put(key, img.ContentType, bytes.NewReader(img.Data))
+			result.written = append(result.written, key)
+		}
+		if err != nil {
+			deleteKeys(store, result.written)
+			return storedImage{}, err
+		}
+		result.asset.Size += int64(len(img.Data))
+		if img.Name == "original" {
+			result.asset.Key = key
+			result.asset.URL = store.URL(key)
+			result.asset.ContentType = img.ContentType
+			result.asset.Width = img.Width
+			result.asset.Height = img.Height
+			continue
+		}
+		result.asset.Variants[img.Name] = Variant{Key: key, URL: store.URL(key)}
+	}
+	return result, nil
+}
+
+func deleteKeys(store storage.Store, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(key); err != nil {
+			log.Printf("Falha ao apagar %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*
+|------------------------------------------------
+| File: internal/domain/asset/model.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package asset
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
+
+// Kind é a finalidade do arquivo, que define os tipos de conteúdo aceitos.
+type Kind string
+
+const (
+	KindProductImage  Kind = "product_image"
+	KindCategoryImage Kind = "category_image"
+)
+
+// contentTypes são os tipos aceitos em cada finalidade, com a extensão usada na chave.
+var contentTypes = map[Kind]map[string]string{
+	KindProductImage:  imageTypes,
+	KindCategoryImage: imageTypes,
+}
+
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// Tipos de registro que usam assets.
+const (
+	OwnerProduct  = "product"
+	OwnerCategory = "category"
+)
+
+// Owner é o registro que usa um asset.
+type Owner struct {
+	Type string
+	ID   uint
+}
+
+// Asset é um arquivo enviado por um agente e já conferido pela API. Assets sem uso
+// (UsageCount zero) são apagados, com o arquivo, depois do prazo de retenção.
+type Asset struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	AgentID     uint       `gorm:"not null;index" json:"agent_id"`
+	Kind        Kind       `gorm:"not null" json:"kind"`
+	Key         string     `gorm:"uniqueIndex;not null" json:"key"`
+	URL         string     `gorm:"not null" json:"url"`
+	ContentType string     `gorm:"not null" json:"content_type"`
+	Size        int64      `gorm:"not null" json:"size"` // Bytes do original e das variantes.
+	Width       int        `gorm:"not null;default:0" json:"width"`
+	Height      int        `gorm:"not null;default:0" json:"height"`
+	Variants    Variants   `gorm:"not null;default:'{}'" json:"variants"`
+	AltText     string     `gorm:"not null;default:''" json:"alt_text"`
+	UploadedBy  *uint      `json:"uploaded_by"` // Usuário que enviou; nulo em processos internos.
+	UnusedSince *time.Time `gorm:"index" json:"unused_since"`
+	UsageCount  int64      `gorm:"->;-:migration" json:"usage_count"` // Calculado nas consultas.
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Variant é uma versão redimensionada de uma imagem.
+type Variant struct {
+	Key string `json:"key"`
+	URL string `json:"url"`
+}
+
+// Variants são as versões de uma imagem pelo nome (thumb, card, full).
+type Variants map[string]Variant
+
+func (Variants) GormDataType() string {
+	return "jsonb"
+}
+
+func (v Variants) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func (v *Variants) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*v = Variants{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), v)
+	case []byte:
+		return json.Unmarshal(value, v)
+	default:
+		return fmt.Errorf("Variants: tipo não suportado %T", src)
+	}
+}
+
+// VariantURL retorna a URL da variante, ou a do original se ela não existir.
+func (a Asset) VariantURL(name string) string {
+	if variant, ok := a.Variants[name]; ok {
+		return variant.URL
+	}
+	return a.URL
+}
+
+// Keys lista as chaves do original e das variantes no store.
+func (a Asset) Keys() []string {
+	keys := []string{a.Key}
+	for _, variant := range a.Variants {
+		keys = append(keys, variant.Key)
+	}
+	return keys
+}
+
+// AssetUsage registra que um asset é usado por um registro de outro domínio.
+type AssetUsage struct {
+	AssetID   uint   `gorm:"primaryKey;autoIncrement:false"`
+	OwnerType string `gorm:"primaryKey"`
+	OwnerID   uint   `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time
+}
+
+// AssetFilter restringe a lista de assets. Campos vazios não filtram.
+type AssetFilter struct {
+	Kind      Kind
+	Unused    *bool // Só os sem uso (true) ou só os em uso (false).
+	CreatedAt pagination.TimeRange
+}
+
+// UploadImageDTO é uma imagem enviada pela API (multipart ou envio direto já conferido).
+type UploadImageDTO struct {
+	Kind       Kind
+	Body       io.Reader
+	Size       int64 // Tamanho informado, usado para conferir a cota antes de ler o arquivo.
+	AltText    string
+	UploadedBy *uint
+}
+
+// UpdateAssetDTO altera os dados editáveis de um asset.
+type UpdateAssetDTO struct {
+	AltText patch.Field[string] `json:"alt_text"`
+}
+
+// RequestUploadDTO descreve o arquivo que o cliente quer enviar direto ao armazenamento.
+type RequestUploadDTO struct {
+	Kind        Kind   `json:"kind"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// ConfirmUploadDTO confirma um envio direto feito com a URL de RequestUpload.
+type ConfirmUploadDTO struct {
+	Key        string `json:"key"`
+	AltText    string `json:"alt_text"`
+	UploadedBy *uint  `json:"uploaded_by"`
+}
+
+// UploadTicket é a autorização para um envio direto: o cliente faz a requisição
+// (Method, URL e Headers) e depois confirma o envio com a Key.
+type UploadTicket struct {
+	Key       string            `json:"key"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
@@ -0,0 +1,212 @@
+/*
+|------------------------------------------------
+| File: internal/domain/asset/repository.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package asset
+
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	FindAll(agentID uint, filter AssetFilter, sort pagination.Sort, page pagination.Page) ([]Asset, error)
+	Count(agentID uint, filter AssetFilter) (int64, error)
+	FindByID(agentID, id uint) (Asset, error)
+	FindByIDs(ids []uint) ([]Asset, error)
+	FindByKey(agentID uint, key string) (Asset, error)
+	FindByURL(agentID uint, url string) (Asset, error)
+	FindOrCreate(asset Asset) (Asset, bool, error)
+	Update(agentID, id uint, changes map[string]interface{}) (Asset, error)
+	Delete(agentID, id uint) error
+	Link(assetID uint, owner Owner) error
+	Unlink(ownerType string, ownerIDs []uint, assetIDs []uint) error
+	FindUnusedBefore(before time.Time, limit int) ([]Asset, error)
+	DeleteUnused(id uint, before time.Time) (bool, error)
+	DeleteByAgents(agentIDs []uint, limit int) ([]Asset, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// usageCount é a subconsulta que preenche Asset.UsageCount.
+const usageCount = "(SELECT COUNT(*) FROM asset_usages WHERE asset_usages.asset_id = assets.id) AS usage_count"
+
+// withUsage seleciona o asset junto com a contagem de uso.
+func withUsage(db *gorm.DB) *gorm.DB {
+	return db.Select("assets.*, " + usageCount)
+}
+
+func (r *repository) FindAll(agentID uint, filter AssetFilter, sort pagination.Sort, page pagination.Page) ([]Asset, error) {
+	var assets []Asset
+	sort = sort.Allowed("created_at", "size")
+	err := r.db.Scopes(withUsage).Where("agent_id = ?", agentID).Scopes(filterScope(filter), page.Scope(sort)).Find(&assets).Error
+	return assets, err
+}
+
+func (r *repository) Count(agentID uint, filter AssetFilter) (int64, error) {
+	var total int64
+	err := r.db.Model(&Asset{}).Where("agent_id = ?", agentID).Scopes(filterScope(filter)).Count(&total).Error
+	return total, err
+}
+
+// filterScope traduz o filtro em condições parametrizadas.
+func filterScope(filter AssetFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Kind != "" {
+			db = db.Where("kind = ?", filter.Kind)
+		}
+		if filter.Unused != nil {
+			db = db.Where("(unused_since IS NOT NULL) = ?", *filter.Unused)
+		}
+		return db.Scopes(filter.CreatedAt.Scope("created_at"))
+	}
+}
+
+func (r *repository) FindByID(agentID, id uint) (Asset, error) {
+	var asset Asset
+	err := r.db.Scopes(withUsage).Where("agent_id = ?", agentID).First(&asset, id).Error
+	return asset, err
+}
+
+// FindByIDs busca vários assets numa única consulta.
+func (r *repository) FindByIDs(ids []uint) ([]Asset, error) {
+	var assets []Asset
+	err := r.db.Scopes(withUsage).Where("id IN ?", ids).Find(&assets).Error
+	return assets, err
+}
+
+func (r *repository) FindByKey(agentID uint, key string) (Asset, error) {
+	var asset Asset
+	err := r.db.Scopes(withUsage).Where("agent_id = ? AND key = ?", agentID, key).First(&asset).Error
+	return asset, err
+}
+
+// FindByURL busca o asset pela URL do original ou de qualquer variante. O jsonpath vai como
+// parâmetro porque o "?" dele seria lido pelo GORM como placeholder.
+func (r *repository) FindByURL(agentID uint, url string) (Asset, error) {
+	var asset Asset
+	err := r.db.Scopes(withUsage).
+		Where("agent_id = ? AND (url = ? OR jsonb_path_exists(variants, ?::jsonpath, jsonb_build_object('url', ?::text)))",
+			agentID, url, "$.*.url ? (@ == $url)", url).
+		First(&asset).Error
+	return asset, err
+}
+
+// FindOrCreate grava o asset, ou retorna o já registrado com a mesma chave. O booleano
+// informa se o asset foi criado agora.
+func (r *repository) FindOrCreate(asset Asset) (Asset, bool, error) {
+	result := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).Create(&asset)
+	if result.Error != nil {
+		return Asset{}, false, result.Error
+	}
+	created := result.RowsAffected > 0
+	found, err := r.FindByKey(asset.AgentID, asset.Key)
+	return found, created, err
+}
+
+func (r *repository) Update(agentID, id uint, changes map[string]interface{}) (Asset, error) {
+	result := r.db.Model(&Asset{}).Where("agent_id = ? AND id = ?", agentID, id).Updates(changes)
+	if result.Error != nil {
+		return Asset{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Asset{}, gorm.ErrRecordNotFound
+	}
+	return r.FindByID(agentID, id)
+}
+
+func (r *repository) Delete(agentID, id uint) error {
+	return r.db.Where("agent_id = ?", agentID).Delete(&Asset{}, id).Error
+}
+
+// Link registra o uso do asset pelo dono e tira o asset da contagem de sem uso.
+func (r *repository) Link(assetID uint, owner Owner) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		usage := AssetUsage{AssetID: assetID, OwnerType: owner.Type, OwnerID: owner.ID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Asset{}).Where("id = ?", assetID).Update("unused_since", nil).Error
+	})
+}
+
+// Unlink remove os usos dos donos informados; com assetIDs, só os desses assets. Os
+// assets que ficam sem nenhum uso passam a contar o prazo de retenção.
+func (r *repository) Unlink(ownerType string, ownerIDs []uint, assetIDs []uint) error {
+	if len(ownerIDs) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var removed []AssetUsage
+		query := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "asset_id"}}}).
+			Where("owner_type = ? AND owner_id IN ?", ownerType, ownerIDs)
+		if len(assetIDs) > 0 {
+			query = query.Where("asset_id IN ?", assetIDs)
+		}
+		if err := query.Delete(&removed).Error; err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(removed))
+		for i, usage := range removed {
+			ids[i] = usage.AssetID
+		}
+		return tx.Model(&Asset{}).
+			Where("id IN ? AND unused_since IS NULL", ids).
+			Where("NOT EXISTS (SELECT 1 FROM asset_usages WHERE asset_usages.asset_id = assets.id)").
+			Update("unused_since", time.Now()).Error
+	})
+}
+
+// FindUnusedBefore lista até limit assets de todos os agentes sem uso desde antes de before.
+func (r *repository) FindUnusedBefore(before time.Time, limit int) ([]Asset, error) {
+	var assets []Asset
+	err := r.db.Where("unused_since < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM asset_usages WHERE asset_usages.asset_id = assets.id)").
+		Order("id asc").Limit(limit).
+		Find(&assets).Error
+	return assets, err
+}
+
+// DeleteUnused apaga o asset só se ele continua sem uso desde antes de before, conferindo
+// na própria exclusão: um asset vinculado depois do FindUnusedBefore não é apagado.
+func (r *repository) DeleteUnused(id uint, before time.Time) (bool, error) {
+	result := r.db.Where("id = ? AND unused_since IS NOT NULL AND unused_since < ?", id, before).
+		Where("NOT EXISTS (SELECT 1 FROM asset_usages WHERE asset_usages.asset_id = assets.id)").
+		Delete(&Asset{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteByAgents apaga até limit assets dos agentes, em uso ou não, junto com os usos deles,
+// e retorna os apagados para os arquivos serem removidos.
+func (r *repository) DeleteByAgents(agentIDs []uint, limit int) ([]Asset, error) {
+	var assets []Asset
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_id IN ?", agentIDs).Order("id asc").Limit(limit).Find(&assets).Error; err != nil || len(assets) == 0 {
+			return err
+		}
+		ids := make([]uint, len(assets))
+		for i, asset := range assets {
+			ids[i] = asset.ID
+		}
+		if err := tx.Where("asset_id IN ?", ids).Delete(&AssetUsage{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&Asset{}).Error
+	})
+	return assets, err
+}
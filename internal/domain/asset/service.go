@@ -0,0 +1,386 @@
+/*
+|------------------------------------------------
+| File: internal/domain/asset/service.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package asset
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/imaging"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/storage"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+	"gorm.io/gorm"
+)
+
+// uploadURLTTL é a validade das URLs de envio direto.
+const uploadURLTTL = 15 * time.Minute
+
+// purgeBatch é quantos assets sem uso são apagados por consulta na coleta.
+const purgeBatch = 100
+
+// Linker é a parte do serviço usada pelos domínios que guardam referências a assets.
+type Linker interface {
+	GetAssetByID(agentID, id uint) (Asset, error)
+	GetAssetByURL(agentID uint, url string) (Asset, error)
+	UploadImage(agentID uint, dto UploadImageDTO) (Asset, error)
+	AdoptImage(agentID uint, kind Kind, keys map[string]string) (Asset, error)
+	Link(agentID, assetID uint, owner Owner) (Asset, error)
+	Unlink(owner Owner, assetIDs ...uint) error
+	UnlinkOwners(ownerType string, ownerIDs []uint) error
+}
+
+type Service interface {
+	Linker
+	GetAssets(agentID uint, filter AssetFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[Asset], error)
+	GetAssetsByIDs(ids []uint) ([]Asset, error)
+	UpdateAsset(agentID, id uint, dto UpdateAssetDTO) (Asset, error)
+	DeleteAsset(agentID, id uint) error
+	RequestUpload(agentID uint, dto RequestUploadDTO) (UploadTicket, error)
+	ConfirmUpload(agentID uint, dto ConfirmUploadDTO) (Asset, error)
+	PurgeTrash(before time.Time) (int64, error)
+	PurgeAgents(agentIDs []uint) error
+}
+
+type service struct {
+	repo     Repository
+	quota    plan.QuotaChecker
+	store    storage.Store
+	maxBytes int64
+}
+
+// NewService cria o serviço. maxBytes é o maior arquivo aceito no envio direto.
+func NewService(repo Repository, quota plan.QuotaChecker, store storage.Store, maxBytes int64) Service {
+	return &service{repo: repo, quota: quota, store: store, maxBytes: maxBytes}
+}
+
+func (s *service) GetAssets(agentID uint, filter AssetFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[Asset], error) {
+	assets, err := s.repo.FindAll(agentID, filter, sort, page)
+	if err != nil {
+		return pagination.Connection[Asset]{}, err
+	}
+	conn := pagination.NewConnection(assets, page, func(item Asset) pagination.Cursor {
+		cursor := sort.Cursor(item.ID)
+		switch sort.Column {
+		case "created_at":
+			cursor.Value = item.CreatedAt
+		case "size":
+			cursor.Value = item.Size
+		}
+		return cursor
+	})
+	return conn.WithCount(func() (int64, error) { return s.repo.Count(agentID, filter) }), nil
+}
+
+func (s *service) GetAssetByID(agentID, id uint) (Asset, error) {
+	return s.repo.FindByID(agentID, id)
+}
+
+// GetAssetByURL busca o asset do agente pela URL do original ou de uma variante. Serve aos
+// clientes que ainda gravam a URL retornada pelo upload em vez do ID do asset.
+func (s *service) GetAssetByURL(agentID uint, url string) (Asset, error) {
+	return s.repo.FindByURL(agentID, url)
+}
+
+func (s *service) GetAssetsByIDs(ids []uint) ([]Asset, error) {
+	return s.repo.FindByIDs(ids)
+}
+
+func (s *service) UpdateAsset(agentID, id uint, dto UpdateAssetDTO) (Asset, error) {
+	if err := dto.Validate(); err != nil {
+		return Asset{}, err
+	}
+	changes := patch.Changes{}
+	patch.Nullable(changes, "alt_text", dto.AltText)
+	if len(changes) == 0 {
+		return s.repo.FindByID(agentID, id)
+	}
+	return s.repo.Update(agentID, id, changes)
+}
+
+// DeleteAsset apaga o asset e seus arquivos. Assets em uso não podem ser apagados.
+func (s *service) DeleteAsset(agentID, id uint) error {
+	asset, err := s.repo.FindByID(agentID, id)
+	if err != nil {
+		return err
+	}
+	if asset.UsageCount > 0 {
+		return apperror.New(apperror.CodeConflict, apperror.MsgAssetInUse, asset.UsageCount)
+	}
+	return s.remove(asset)
+}
+
+// remove apaga o registro, os arquivos e devolve o espaço à cota do agente.
+func (s *service) remove(asset Asset) error {
+	if err := s.repo.Delete(asset.AgentID, asset.ID); err != nil {
+		return err
+	}
+	s.release(asset)
+	return nil
+}
+
+// release apaga os arquivos de um asset já excluído e devolve o espaço à cota do agente.
+func (s *service) release(asset Asset) {
+	deleteKeys(s.store, asset.Keys())
+	if err := s.quota.AddStorageUsage(asset.AgentID, -asset.Size); err != nil {
+		log.Printf("Falha ao registrar uso de armazenamento do agente %d: %v", asset.AgentID, err)
+	}
+}
+
+// UploadImage valida e processa a imagem, grava o original e as variantes na pasta do
+// agente e a registra como asset. A mesma imagem enviada de novo pelo agente reaproveita
+// o asset existente, sem gravar nem consumir a cota outra vez.
+func (s *service) UploadImage(agentID uint, dto UploadImageDTO) (Asset, error) {
+	if _, ok := imageFolders[dto.Kind]; !ok {
+		return Asset{}, apperror.Invalid("kind", apperror.MsgOneOf, string(KindProductImage)+", "+string(KindCategoryImage))
+	}
+	if err := s.quota.CheckQuota(agentID, plan.ResourceStorage, dto.Size); err != nil {
+		return Asset{}, err
+	}
+	images, err := imaging.Process(dto.Body)
+	if err != nil {
+		return Asset{}, err
+	}
+	stored, err := storeImage(s.store, agentID, dto.Kind, images)
+	if err != nil {
+		return Asset{}, err
+	}
+
+	now := time.Now()
+	candidate := stored.asset
+	candidate.AgentID = agentID
+	candidate.AltText = dto.AltText
+	candidate.UploadedBy = dto.UploadedBy
+	candidate.UnusedSince = &now // Até ser ligado a um registro.
+	asset, created, err := s.repo.FindOrCreate(candidate)
+	if err != nil {
+		deleteKeys(s.store, stored.written)
+		return Asset{}, err
+	}
+	if created {
+		if err := s.quota.AddStorageUsage(agentID, asset.Size); err != nil {
+			log.Printf("Falha ao registrar uso de armazenamento do agente %d: %v", agentID, err)
+		}
+	}
+	return asset, nil
+}
+
+// AdoptImage registra como asset uma imagem que já está na pasta do agente, gravada antes
+// da biblioteca de mídia existir. keys são as chaves por variante e precisam incluir a
+// "original". O espaço já foi contado na cota quando o arquivo foi enviado.
+func (s *service) AdoptImage(agentID uint, kind Kind, keys map[string]string) (Asset, error) {
+	prefix := ImagePrefix(agentID, kind)
+	original, ok := keys["original"]
+	if _, known := imageFolders[kind]; !known || !ok {
+		return Asset{}, apperror.Invalid("key", apperror.MsgInvalidUploadKey)
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			return Asset{}, apperror.Invalid("key", apperror.MsgInvalidUploadKey)
+		}
+	}
+	existing, err := s.repo.FindByKey(agentID, original)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return existing, err
+	}
+
+	now := time.Now()
+	candidate := Asset{AgentID: agentID, Kind: kind, Key: original, URL: s.store.URL(original), Variants: Variants{}, UnusedSince: &now}
+	for variant, key := range keys {
+		info, err := s.store.Stat(key)
+		if err != nil {
+			return Asset{}, fmt.Errorf("ler %s: %w", key, err)
+		}
+		candidate.Size += info.Size
+		if variant == "original" {
+			candidate.ContentType = info.ContentType
+			continue
+		}
+		candidate.Variants[variant] = Variant{Key: key, URL: s.store.URL(key)}
+	}
+	body, err := s.store.Get(original)
+	if err != nil {
+		return Asset{}, fmt.Errorf("ler %s: %w", original, err)
+	}
+	defer body.Close()
+	config, format, err := image.DecodeConfig(body)
+	if err != nil {
+		return Asset{}, fmt.Errorf("ler %s: %w", original, err)
+	}
+	candidate.Width, candidate.Height = config.Width, config.Height
+	if candidate.ContentType == "" || candidate.ContentType == "application/octet-stream" {
+		candidate.ContentType = "image/" + format
+	}
+	asset, _, err := s.repo.FindOrCreate(candidate)
+	return asset, err
+}
+
+// Link registra que owner usa o asset, que precisa ser do mesmo agente.
+func (s *service) Link(agentID, assetID uint, owner Owner) (Asset, error) {
+	asset, err := s.repo.FindByID(agentID, assetID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Asset{}, apperror.Invalid("assetId", apperror.MsgNotFound)
+	}
+	if err != nil {
+		return Asset{}, err
+	}
+	if err := s.repo.Link(asset.ID, owner); err != nil {
+		return Asset{}, err
+	}
+	asset.UnusedSince = nil
+	return asset, nil
+}
+
+// Unlink remove os usos de owner: só os dos assetIDs ou, sem eles, todos.
+func (s *service) Unlink(owner Owner, assetIDs ...uint) error {
+	return s.repo.Unlink(owner.Type, []uint{owner.ID}, assetIDs)
+}
+
+// UnlinkOwners remove os usos de vários registros, ex.: os apagados definitivamente.
+func (s *service) UnlinkOwners(ownerType string, ownerIDs []uint) error {
+	return s.repo.Unlink(ownerType, ownerIDs, nil)
+}
+
+// PurgeTrash apaga os assets, e seus arquivos, sem uso desde antes de before. Roda no
+// mesmo job da lixeira: um asset sem uso é tratado como se estivesse nela.
+func (s *service) PurgeTrash(before time.Time) (int64, error) {
+	var purged int64
+	for {
+		assets, err := s.repo.FindUnusedBefore(before, purgeBatch)
+		if err != nil {
+			return purged, err
+		}
+		for _, asset := range assets {
+			// O asset pode ter voltado a ser usado desde a busca; aí fica onde está.
+			deleted, err := s.repo.DeleteUnused(asset.ID, before)
+			if err != nil {
+				return purged, err
+			}
+			if deleted {
+				s.release(asset)
+				purged++
+			}
+		}
+		if len(assets) < purgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// PurgeAgents apaga todos os assets dos agentes removidos, com os arquivos. O contador de
+// armazenamento é apagado junto com os dados do plano, então não é atualizado aqui.
+func (s *service) PurgeAgents(agentIDs []uint) error {
+	for {
+		assets, err := s.repo.DeleteByAgents(agentIDs, purgeBatch)
+		if err != nil {
+			return err
+		}
+		for _, asset := range assets {
+			deleteKeys(s.store, asset.Keys())
+		}
+		if len(assets) < purgeBatch {
+			return nil
+		}
+	}
+}
+
+// uploadPrefix é a pasta dos envios diretos do agente no store.
+func uploadPrefix(agentID uint) string {
+	return fmt.Sprintf("agents/%d/uploads/", agentID)
+}
+
+// RequestUpload reserva uma chave na pasta do agente e gera a URL assinada para o cliente
+// enviar o arquivo direto ao armazenamento, sem passar pela API.
+func (s *service) RequestUpload(agentID uint, dto RequestUploadDTO) (UploadTicket, error) {
+	if err := dto.Validate(s.maxBytes); err != nil {
+		return UploadTicket{}, err
+	}
+	presigner, ok := s.store.(storage.Presigner)
+	if !ok {
+		return UploadTicket{}, apperror.New(apperror.CodeForbidden, apperror.MsgDirectUploadDisabled)
+	}
+	if err := s.quota.CheckQuota(agentID, plan.ResourceStorage, dto.Size); err != nil {
+		return UploadTicket{}, err
+	}
+
+	key := uploadPrefix(agentID) + string(dto.Kind) + "/" + uuid.NewString() + contentTypes[dto.Kind][dto.ContentType]
+	put, err := presigner.PresignPut(key, dto.ContentType, dto.Size, uploadURLTTL)
+	if err != nil {
+		return UploadTicket{}, err
+	}
+	return UploadTicket{Key: key, URL: put.URL, Method: put.Method, Headers: put.Headers, ExpiresAt: put.ExpiresAt}, nil
+}
+
+// ConfirmUpload confere o arquivo enviado para key (existência, tamanho e conteúdo) e o
+// registra como asset do agente, pelo mesmo processamento do upload pela API. O arquivo
+// enviado é apagado em seguida, aceito ou não: o asset guarda a versão processada.
+func (s *service) ConfirmUpload(agentID uint, dto ConfirmUploadDTO) (Asset, error) {
+	kind, ok := parseUploadKey(agentID, dto.Key)
+	if !ok {
+		return Asset{}, apperror.Invalid("key", apperror.MsgInvalidUploadKey)
+	}
+	if err := validate.All(validate.Field("altText", dto.AltText, validate.MaxLength(maxAltTextLength))); err != nil {
+		return Asset{}, err
+	}
+	info, err := s.store.Stat(dto.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Asset{}, apperror.Invalid("key", apperror.MsgUploadNotFound)
+	}
+	if err != nil {
+		return Asset{}, err
+	}
+	if err := validate.All(validate.Field("file", info.Size, sizeWithin(s.maxBytes))); err != nil {
+		s.discard(dto.Key)
+		return Asset{}, err
+	}
+
+	body, err := s.store.Get(dto.Key)
+	if err != nil {
+		return Asset{}, err
+	}
+	asset, err := s.UploadImage(agentID, UploadImageDTO{
+		Kind:       kind,
+		Body:       body,
+		Size:       info.Size,
+		AltText:    dto.AltText,
+		UploadedBy: dto.UploadedBy,
+	})
+	body.Close()
+	if err == nil || apperror.HasCode(err, apperror.CodeValidation) || apperror.HasCode(err, apperror.CodeQuotaExceeded) {
+		s.discard(dto.Key)
+	}
+	return asset, err
+}
+
+func (s *service) discard(key string) {
+	if err := s.store.Delete(key); err != nil {
+		log.Printf("Falha ao apagar o envio %s: %v", key, err)
+	}
+}
+
+// parseUploadKey confere se a chave é um envio direto do agente
+// (agents/<agente>/uploads/<finalidade>/<arquivo>) e retorna a finalidade.
+func parseUploadKey(agentID uint, key string) (Kind, bool) {
+	rest, ok := strings.CutPrefix(key, uploadPrefix(agentID))
+	if !ok {
+		return "", false
+	}
+	kind, name, ok := strings.Cut(rest, "/")
+	if _, known := contentTypes[Kind(kind)]; !ok || !known || name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return Kind(kind), true
+}
@@ -0,0 +1,55 @@
+/*
+|------------------------------------------------
+| File: internal/domain/asset/validation.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package asset
+
+import (
+	"sort"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+)
+
+const maxAltTextLength = 250
+
+// sizeWithin aceita tamanhos de 1 byte até max.
+func sizeWithin(max int64) validate.Rule[int64] {
+	return func(value int64) *validate.Failure {
+		if value <= 0 {
+			return &validate.Failure{Key: apperror.MsgEmptyUpload}
+		}
+		if value > max {
+			return &validate.Failure{Key: apperror.MsgUploadTooLarge, Args: []interface{}{max}}
+		}
+		return nil
+	}
+}
+
+func (dto RequestUploadDTO) Validate(maxBytes int64) error {
+	kinds := make([]string, 0, len(contentTypes))
+	for kind := range contentTypes {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	types := make([]string, 0, len(contentTypes[dto.Kind]))
+	for contentType := range contentTypes[dto.Kind] {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+	checks := []validate.Check{
+		validate.Field("kind", string(dto.Kind), validate.OneOf(kinds...)),
+		validate.Field("size", dto.Size, sizeWithin(maxBytes)),
+	}
+	if len(types) > 0 {
+		checks = append(checks, validate.Field("contentType", dto.ContentType, validate.OneOf(types...)))
+	}
+	return validate.All(checks...)
+}
+
+func (dto UpdateAssetDTO) Validate() error {
+	return validate.All(validate.Patch("altText", dto.AltText, validate.MaxLength(maxAltTextLength)))
+}
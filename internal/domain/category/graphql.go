@@ -7,51 +7,108 @@
 */
 package category
 
-import "github.com/graphql-go/graphql"
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/events"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
 
-var categoryType = graphql.NewObject(
+// CategoryType é o tipo GraphQL para a entidade Category, exportado para os campos de relacionamento.
+var CategoryType = graphql.NewObject(
 	graphql.ObjectConfig{
 		Name: "Category",
 		Fields: graphql.Fields{
-			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
-			"agent_id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)}, // <-- MUDANÇA: Expondo o agent_id
-			"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
-			"created_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
-			"updated_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"id":             &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"agent_id":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)}, // <-- MUDANÇA: Expondo o agent_id
+			"name":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"image_asset_id": &graphql.Field{Type: graphql.Int, Description: "Asset da biblioteca de mídia usado como imagem."},
+			"version":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"created_at":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"updated_at":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"deleted_at": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if m, ok := p.Source.(Category); ok && m.DeletedAt.Valid {
+						return m.DeletedAt.Time, nil
+					}
+					return nil, nil
+				},
+			},
 		},
 	},
 )
 
-var paginatedCategoriesType = graphql.NewObject(
-	graphql.ObjectConfig{
-		Name: "PaginatedCategories",
-		Fields: graphql.Fields{
-			"data":        &graphql.Field{Type: graphql.NewList(categoryType)},
-			"total":       &graphql.Field{Type: graphql.Int},
-			"page":        &graphql.Field{Type: graphql.Int},
-			"per_page":    &graphql.Field{Type: graphql.Int},
-			"total_pages": &graphql.Field{Type: graphql.Int},
+// updateCategoryInputType tem todos os campos opcionais: o que não for enviado não muda.
+var updateCategoryInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UpdateCategoryInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"imageAssetId": &graphql.InputObjectFieldConfig{Type: graphql.Int, Description: "Imagem da biblioteca de mídia; null remove a imagem."},
 		},
 	},
 )
 
+var categoryFilterInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "CategoryFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"text":      &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Procura no nome."},
+			"createdAt": &graphql.InputObjectFieldConfig{Type: pagination.TimeRangeInput},
+		},
+	},
+)
+
+var categorySortInputType = pagination.SortInput("Category", graphql.EnumValueConfigMap{
+	"NAME":       &graphql.EnumValueConfig{Value: "name"},
+	"CREATED_AT": &graphql.EnumValueConfig{Value: "created_at"},
+})
+
+// filterFromArgs lê o argumento filter da query categories.
+func filterFromArgs(args map[string]interface{}) (CategoryFilter, error) {
+	var filter CategoryFilter
+	input, ok := args["filter"].(map[string]interface{})
+	if !ok {
+		return filter, nil
+	}
+	filter.Text, _ = input["text"].(string)
+	createdAt, err := pagination.TimeRangeFromArgs(input["createdAt"])
+	if err != nil {
+		return filter, err
+	}
+	filter.CreatedAt = createdAt
+	return filter, nil
+}
+
+var categoriesConnectionType = pagination.ConnectionType("Category", CategoryType)
+
 func GetQueryFields(service Service) graphql.Fields {
 	return graphql.Fields{
 		"categories": &graphql.Field{
-			Type:        paginatedCategoriesType,
+			Type:        categoriesConnectionType,
 			Description: "Obtém categorias para um agente específico.",
-			Args: graphql.FieldConfigArgument{
+			Args: pagination.Args(graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}, // <-- MUDANÇA
-				"page":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
-			},
+				"filter":  &graphql.ArgumentConfig{Type: categoryFilterInputType},
+				"sort":    &graphql.ArgumentConfig{Type: categorySortInputType},
+			}),
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				agentId, _ := p.Args["agentId"].(int)
-				page, _ := p.Args["page"].(int)
-				return service.GetAllCategories(uint(agentId), page)
+				page, err := pagination.PageFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				filter, err := filterFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return service.GetAllCategories(uint(agentId), filter, pagination.SortFromArgs(p.Args), page)
 			},
 		},
 		"category": &graphql.Field{
-			Type:        categoryType,
+			Type:        CategoryType,
 			Description: "Obtém uma categoria pelo seu ID, dentro de um agente.",
 			Args: graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}, // <-- MUDANÇA
@@ -64,8 +121,9 @@ func GetQueryFields(service Service) graphql.Fields {
 			},
 		},
 		"searchCategories": &graphql.Field{
-			Type:        graphql.NewList(categoryType),
-			Description: "Busca categorias por nome, dentro de um agente.",
+			Type:              graphql.NewList(CategoryType),
+			Description:       "Busca categorias por nome, dentro de um agente.",
+			DeprecationReason: "Use categories com filter.text.",
 			Args: graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}, // <-- MUDANÇA
 				"name":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
@@ -76,40 +134,67 @@ func GetQueryFields(service Service) graphql.Fields {
 				return service.SearchCategories(uint(agentId), name)
 			},
 		},
+		"trashedCategories": &graphql.Field{
+			Type:        graphql.NewList(CategoryType),
+			Description: "Lista as categorias excluídas de um agente que ainda podem ser restauradas.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.GetTrashedCategories(uint(agentId))
+			},
+		},
 	}
 }
 
 func GetMutationFields(service Service) graphql.Fields {
 	return graphql.Fields{
 		"createCategory": &graphql.Field{
-			Type:        categoryType,
+			Type:        CategoryType,
 			Description: "Cria uma nova categoria para um agente.",
 			Args: graphql.FieldConfigArgument{
 				// ↓↓ MUDANÇA PRINCIPAL AQUI ↓↓
-				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"name":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"agentId":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"name":         &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"imageAssetId": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Imagem da biblioteca de mídia."},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				// O agentId é capturado...
 				agentId, _ := p.Args["agentId"].(int)
 				name, _ := p.Args["name"].(string)
+				dto := CreateCategoryDTO{AgentID: uint(agentId), Name: name}
+				if v, ok := p.Args["imageAssetId"].(int); ok {
+					assetID := uint(v)
+					dto.ImageAssetID = &assetID
+				}
 				// ...e passado para o serviço através do DTO.
-				return service.CreateCategory(CreateCategoryDTO{AgentID: uint(agentId), Name: name})
+				return service.CreateCategory(dto)
 			},
 		},
 		"updateCategory": &graphql.Field{
-			Type:        categoryType,
-			Description: "Atualiza uma categoria de um agente.",
+			Type:        CategoryType,
+			Description: "Atualiza parcialmente uma categoria de um agente.",
 			Args: graphql.FieldConfigArgument{
-				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}, // <-- MUDANÇA
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
 				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"name":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"version": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int), Description: "Versão lida pelo cliente."},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateCategoryInputType)},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				agentId, _ := p.Args["agentId"].(int)
 				id, _ := p.Args["id"].(int)
-				name, _ := p.Args["name"].(string)
-				return service.UpdateCategory(uint(agentId), uint(id), UpdateCategoryDTO{Name: name})
+				version, _ := p.Args["version"].(int)
+				input := patch.InputFromArgs(p, "input")
+				dto := UpdateCategoryDTO{
+					Version:      uint(version),
+					Name:         patch.Get[string](input, "name"),
+					ImageAssetID: patch.Map(patch.Get[int](input, "imageAssetId"), func(v int) uint { return uint(v) }),
+				}
+				return service.UpdateCategory(uint(agentId), uint(id), dto)
 			},
 		},
 		"deleteCategory": &graphql.Field{
@@ -132,5 +217,47 @@ func GetMutationFields(service Service) graphql.Fields {
 				return map[string]interface{}{"deletedId": id, "success": true}, nil
 			},
 		},
+		"restoreCategory": &graphql.Field{
+			Type:        CategoryType,
+			Description: "Restaura uma categoria excluída de um agente.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.RestoreCategory(uint(agentId), uint(id))
+			},
+		},
+	}
+}
+
+var categoryChangeEventType = events.ChangeEventType("Category", "category", CategoryType)
+
+// GetSubscriptionFields expõe as mudanças nos categorias de um agente. Só usuários do próprio
+// agente (ou administradores) podem se inscrever.
+func GetSubscriptionFields(bus *events.Bus) graphql.Fields {
+	return graphql.Fields{
+		"categoryChanged": &graphql.Field{
+			Type:        graphql.NewNonNull(categoryChangeEventType),
+			Description: "Avisa quando uma categoria do agente é criada, alterada, excluída ou restaurada.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return bus.Subscribe(p.Context, events.TopicCategory, uint(agentId)), nil
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source, nil
+			},
+		},
 	}
 }
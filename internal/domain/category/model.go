@@ -7,33 +7,43 @@
 */
 package category
 
-import "time"
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"gorm.io/gorm"
+)
 
 // Category representa a entidade no banco de dados.
 type Category struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	AgentID   uint      `gorm:"not null" json:"agent_id"` // <-- MUDANÇA: Adicionado AgentID
-	Name      string    `gorm:"not null" json:"name"`     // Removi o 'unique' daqui. Nomes podem se repetir entre agentes diferentes.
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	AgentID      uint           `gorm:"not null" json:"agent_id"`    // <-- MUDANÇA: Adicionado AgentID
+	Name         string         `gorm:"not null" json:"name"`        // Removi o 'unique' daqui. Nomes podem se repetir entre agentes diferentes.
+	ImageAssetID *uint          `gorm:"index" json:"image_asset_id"` // Imagem da biblioteca de mídia.
+	Version      uint           `gorm:"not null;default:1" json:"version"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 }
 
 // CreateCategoryDTO é o Data Transfer Object para a criação de uma categoria.
 type CreateCategoryDTO struct {
-	AgentID uint   `json:"agent_id"` // <-- MUDANÇA: Adicionado AgentID
-	Name    string `json:"name"`
+	AgentID      uint   `json:"agent_id"` // <-- MUDANÇA: Adicionado AgentID
+	Name         string `json:"name"`
+	ImageAssetID *uint  `json:"image_asset_id"`
 }
 
 // UpdateCategoryDTO é o Data Transfer Object para a atualização de uma categoria.
+// Campos não enviados ficam como estão.
 type UpdateCategoryDTO struct {
-	Name string `json:"name"`
+	Version      uint                `json:"version"`
+	Name         patch.Field[string] `json:"name"`
+	ImageAssetID patch.Field[uint]   `json:"image_asset_id"` // null remove a imagem.
 }
 
-// PaginatedCategories é a estrutura de resposta para a lista paginada de categorias.
-type PaginatedCategories struct {
-	Data       []Category `json:"data"`
-	Total      int64      `json:"total"`
-	Page       int        `json:"page"`
-	PerPage    int        `json:"per_page"`
-	TotalPages int        `json:"total_pages"`
+// CategoryFilter restringe a lista de categorias. Campos vazios não filtram.
+type CategoryFilter struct {
+	Text      string // Procura no nome.
+	CreatedAt pagination.TimeRange
 }
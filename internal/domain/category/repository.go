@@ -7,15 +7,30 @@
 */
 package category
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type Repository interface {
-	FindAll(agentID uint, page, perPage int) ([]Category, int64, error)
+	FindAll(agentID uint, filter CategoryFilter, sort pagination.Sort, page pagination.Page) ([]Category, error)
+	Count(agentID uint, filter CategoryFilter) (int64, error)
 	FindByID(agentID, id uint) (Category, error)
 	Search(agentID uint, name string) ([]Category, error)
-	Create(category Category) (Category, error)
-	Update(category Category) (Category, error)
+	Create(category Category, quota plan.QuotaGuard) (Category, error)
+	Update(agentID, id, version uint, changes map[string]interface{}) (Category, error)
 	Delete(agentID, id uint) error
+	FindTrashed(agentID uint) ([]Category, error)
+	Restore(agentID, id uint, quota plan.QuotaGuard) error
+	Purge(before time.Time) ([]uint, error)
+	PurgeAgents(agentIDs []uint) ([]uint, error)
+	FindByIDs(ids []uint) ([]Category, error)
+	FindByAgentIDs(agentIDs []uint) ([]Category, error)
 }
 
 type repository struct {
@@ -26,17 +41,28 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) FindAll(agentID uint, page, perPage int) ([]Category, int64, error) {
+func (r *repository) FindAll(agentID uint, filter CategoryFilter, sort pagination.Sort, page pagination.Page) ([]Category, error) {
 	var categories []Category
+	sort = sort.Allowed("name", "created_at")
+	err := r.db.Where("agent_id = ?", agentID).Scopes(filterScope(filter), page.Scope(sort)).Find(&categories).Error
+	return categories, err
+}
+
+func (r *repository) Count(agentID uint, filter CategoryFilter) (int64, error) {
 	var total int64
-	// <-- MUDANÇA: Adicionado Where("agent_id = ?", agentID)
-	if err := r.db.Model(&Category{}).Where("agent_id = ?", agentID).Count(&total).Error; err != nil {
-		return nil, 0, err
+	err := r.db.Model(&Category{}).Where("agent_id = ?", agentID).Scopes(filterScope(filter)).Count(&total).Error
+	return total, err
+}
+
+// filterScope traduz o filtro em condições parametrizadas.
+func filterScope(filter CategoryFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Text != "" {
+			pattern := pagination.ContainsPattern(filter.Text)
+			db = db.Where("name ILIKE ?", pattern)
+		}
+		return db.Scopes(filter.CreatedAt.Scope("created_at"))
 	}
-	offset := (page - 1) * perPage
-	// <-- MUDANÇA: Adicionado Where("agent_id = ?", agentID)
-	err := r.db.Where("agent_id = ?", agentID).Limit(perPage).Offset(offset).Order("id asc").Find(&categories).Error
-	return categories, total, err
 }
 
 func (r *repository) FindByID(agentID, id uint) (Category, error) {
@@ -53,17 +79,91 @@ func (r *repository) Search(agentID uint, name string) ([]Category, error) {
 	return categories, err
 }
 
-func (r *repository) Create(category Category) (Category, error) {
-	err := r.db.Create(&category).Error
+func (r *repository) Create(category Category, quota plan.QuotaGuard) (Category, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := quota(tx); err != nil {
+			return err
+		}
+		return tx.Create(&category).Error
+	})
 	return category, err
 }
 
-func (r *repository) Update(category Category) (Category, error) {
-	err := r.db.Save(&category).Error
-	return category, err
+// Update altera só as colunas em changes, e somente se a versão no banco ainda for version,
+// incrementando-a. Se outra atualização chegou antes, retorna apperror.ErrVersionMismatch.
+func (r *repository) Update(agentID, id, version uint, changes map[string]interface{}) (Category, error) {
+	changes["version"] = gorm.Expr("version + 1")
+	result := r.db.Model(&Category{}).Where("agent_id = ? AND id = ? AND version = ?", agentID, id, version).Updates(changes)
+	if result.Error != nil {
+		return Category{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Category{}, apperror.ErrVersionMismatch
+	}
+	return r.FindByID(agentID, id)
 }
 
 func (r *repository) Delete(agentID, id uint) error {
 	// <-- MUDANÇA: Adicionado Where("agent_id = ?", agentID) para segurança
 	return r.db.Where("agent_id = ?", agentID).Delete(&Category{}, id).Error
 }
+
+// FindTrashed lista as categorias excluídas (soft delete) de um agente.
+func (r *repository) FindTrashed(agentID uint) ([]Category, error) {
+	var categories []Category
+	err := r.db.Unscoped().Where("agent_id = ? AND deleted_at IS NOT NULL", agentID).Order("deleted_at desc").Find(&categories).Error
+	return categories, err
+}
+
+// Restore tira uma categoria da lixeira.
+func (r *repository) Restore(agentID, id uint, quota plan.QuotaGuard) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := quota(tx); err != nil {
+			return err
+		}
+		result := tx.Unscoped().Model(&Category{}).
+			Where("agent_id = ? AND id = ? AND deleted_at IS NOT NULL", agentID, id).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Purge remove definitivamente as categorias excluídas antes de before e retorna os IDs removidos.
+func (r *repository) Purge(before time.Time) ([]uint, error) {
+	return r.purge(r.db.Where("deleted_at IS NOT NULL AND deleted_at < ?", before))
+}
+
+// PurgeAgents remove definitivamente todas as categorias dos agentes e retorna os IDs removidos.
+func (r *repository) PurgeAgents(agentIDs []uint) ([]uint, error) {
+	return r.purge(r.db.Where("agent_id IN ?", agentIDs))
+}
+
+func (r *repository) purge(query *gorm.DB) ([]uint, error) {
+	var purged []Category
+	err := query.Unscoped().Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).Delete(&purged).Error
+	ids := make([]uint, len(purged))
+	for i, item := range purged {
+		ids[i] = item.ID
+	}
+	return ids, err
+}
+
+// FindByIDs busca várias categorias numa única consulta.
+func (r *repository) FindByIDs(ids []uint) ([]Category, error) {
+	var categories []Category
+	err := r.db.Where("id IN ?", ids).Find(&categories).Error
+	return categories, err
+}
+
+// FindByAgentIDs busca as categorias de vários agentes numa única consulta.
+func (r *repository) FindByAgentIDs(agentIDs []uint) ([]Category, error) {
+	var categories []Category
+	err := r.db.Where("agent_id IN ?", agentIDs).Order("id asc").Find(&categories).Error
+	return categories, err
+}
@@ -7,40 +7,64 @@
 */
 package category
 
-import "math"
+import (
+	"errors"
+	"log"
+	"time"
 
-const PageSize = 8
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/events"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"gorm.io/gorm"
+)
 
 type Service interface {
-	GetAllCategories(agentID uint, page int) (PaginatedCategories, error)
+	GetAllCategories(agentID uint, filter CategoryFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[Category], error)
 	GetCategoryByID(agentID, id uint) (Category, error)
 	SearchCategories(agentID uint, name string) ([]Category, error)
 	CreateCategory(dto CreateCategoryDTO) (Category, error)
 	UpdateCategory(agentID, id uint, dto UpdateCategoryDTO) (Category, error)
 	DeleteCategory(agentID, id uint) error
+	GetTrashedCategories(agentID uint) ([]Category, error)
+	RestoreCategory(agentID, id uint) (Category, error)
+	PurgeTrash(before time.Time) (int64, error)
+	PurgeAgents(agentIDs []uint) error
+	GetCategoriesByIDs(ids []uint) ([]Category, error)
+	GetCategoriesByAgentIDs(agentIDs []uint) ([]Category, error)
 }
 
 type service struct {
-	repo Repository
+	repo   Repository
+	quota  plan.QuotaChecker
+	events events.Publisher
+	assets asset.Linker
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// NewService cria o serviço. As mudanças nos registros são publicadas em publisher e as
+// imagens vêm da biblioteca de mídia (assets).
+func NewService(repo Repository, quota plan.QuotaChecker, publisher events.Publisher, assets asset.Linker) Service {
+	return &service{repo: repo, quota: quota, events: publisher, assets: assets}
 }
 
-func (s *service) GetAllCategories(agentID uint, page int) (PaginatedCategories, error) {
-	categories, total, err := s.repo.FindAll(agentID, page, PageSize)
+func (s *service) GetAllCategories(agentID uint, filter CategoryFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[Category], error) {
+	categories, err := s.repo.FindAll(agentID, filter, sort, page)
 	if err != nil {
-		return PaginatedCategories{}, err
+		return pagination.Connection[Category]{}, err
 	}
-	totalPages := int(math.Ceil(float64(total) / float64(PageSize)))
-	return PaginatedCategories{
-		Data:       categories,
-		Total:      total,
-		Page:       page,
-		PerPage:    PageSize,
-		TotalPages: totalPages,
-	}, nil
+	conn := pagination.NewConnection(categories, page, func(item Category) pagination.Cursor {
+		cursor := sort.Cursor(item.ID)
+		switch sort.Column {
+		case "name":
+			cursor.Value = item.Name
+		case "created_at":
+			cursor.Value = item.CreatedAt
+		}
+		return cursor
+	})
+	return conn.WithCount(func() (int64, error) { return s.repo.Count(agentID, filter) }), nil
 }
 
 func (s *service) GetCategoryByID(agentID, id uint) (Category, error) {
@@ -52,27 +76,153 @@ func (s *service) SearchCategories(agentID uint, name string) ([]Category, error
 }
 
 func (s *service) CreateCategory(dto CreateCategoryDTO) (Category, error) {
+	if err := dto.Validate(); err != nil {
+		return Category{}, err
+	}
 	category := Category{
 		AgentID: dto.AgentID,
 		Name:    dto.Name,
 	}
-	return s.repo.Create(category)
+	if dto.ImageAssetID != nil {
+		if err := s.checkImage(dto.AgentID, *dto.ImageAssetID); err != nil {
+			return Category{}, err
+		}
+		category.ImageAssetID = dto.ImageAssetID
+	}
+	created, err := s.repo.Create(category, s.quota.Reserve(dto.AgentID, plan.ResourceCategories, 1))
+	if err == nil {
+		s.linkImage(created.AgentID, created.ID, nil, created.ImageAssetID)
+	}
+	return s.publish(events.ActionCreated, created, err)
 }
 
 func (s *service) UpdateCategory(agentID, id uint, dto UpdateCategoryDTO) (Category, error) {
+	if err := dto.Validate(); err != nil {
+		return Category{}, err
+	}
 	categoryToUpdate, err := s.repo.FindByID(agentID, id)
 	if err != nil {
 		return Category{}, err
 	}
-	categoryToUpdate.Name = dto.Name
-	return s.repo.Update(categoryToUpdate)
+	if categoryToUpdate.Version != dto.Version {
+		return Category{}, apperror.Conflict(categoryToUpdate)
+	}
+
+	changes := patch.Changes{}
+	if err := patch.Required(changes, "name", dto.Name); err != nil {
+		return Category{}, err
+	}
+	if dto.ImageAssetID.Set {
+		changes["image_asset_id"] = nil
+		if !dto.ImageAssetID.Null {
+			if err := s.checkImage(agentID, dto.ImageAssetID.Value); err != nil {
+				return Category{}, err
+			}
+			changes["image_asset_id"] = dto.ImageAssetID.Value
+		}
+	}
+	if len(changes) == 0 {
+		return categoryToUpdate, nil
+	}
+
+	updated, err := s.repo.Update(agentID, id, dto.Version, changes)
+	if errors.Is(err, apperror.ErrVersionMismatch) {
+		return s.conflict(agentID, id)
+	}
+	if err == nil {
+		s.linkImage(agentID, id, categoryToUpdate.ImageAssetID, updated.ImageAssetID)
+	}
+	return s.publish(events.ActionUpdated, updated, err)
+}
+
+// conflict relê a categoria para devolver ao cliente o estado que venceu a disputa.
+func (s *service) conflict(agentID, id uint) (Category, error) {
+	current, err := s.repo.FindByID(agentID, id)
+	if err != nil {
+		return Category{}, err
+	}
+	return Category{}, apperror.Conflict(current)
 }
 
 func (s *service) DeleteCategory(agentID, id uint) error {
 	// A verificação de existência já acontece no repositório, mas podemos manter aqui também.
-	_, err := s.repo.FindByID(agentID, id)
+	existing, err := s.repo.FindByID(agentID, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.publish(events.ActionDeleted, existing, s.repo.Delete(agentID, id))
+	return err
+}
+
+func (s *service) GetTrashedCategories(agentID uint) ([]Category, error) {
+	return s.repo.FindTrashed(agentID)
+}
+
+func (s *service) RestoreCategory(agentID, id uint) (Category, error) {
+	if err := s.repo.Restore(agentID, id, s.quota.Reserve(agentID, plan.ResourceCategories, 1)); err != nil {
+		return Category{}, err
+	}
+	restored, err := s.repo.FindByID(agentID, id)
+	return s.publish(events.ActionRestored, restored, err)
+}
+
+// checkImage confere se o asset escolhido como imagem é do agente.
+func (s *service) checkImage(agentID, assetID uint) error {
+	_, err := s.assets.GetAssetByID(agentID, assetID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Invalid("imageAssetId", apperror.MsgNotFound)
+	}
+	return err
+}
+
+// linkImage atualiza o uso dos assets quando a imagem da categoria muda. Falhas só são
+// registradas: no pior caso, um asset ainda usado começa a contar o prazo de retenção.
+func (s *service) linkImage(agentID, id uint, previous, current *uint) {
+	if previous != nil && (current == nil || *previous != *current) {
+		if err := s.assets.Unlink(asset.Owner{Type: asset.OwnerCategory, ID: id}); err != nil {
+			log.Printf("Falha ao desvincular o asset %d da categoria %d: %v", *previous, id, err)
+		}
+	}
+	if current != nil {
+		if _, err := s.assets.Link(agentID, *current, asset.Owner{Type: asset.OwnerCategory, ID: id}); err != nil {
+			log.Printf("Falha ao vincular o asset %d à categoria %d: %v", *current, id, err)
+		}
+	}
+}
+
+// PurgeTrash apaga as categorias da lixeira e libera os assets que elas usavam.
+func (s *service) PurgeTrash(before time.Time) (int64, error) {
+	ids, err := s.repo.Purge(before)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.assets.UnlinkOwners(asset.OwnerCategory, ids); err != nil {
+		log.Printf("Falha ao desvincular os assets das categorias apagadas: %v", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// PurgeAgents apaga todas as categorias dos agentes removidos e os usos de assets delas.
+func (s *service) PurgeAgents(agentIDs []uint) error {
+	ids, err := s.repo.PurgeAgents(agentIDs)
 	if err != nil {
 		return err
 	}
-	return s.repo.Delete(agentID, id)
+	return s.assets.UnlinkOwners(asset.OwnerCategory, ids)
+}
+
+func (s *service) GetCategoriesByIDs(ids []uint) ([]Category, error) {
+	return s.repo.FindByIDs(ids)
+}
+
+func (s *service) GetCategoriesByAgentIDs(agentIDs []uint) ([]Category, error) {
+	return s.repo.FindByAgentIDs(agentIDs)
+}
+
+// publish avisa os inscritos quando a operação deu certo.
+func (s *service) publish(action string, category Category, err error) (Category, error) {
+	if err == nil {
+		s.events.Publish(events.Event{Topic: events.TopicCategory, Action: action, AgentID: category.AgentID, Payload: category})
+	}
+	return category, err
 }
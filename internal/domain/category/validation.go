@@ -0,0 +1,25 @@
+/*
+|------------------------------------------------
+| File: internal/domain/category/validation.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package category
+
+import "github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+
+const maxNameLength = 80
+
+func (dto CreateCategoryDTO) Validate() error {
+	return validate.All(
+		validate.Field("agentId", dto.AgentID, validate.NotZero),
+		validate.Field("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+	)
+}
+
+func (dto UpdateCategoryDTO) Validate() error {
+	return validate.All(
+		validate.Patch("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+	)
+}
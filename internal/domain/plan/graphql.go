@@ -0,0 +1,196 @@
+/*
+|------------------------------------------------
+| File: internal/domain/plan/graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package plan
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+// Os valores de armazenamento usam Float porque graphql.Int é limitado a 32 bits.
+var planType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Plan",
+		Fields: graphql.Fields{
+			"id":                &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"max_products":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"max_categories":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"max_users":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"max_storage_bytes": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"created_at":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"updated_at":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	},
+)
+
+var usageCounterType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name:        "UsageCounter",
+		Description: "Consumo de um recurso. limit igual a 0 significa ilimitado.",
+		Fields: graphql.Fields{
+			"used":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"limit": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	},
+)
+
+var agentUsageType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "AgentUsage",
+		Fields: graphql.Fields{
+			"agent_id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"plan":       &graphql.Field{Type: planType},
+			"products":   &graphql.Field{Type: graphql.NewNonNull(usageCounterType)},
+			"categories": &graphql.Field{Type: graphql.NewNonNull(usageCounterType)},
+			"users":      &graphql.Field{Type: graphql.NewNonNull(usageCounterType)},
+			"storage":    &graphql.Field{Type: graphql.NewNonNull(usageCounterType)},
+		},
+	},
+)
+
+var planLimitArgs = graphql.FieldConfigArgument{
+	"name":            &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+	"maxProducts":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+	"maxCategories":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+	"maxUsers":        &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+	"maxStorageBytes": &graphql.ArgumentConfig{Type: graphql.Float, DefaultValue: 0.0},
+}
+
+func GetQueryFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"plans": &graphql.Field{
+			Type:        graphql.NewList(planType),
+			Description: "Lista os planos de assinatura. Só administradores.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := session.RequireAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				return service.GetAllPlans()
+			},
+		},
+		"plan": &graphql.Field{
+			Type:        planType,
+			Description: "Obtém um plano pelo seu ID.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := session.RequireAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(int)
+				return service.GetPlanByID(uint(id))
+			},
+		},
+		"agentUsage": &graphql.Field{
+			Type:        agentUsageType,
+			Description: "Obtém o consumo de um agente comparado aos limites do seu plano. Só usuários do próprio agente (ou administradores).",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.GetAgentUsage(uint(agentId))
+			},
+		},
+	}
+}
+
+func GetMutationFields(service Service) graphql.Fields {
+	updateArgs := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+	}
+	for name, arg := range planLimitArgs {
+		updateArgs[name] = arg
+	}
+
+	return graphql.Fields{
+		"createPlan": &graphql.Field{
+			Type:        planType,
+			Description: "Cria um novo plano de assinatura.",
+			Args:        planLimitArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := session.RequireAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				dto := CreatePlanDTO{
+					Name:            p.Args["name"].(string),
+					MaxProducts:     int64(p.Args["maxProducts"].(int)),
+					MaxCategories:   int64(p.Args["maxCategories"].(int)),
+					MaxUsers:        int64(p.Args["maxUsers"].(int)),
+					MaxStorageBytes: int64(p.Args["maxStorageBytes"].(float64)),
+				}
+				return service.CreatePlan(dto)
+			},
+		},
+		"updatePlan": &graphql.Field{
+			Type:        planType,
+			Description: "Atualiza um plano de assinatura.",
+			Args:        updateArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := session.RequireAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				id := p.Args["id"].(int)
+				dto := UpdatePlanDTO{
+					Name:            p.Args["name"].(string),
+					MaxProducts:     int64(p.Args["maxProducts"].(int)),
+					MaxCategories:   int64(p.Args["maxCategories"].(int)),
+					MaxUsers:        int64(p.Args["maxUsers"].(int)),
+					MaxStorageBytes: int64(p.Args["maxStorageBytes"].(float64)),
+				}
+				return service.UpdatePlan(uint(id), dto)
+			},
+		},
+		"deletePlan": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name:   "DeletePlanPayload",
+				Fields: graphql.Fields{"deletedId": &graphql.Field{Type: graphql.Int}, "success": &graphql.Field{Type: graphql.Boolean}},
+			}),
+			Description: "Deleta um plano. Os agentes que o utilizavam ficam sem plano.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := session.RequireAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(int)
+				err := service.DeletePlan(uint(id))
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"deletedId": id, "success": true}, nil
+			},
+		},
+		"assignPlan": &graphql.Field{
+			Type:        planType,
+			Description: "Atribui um plano a um agente. Sem planId, o agente fica sem plano.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"planId":  &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if _, err := session.RequireAdmin(p.Context); err != nil {
+					return nil, err
+				}
+				agentId, _ := p.Args["agentId"].(int)
+				var planID *uint
+				if v, ok := p.Args["planId"]; ok && v != nil {
+					id := uint(v.(int))
+					planID = &id
+				}
+				return service.AssignPlan(uint(agentId), planID)
+			},
+		},
+	}
+}
@@ -0,0 +1,88 @@
+/*
+|------------------------------------------------
+| File: internal/domain/plan/model.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package plan
+
+import "time"
+
+// Resource identifica um recurso limitado pelo plano do agente.
+type Resource string
+
+const (
+	ResourceProducts   Resource = "products"
+	ResourceCategories Resource = "categories"
+	ResourceUsers      Resource = "users"
+	ResourceStorage    Resource = "storage"
+)
+
+// Plan representa um plano de assinatura. Um limite igual a zero significa ilimitado.
+type Plan struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	Name            string    `gorm:"unique;not null" json:"name"`
+	MaxProducts     int64     `gorm:"not null;default:0" json:"max_products"`
+	MaxCategories   int64     `gorm:"not null;default:0" json:"max_categories"`
+	MaxUsers        int64     `gorm:"not null;default:0" json:"max_users"`
+	MaxStorageBytes int64     `gorm:"not null;default:0" json:"max_storage_bytes"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Limit retorna o limite do plano para o recurso informado.
+func (p Plan) Limit(resource Resource) int64 {
+	switch resource {
+	case ResourceProducts:
+		return p.MaxProducts
+	case ResourceCategories:
+		return p.MaxCategories
+	case ResourceUsers:
+		return p.MaxUsers
+	case ResourceStorage:
+		return p.MaxStorageBytes
+	}
+	return 0
+}
+
+// StorageUsage guarda o total de bytes armazenados por agente.
+type StorageUsage struct {
+	AgentID   uint      `gorm:"primaryKey;autoIncrement:false" json:"agent_id"`
+	Bytes     int64     `gorm:"not null;default:0" json:"bytes"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreatePlanDTO é o DTO para a criação de um plano.
+type CreatePlanDTO struct {
+	Name            string `json:"name"`
+	MaxProducts     int64  `json:"max_products"`
+	MaxCategories   int64  `json:"max_categories"`
+	MaxUsers        int64  `json:"max_users"`
+	MaxStorageBytes int64  `json:"max_storage_bytes"`
+}
+
+// UpdatePlanDTO é o DTO para a atualização de um plano.
+type UpdatePlanDTO struct {
+	Name            string `json:"name"`
+	MaxProducts     int64  `json:"max_products"`
+	MaxCategories   int64  `json:"max_categories"`
+	MaxUsers        int64  `json:"max_users"`
+	MaxStorageBytes int64  `json:"max_storage_bytes"`
+}
+
+// UsageCounter é o consumo de um recurso comparado ao limite do plano.
+type UsageCounter struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+// AgentUsage é o consumo atual de um agente em todos os recursos limitados.
+type AgentUsage struct {
+	AgentID    uint         `json:"agent_id"`
+	Plan       *Plan        `json:"plan"`
+	Products   UsageCounter `json:"products"`
+	Categories UsageCounter `json:"categories"`
+	Users      UsageCounter `json:"users"`
+	Storage    UsageCounter `json:"storage"`
+}
@@ -0,0 +1,145 @@
+/*
+|------------------------------------------------
+| File: internal/domain/plan/repository.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package plan
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	FindAll() ([]Plan, error)
+	FindByID(id uint) (Plan, error)
+	FindByAgentID(agentID uint) (*Plan, error)
+	Create(plan Plan) (Plan, error)
+	Update(plan Plan) (Plan, error)
+	Delete(id uint) error
+	AssignToAgent(agentID uint, planID *uint) error
+	CountUsage(agentID uint, resource Resource) (int64, error)
+	AddStorage(agentID uint, delta int64) error
+	DeleteStorage(agentIDs []uint) error
+	// WithTx retorna o repositório operando dentro da transação tx.
+	WithTx(tx *gorm.DB) Repository
+	LockAgent(agentID uint) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) WithTx(tx *gorm.DB) Repository {
+	return &repository{db: tx}
+}
+
+// LockAgent trava a linha do agente até o fim da transação. Agente inexistente não é erro:
+// a criação falha depois pela chave estrangeira.
+func (r *repository) LockAgent(agentID uint) error {
+	var ids []uint
+	return r.db.Table("agents").Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", agentID).Pluck("id", &ids).Error
+}
+
+func (r *repository) FindAll() ([]Plan, error) {
+	var plans []Plan
+	err := r.db.Order("id asc").Find(&plans).Error
+	return plans, err
+}
+
+func (r *repository) FindByID(id uint) (Plan, error) {
+	var plan Plan
+	err := r.db.First(&plan, id).Error
+	return plan, err
+}
+
+// FindByAgentID retorna o plano atribuído ao agente, ou nil se ele não tiver plano.
+func (r *repository) FindByAgentID(agentID uint) (*Plan, error) {
+	var plan Plan
+	err := r.db.Joins("JOIN agents ON agents.plan_id = plans.id").
+		Where("agents.id = ? AND agents.deleted_at IS NULL", agentID).
+		First(&plan).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *repository) Create(plan Plan) (Plan, error) {
+	err := r.db.Create(&plan).Error
+	return plan, err
+}
+
+func (r *repository) Update(plan Plan) (Plan, error) {
+	err := r.db.Save(&plan).Error
+	return plan, err
+}
+
+func (r *repository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("agents").Where("plan_id = ?", id).Update("plan_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Plan{}, id).Error
+	})
+}
+
+// AssignToAgent atribui (ou remove, quando planID é nil) o plano de um agente.
+func (r *repository) AssignToAgent(agentID uint, planID *uint) error {
+	result := r.db.Table("agents").Where("id = ?", agentID).Update("plan_id", planID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountUsage calcula o consumo atual do agente para um recurso.
+func (r *repository) CountUsage(agentID uint, resource Resource) (int64, error) {
+	var used int64
+	switch resource {
+	case ResourceProducts, ResourceCategories, ResourceUsers:
+		err := r.db.Table(string(resource)).Where("agent_id = ? AND deleted_at IS NULL", agentID).Count(&used).Error
+		return used, err
+	case ResourceStorage:
+		err := r.db.Model(&StorageUsage{}).Where("agent_id = ?", agentID).
+			Select("COALESCE(SUM(bytes), 0)").Scan(&used).Error
+		return used, err
+	}
+	return 0, fmt.Errorf("recurso desconhecido: %s", resource)
+}
+
+// AddStorage soma (ou subtrai, com delta negativo) bytes ao contador de armazenamento do agente.
+func (r *repository) AddStorage(agentID uint, delta int64) error {
+	usage := StorageUsage{AgentID: agentID, Bytes: delta}
+	if delta < 0 {
+		usage.Bytes = 0
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "agent_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"bytes":      gorm.Expr("GREATEST(storage_usages.bytes + ?, 0)", delta),
+			"updated_at": gorm.Expr("NOW()"),
+		}),
+	}).Create(&usage).Error
+}
+
+// DeleteStorage apaga os contadores de armazenamento dos agentes.
+func (r *repository) DeleteStorage(agentIDs []uint) error {
+	return r.db.Where("agent_id IN ?", agentIDs).Delete(&StorageUsage{}).Error
+}
@@ -0,0 +1,179 @@
+/*
+|------------------------------------------------
+| File: internal/domain/plan/service.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package plan
+
+import (
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"gorm.io/gorm"
+)
+
+// QuotaChecker é a parte do serviço de planos consultada pelos fluxos de criação.
+type QuotaChecker interface {
+	CheckQuota(agentID uint, resource Resource, delta int64) error
+	// Reserve retorna a checagem de cota a rodar na transação que cria o registro.
+	Reserve(agentID uint, resource Resource, delta int64) QuotaGuard
+	AddStorageUsage(agentID uint, delta int64) error
+}
+
+// QuotaGuard confere a cota dentro da transação que cria ou restaura o registro, antes da
+// escrita. A linha do agente fica travada até o fim da transação, então criações
+// concorrentes do mesmo agente são contadas uma depois da outra.
+type QuotaGuard func(tx *gorm.DB) error
+
+type Service interface {
+	QuotaChecker
+	GetAllPlans() ([]Plan, error)
+	GetPlanByID(id uint) (Plan, error)
+	CreatePlan(dto CreatePlanDTO) (Plan, error)
+	UpdatePlan(id uint, dto UpdatePlanDTO) (Plan, error)
+	DeletePlan(id uint) error
+	AssignPlan(agentID uint, planID *uint) (*Plan, error)
+	GetAgentUsage(agentID uint) (AgentUsage, error)
+	PurgeAgents(agentIDs []uint) error
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) GetAllPlans() ([]Plan, error) {
+	return s.repo.FindAll()
+}
+
+func (s *service) GetPlanByID(id uint) (Plan, error) {
+	return s.repo.FindByID(id)
+}
+
+func (s *service) CreatePlan(dto CreatePlanDTO) (Plan, error) {
+	if err := dto.Validate(); err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{
+		Name:            dto.Name,
+		MaxProducts:     dto.MaxProducts,
+		MaxCategories:   dto.MaxCategories,
+		MaxUsers:        dto.MaxUsers,
+		MaxStorageBytes: dto.MaxStorageBytes,
+	}
+	return s.repo.Create(plan)
+}
+
+func (s *service) UpdatePlan(id uint, dto UpdatePlanDTO) (Plan, error) {
+	if err := dto.Validate(); err != nil {
+		return Plan{}, err
+	}
+	planToUpdate, err := s.repo.FindByID(id)
+	if err != nil {
+		return Plan{}, err
+	}
+	planToUpdate.Name = dto.Name
+	planToUpdate.MaxProducts = dto.MaxProducts
+	planToUpdate.MaxCategories = dto.MaxCategories
+	planToUpdate.MaxUsers = dto.MaxUsers
+	planToUpdate.MaxStorageBytes = dto.MaxStorageBytes
+	return s.repo.Update(planToUpdate)
+}
+
+func (s *service) DeletePlan(id uint) error {
+	_, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *service) AssignPlan(agentID uint, planID *uint) (*Plan, error) {
+	var plan *Plan
+	if planID != nil {
+		found, err := s.repo.FindByID(*planID)
+		if err != nil {
+			return nil, err
+		}
+		plan = &found
+	}
+	if err := s.repo.AssignToAgent(agentID, planID); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *service) GetAgentUsage(agentID uint) (AgentUsage, error) {
+	plan, err := s.repo.FindByAgentID(agentID)
+	if err != nil {
+		return AgentUsage{}, err
+	}
+	usage := AgentUsage{AgentID: agentID, Plan: plan}
+	counters := map[Resource]*UsageCounter{
+		ResourceProducts:   &usage.Products,
+		ResourceCategories: &usage.Categories,
+		ResourceUsers:      &usage.Users,
+		ResourceStorage:    &usage.Storage,
+	}
+	for resource, counter := range counters {
+		used, err := s.repo.CountUsage(agentID, resource)
+		if err != nil {
+			return AgentUsage{}, err
+		}
+		counter.Used = used
+		if plan != nil {
+			counter.Limit = plan.Limit(resource)
+		}
+	}
+	return usage, nil
+}
+
+// CheckQuota retorna um erro QUOTA_EXCEEDED se somar delta ao consumo atual ultrapassar o plano.
+// Agentes sem plano, ou recursos com limite zero, não são limitados.
+func (s *service) CheckQuota(agentID uint, resource Resource, delta int64) error {
+	return checkQuota(s.repo, agentID, resource, delta)
+}
+
+func (s *service) Reserve(agentID uint, resource Resource, delta int64) QuotaGuard {
+	return func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.LockAgent(agentID); err != nil {
+			return err
+		}
+		return checkQuota(repo, agentID, resource, delta)
+	}
+}
+
+func checkQuota(repo Repository, agentID uint, resource Resource, delta int64) error {
+	plan, err := repo.FindByAgentID(agentID)
+	if err != nil {
+		return err
+	}
+	if plan == nil {
+		return nil
+	}
+	limit := plan.Limit(resource)
+	if limit <= 0 {
+		return nil
+	}
+	used, err := repo.CountUsage(agentID, resource)
+	if err != nil {
+		return err
+	}
+	if used+delta > limit {
+		return apperror.QuotaExceeded(string(resource), limit, used)
+	}
+	return nil
+}
+
+func (s *service) AddStorageUsage(agentID uint, delta int64) error {
+	return s.repo.AddStorage(agentID, delta)
+}
+
+// PurgeAgents apaga o contador de armazenamento dos agentes removidos.
+func (s *service) PurgeAgents(agentIDs []uint) error {
+	return s.repo.DeleteStorage(agentIDs)
+}
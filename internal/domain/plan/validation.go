@@ -0,0 +1,36 @@
+/*
+|------------------------------------------------
+| File: internal/domain/plan/validation.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package plan
+
+import "github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+
+const maxNameLength = 60
+
+// limitChecks valida os limites do plano; zero significa ilimitado.
+func limitChecks(maxProducts, maxCategories, maxUsers, maxStorageBytes int64) []validate.Check {
+	return []validate.Check{
+		validate.Field("maxProducts", maxProducts, validate.NonNegative[int64]),
+		validate.Field("maxCategories", maxCategories, validate.NonNegative[int64]),
+		validate.Field("maxUsers", maxUsers, validate.NonNegative[int64]),
+		validate.Field("maxStorageBytes", maxStorageBytes, validate.NonNegative[int64]),
+	}
+}
+
+func (dto CreatePlanDTO) Validate() error {
+	checks := []validate.Check{
+		validate.Field("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+	}
+	return validate.All(append(checks, limitChecks(dto.MaxProducts, dto.MaxCategories, dto.MaxUsers, dto.MaxStorageBytes)...)...)
+}
+
+func (dto UpdatePlanDTO) Validate() error {
+	checks := []validate.Check{
+		validate.Field("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+	}
+	return validate.All(append(checks, limitChecks(dto.MaxProducts, dto.MaxCategories, dto.MaxUsers, dto.MaxStorageBytes)...)...)
+}
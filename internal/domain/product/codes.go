@@ -0,0 +1,89 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/codes.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+)
+
+// Limites das referências externas.
+const (
+	maxExternalRefs    = 20
+	maxRefSystemLength = 40
+	maxRefValueLength  = 120
+)
+
+// ExternalRefs guarda o código do produto em cada sistema externo, como
+// {"ifood": "8f2c", "pdv": "1042"}.
+type ExternalRefs map[string]string
+
+func (ExternalRefs) GormDataType() string {
+	return "jsonb"
+}
+
+func (r ExternalRefs) Value() (driver.Value, error) {
+	if r == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+func (r *ExternalRefs) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*r = ExternalRefs{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), r)
+	case []byte:
+		return json.Unmarshal(value, r)
+	default:
+		return fmt.Errorf("ExternalRefs: tipo não suportado %T", src)
+	}
+}
+
+// Systems lista os sistemas em ordem alfabética.
+func (r ExternalRefs) Systems() []string {
+	systems := make([]string, 0, len(r))
+	for system := range r {
+		systems = append(systems, system)
+	}
+	sort.Strings(systems)
+	return systems
+}
+
+// trimmed tira os espaços das pontas dos sistemas e das referências.
+func (r ExternalRefs) trimmed() ExternalRefs {
+	if r == nil {
+		return nil
+	}
+	result := make(ExternalRefs, len(r))
+	for system, ref := range r {
+		result[strings.TrimSpace(system)] = strings.TrimSpace(ref)
+	}
+	return result
+}
+
+// CodeMigrations criam o índice que garante o SKU único por agente. Produtos sem SKU e
+// os que estão na lixeira ficam de fora, então um SKU volta a ficar livre quando o
+// produto é excluído.
+var CodeMigrations = []database.SQLMigration{
+	{
+		ID: "0005_products_sku_unique",
+		SQL: `
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_agent_sku
+	ON products (agent_id, sku) WHERE sku <> '' AND deleted_at IS NULL;`,
+	},
+}
@@ -0,0 +1,59 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/gallery.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/upload"
+)
+
+// maxGalleryImages é quantas imagens um produto pode ter.
+const maxGalleryImages = 20
+
+// ProductImage é uma imagem da galeria do produto, vinda da biblioteca de mídia. A capa
+// define image_url, images e image_asset_id do produto, mantidos para os clientes antigos.
+type ProductImage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProductID uint      `gorm:"not null;uniqueIndex:idx_product_images_asset" json:"product_id"`
+	AssetID   uint      `gorm:"not null;uniqueIndex:idx_product_images_asset" json:"asset_id"`
+	URLs      ImageSet  `gorm:"not null;default:'{}'" json:"urls"` // Cópia das URLs do asset, que não mudam.
+	AltText   string    `gorm:"not null;default:''" json:"alt_text"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	IsCover   bool      `gorm:"not null;default:false" json:"is_cover"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AddProductImageDTO - imagem para a galeria: um asset já enviado ou um arquivo novo.
+type AddProductImageDTO struct {
+	AssetID    uint
+	File       *upload.File
+	AltText    string // Vazio usa o texto alternativo do asset.
+	Cover      bool
+	UploadedBy *uint // Autor do envio, quando vem um arquivo.
+}
+
+// GalleryMigrations criam a galeria dos produtos que já tinham imagem da biblioteca de
+// mídia, usando-a como capa. Rodam depois do AutoMigrate, que cria product_images.
+var GalleryMigrations = []database.SQLMigration{
+	{
+		ID: "0003_product_images_from_cover",
+		SQL: `
+INSERT INTO product_images (product_id, asset_id, urls, alt_text, position, is_cover, created_at, updated_at)
+SELECT id, image_asset_id,
+	CASE WHEN coalesce(images->>'original', '') = ''
+		THEN jsonb_build_object('original', image_url, 'thumb', image_url, 'card', image_url, 'full', image_url)
+		ELSE images END,
+	'', 0, true, now(), now()
+FROM products
+WHERE image_asset_id IS NOT NULL
+ON CONFLICT DO NOTHING;`,
+	},
+}
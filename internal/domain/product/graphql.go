@@ -7,57 +7,261 @@
 */
 package product
 
-import "github.com/graphql-go/graphql"
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/events"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/upload"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+)
+
+// imageURLsType expõe as URLs de uma imagem em cada tamanho.
+var imageURLsType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "ProductImageURLs",
+		Fields: graphql.Fields{
+			"original": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"thumb":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"card":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"full":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	},
+)
 
-// Type principal do produto
-var productType = graphql.NewObject(
+// ProductImageType é uma imagem da galeria, exportado para os campos de relacionamento
+// (Product.images, Product.coverImage e ProductImage.asset).
+var ProductImageType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "ProductImage",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"product_id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"asset_id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"urls":       &graphql.Field{Type: graphql.NewNonNull(imageURLsType)},
+			"alt_text":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"position":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"is_cover":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"created_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	},
+)
+
+var externalRefType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "ProductExternalRef",
+		Fields: graphql.Fields{
+			"system": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"ref":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	},
+)
+
+var externalRefInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "ProductExternalRefInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"system": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "Sistema externo, como \"ifood\"."},
+			"ref":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "Código do produto nesse sistema."},
+		},
+	},
+)
+
+// ProductType é o tipo principal do produto, exportado para os campos de relacionamento.
+var ProductType = graphql.NewObject(
 	graphql.ObjectConfig{
 		Name: "Product",
 		Fields: graphql.Fields{
-			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
-			"agent_id":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
-			"category_id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
-			"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
-			"description": &graphql.Field{Type: graphql.String},
-			"price":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
-			"image_url":   &graphql.Field{Type: graphql.String},
-			"is_active":   &graphql.Field{Type: graphql.Boolean},
-			"created_at":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
-			"updated_at":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"id":                  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"agent_id":            &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"category_id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":                &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"description":         &graphql.Field{Type: graphql.String},
+			"sku":                 &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"barcode":             &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "EAN/GTIN."},
+			"image_url":           &graphql.Field{Type: graphql.String},
+			"image_asset_id":      &graphql.Field{Type: graphql.Int, Description: "Asset da biblioteca de mídia da capa do produto."},
+			"is_active":           &graphql.Field{Type: graphql.Boolean},
+			"position":            &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"tags":                &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"track_stock":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"stock":               &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Muda só pelo adjustStock. Em produtos com variações, vale o estoque de cada uma."},
+			"low_stock_threshold": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Estoque em que sai o aviso de estoque baixo; 0 não avisa."},
+			"auto_deactivate":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Desativa o produto (ou a variação) quando o estoque zera."},
+			"version":             &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"created_at":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"updated_at":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"external_refs": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(externalRefType))),
+				Description: "Código do produto em cada sistema externo, por ordem de sistema.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					product, _ := p.Source.(Product)
+					refs := make([]map[string]interface{}, 0, len(product.ExternalRefs))
+					for _, system := range product.ExternalRefs.Systems() {
+						refs = append(refs, map[string]interface{}{"system": system, "ref": product.ExternalRefs[system]})
+					}
+					return refs, nil
+				},
+			},
+			"deleted_at": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if m, ok := p.Source.(Product); ok && m.DeletedAt.Valid {
+						return m.DeletedAt.Time, nil
+					}
+					return nil, nil
+				},
+			},
 		},
 	},
 )
 
-var paginatedProductsType = graphql.NewObject(
+// updateProductInputType tem todos os campos opcionais: o que não for enviado não muda,
+// e null limpa description, imageUrl e imageAssetId.
+var updateProductInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UpdateProductInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"categoryId":        &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"name":              &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"description":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"price":             &graphql.InputObjectFieldConfig{Type: money.MoneyInputType},
+			"imageUrl":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"imageAssetId":      &graphql.InputObjectFieldConfig{Type: graphql.Int, Description: "Imagem da biblioteca de mídia; null remove a imagem."},
+			"isActive":          &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"position":          &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"tags":              &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"trackStock":        &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"lowStockThreshold": &graphql.InputObjectFieldConfig{Type: graphql.Int, Description: "null desliga o aviso."},
+			"autoDeactivate":    &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"sku":               &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Único por agente; null remove."},
+			"barcode":           &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "EAN/GTIN; null remove."},
+			"externalRefs":      &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(externalRefInputType)), Description: "Substitui todas as referências; null limpa."},
+		},
+	},
+)
+
+var productFilterInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "ProductFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"categoryIds": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+			"isActive":    &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"priceMin":    &graphql.InputObjectFieldConfig{Type: money.MoneyInputType, Description: "Só produtos nesta moeda, a partir deste valor."},
+			"priceMax":    &graphql.InputObjectFieldConfig{Type: money.MoneyInputType, Description: "Só produtos nesta moeda, até este valor."},
+			"text":        &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Procura no nome e na descrição."},
+			"createdAt":   &graphql.InputObjectFieldConfig{Type: pagination.TimeRangeInput},
+		},
+	},
+)
+
+var productSortInputType = pagination.SortInput("Product", graphql.EnumValueConfigMap{
+	"NAME":       &graphql.EnumValueConfig{Value: "name"},
+	"PRICE":      &graphql.EnumValueConfig{Value: "price_amount"},
+	"CREATED_AT": &graphql.EnumValueConfig{Value: "created_at"},
+	"POSITION":   &graphql.EnumValueConfig{Value: "position"},
+})
+
+// filterFromArgs lê o argumento filter da query products.
+func filterFromArgs(args map[string]interface{}) (ProductFilter, error) {
+	var filter ProductFilter
+	input, ok := args["filter"].(map[string]interface{})
+	if !ok {
+		return filter, nil
+	}
+	if ids, ok := input["categoryIds"].([]interface{}); ok {
+		for _, id := range ids {
+			if v, ok := id.(int); ok {
+				filter.CategoryIDs = append(filter.CategoryIDs, uint(v))
+			}
+		}
+	}
+	if v, ok := input["isActive"].(bool); ok {
+		filter.IsActive = &v
+	}
+	var err error
+	if filter.PriceMin, err = priceFromArgs(input, "priceMin"); err != nil {
+		return filter, err
+	}
+	if filter.PriceMax, err = priceFromArgs(input, "priceMax"); err != nil {
+		return filter, err
+	}
+	filter.Text, _ = input["text"].(string)
+	createdAt, err := pagination.TimeRangeFromArgs(input["createdAt"])
+	if err != nil {
+		return filter, err
+	}
+	filter.CreatedAt = createdAt
+	return filter, nil
+}
+
+// priceFromArgs lê um dos limites de preço do filtro, recusando valores que o cadastro
+// também recusaria.
+func priceFromArgs(input map[string]interface{}, name string) (*money.Money, error) {
+	value, ok := money.InputFromArgs(input[name])
+	if !ok {
+		return nil, nil
+	}
+	if err := validate.All(validate.Field(name, value, validate.Money)); err != nil {
+		return nil, err
+	}
+	price, _ := value.Parse()
+	return &price, nil
+}
+
+var productSearchResultType = graphql.NewObject(
 	graphql.ObjectConfig{
-		Name: "PaginatedProducts",
+		Name: "ProductSearchResult",
 		Fields: graphql.Fields{
-			"data":        &graphql.Field{Type: graphql.NewList(productType)},
-			"total":       &graphql.Field{Type: graphql.Int},
-			"page":        &graphql.Field{Type: graphql.Int},
-			"per_page":    &graphql.Field{Type: graphql.Int},
-			"total_pages": &graphql.Field{Type: graphql.Int},
+			"product": &graphql.Field{
+				Type: graphql.NewNonNull(ProductType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if result, ok := p.Source.(SearchResult); ok {
+						return result.Product, nil
+					}
+					return nil, nil
+				},
+			},
+			"rank":           &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"name_highlight": &graphql.Field{Type: graphql.String, Description: "Nome em HTML escapado, com os termos encontrados entre <mark> e </mark>."},
+			"snippet":        &graphql.Field{Type: graphql.String, Description: "Trechos da descrição em HTML escapado, com os termos encontrados entre <mark> e </mark>."},
 		},
 	},
 )
 
+var productSearchConnectionType = pagination.ConnectionType("ProductSearchResult", productSearchResultType)
+
+// ProductConnectionType também é usado em Category.products.
+var ProductConnectionType = pagination.ConnectionType("Product", ProductType)
+
 func GetQueryFields(service Service) graphql.Fields {
-	return graphql.Fields{
+	fields := graphql.Fields{
 		"products": &graphql.Field{
-			Type:        paginatedProductsType,
+			Type:        ProductConnectionType,
 			Description: "Obtém produtos para um agente específico.",
-			Args: graphql.FieldConfigArgument{
+			Args: pagination.Args(graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"page":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
-			},
+				"filter":  &graphql.ArgumentConfig{Type: productFilterInputType},
+				"sort":    &graphql.ArgumentConfig{Type: productSortInputType},
+			}),
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				agentId, _ := p.Args["agentId"].(int)
-				page, _ := p.Args["page"].(int)
-				return service.GetAllProducts(uint(agentId), page)
+				page, err := pagination.PageFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				filter, err := filterFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return service.GetAllProducts(uint(agentId), filter, pagination.SortFromArgs(p.Args), page)
 			},
 		},
 		"product": &graphql.Field{
-			Type:        productType,
+			Type:        ProductType,
 			Description: "Obtém um produto pelo seu ID, dentro de um agente.",
 			Args: graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
@@ -70,21 +274,26 @@ func GetQueryFields(service Service) graphql.Fields {
 			},
 		},
 		"searchProducts": &graphql.Field{
-			Type:        graphql.NewList(productType),
-			Description: "Busca produtos por nome, dentro de um agente.",
-			Args: graphql.FieldConfigArgument{
+			Type:        productSearchConnectionType,
+			Description: "Busca textual nos produtos de um agente, por nome, tags e descrição, ignorando acentos e tolerando erros de digitação. Os mais relevantes vêm primeiro.",
+			Args: pagination.Args(graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"name":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-			},
+				"query":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "Aceita aspas para frases e - para excluir termos."},
+			}),
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				agentId, _ := p.Args["agentId"].(int)
-				name, _ := p.Args["name"].(string)
-				return service.SearchProducts(uint(agentId), name)
+				query, _ := p.Args["query"].(string)
+				page, err := pagination.PageFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return service.SearchProducts(uint(agentId), query, page)
 			},
 		},
 		"productsByCategory": &graphql.Field{
-			Type:        graphql.NewList(productType),
-			Description: "Busca produtos por category_id e agent_id.",
+			Type:              graphql.NewList(ProductType),
+			Description:       "Busca produtos por category_id e agent_id.",
+			DeprecationReason: "Use products com filter.categoryIds.",
 			Args: graphql.FieldConfigArgument{
 				"agentId":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
 				"categoryId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
@@ -95,75 +304,122 @@ func GetQueryFields(service Service) graphql.Fields {
 				return service.SearchByCategory(agentId, categoryId)
 			},
 		},
+		"productBySku": &graphql.Field{
+			Type:        ProductType,
+			Description: "Obtém um produto pelo SKU, dentro de um agente.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"sku":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				sku, _ := p.Args["sku"].(string)
+				return service.GetProductBySKU(uint(agentId), sku)
+			},
+		},
+		"productByBarcode": &graphql.Field{
+			Type:        ProductType,
+			Description: "Obtém um produto pelo código de barras (EAN/GTIN), dentro de um agente. Se mais de um produto tiver o código, vem o mais antigo.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"barcode": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				barcode, _ := p.Args["barcode"].(string)
+				return service.GetProductByBarcode(uint(agentId), barcode)
+			},
+		},
+		"trashedProducts": &graphql.Field{
+			Type:        graphql.NewList(ProductType),
+			Description: "Lista os produtos excluídos de um agente que ainda podem ser restaurados.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.GetTrashedProducts(uint(agentId))
+			},
+		},
+	}
+	for name, field := range optionQueryFields(service) {
+		fields[name] = field
+	}
+	for name, field := range stockQueryFields(service) {
+		fields[name] = field
 	}
+	return fields
 }
 
 func GetMutationFields(service Service) graphql.Fields {
-	return graphql.Fields{
+	fields := graphql.Fields{
 		"createProduct": &graphql.Field{
-			Type:        productType,
+			Type:        ProductType,
 			Description: "Cria um novo produto para um agente.",
-			Args: graphql.FieldConfigArgument{
-				"agentId":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"categoryId":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"name":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-				"description": &graphql.ArgumentConfig{Type: graphql.String},
-				"price":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
-				"imageUrl":    &graphql.ArgumentConfig{Type: graphql.String},
-				"isActive":    &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: true},
+			Args:        createProductArgs(),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return service.CreateProduct(createDTOFromArgs(p.Args))
 			},
+		},
+		"upsertProductBySku": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "UpsertProductPayload",
+				Fields: graphql.Fields{
+					"product": &graphql.Field{Type: graphql.NewNonNull(ProductType)},
+					"created": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Falso quando um produto com o SKU já existia e foi atualizado."},
+				},
+			}),
+			Description: "Cria o produto ou, se o agente já tiver um com o mesmo SKU, substitui os dados dele pelos enviados. A imagem só muda quando enviada; o estoque, só pelo adjustStock.",
+			Args:        createProductArgs(),
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				dto := CreateProductDTO{
-					AgentID:     uint(p.Args["agentId"].(int)),
-					CategoryID:  uint(p.Args["categoryId"].(int)),
-					Name:        p.Args["name"].(string),
-					Description: "",
-					Price:       p.Args["price"].(float64),
-					ImageURL:    "",
-					IsActive:    true,
-				}
-				if v, ok := p.Args["description"]; ok && v != nil {
-					dto.Description = v.(string)
-				}
-				if v, ok := p.Args["imageUrl"]; ok && v != nil {
-					dto.ImageURL = v.(string)
+				dto := createDTOFromArgs(p.Args)
+				if _, err := session.RequireAgent(p.Context, dto.AgentID); err != nil {
+					return nil, err
 				}
-				if v, ok := p.Args["isActive"]; ok && v != nil {
-					dto.IsActive = v.(bool)
+				product, created, err := service.UpsertProductBySKU(dto)
+				if err != nil {
+					return nil, err
 				}
-				return service.CreateProduct(dto)
+				return map[string]interface{}{"product": product, "created": created}, nil
 			},
 		},
 		"updateProduct": &graphql.Field{
-			Type:        productType,
-			Description: "Atualiza um produto de um agente.",
+			Type:        ProductType,
+			Description: "Atualiza parcialmente um produto de um agente.",
 			Args: graphql.FieldConfigArgument{
-				"agentId":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"id":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"categoryId":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"name":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-				"description": &graphql.ArgumentConfig{Type: graphql.String},
-				"price":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
-				"imageUrl":    &graphql.ArgumentConfig{Type: graphql.String},
-				"isActive":    &graphql.ArgumentConfig{Type: graphql.Boolean},
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"version": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int), Description: "Versão lida pelo cliente."},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateProductInputType)},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				input := patch.InputFromArgs(p, "input")
 				dto := UpdateProductDTO{
-					CategoryID:  uint(p.Args["categoryId"].(int)),
-					Name:        p.Args["name"].(string),
-					Description: "",
-					Price:       p.Args["price"].(float64),
-					ImageURL:    "",
-					IsActive:    true,
-				}
-				if v, ok := p.Args["description"]; ok && v != nil {
-					dto.Description = v.(string)
-				}
-				if v, ok := p.Args["imageUrl"]; ok && v != nil {
-					dto.ImageURL = v.(string)
-				}
-				if v, ok := p.Args["isActive"]; ok && v != nil {
-					dto.IsActive = v.(bool)
+					Version:           uint(p.Args["version"].(int)),
+					CategoryID:        patch.Map(patch.Get[int](input, "categoryId"), func(v int) uint { return uint(v) }),
+					Name:              patch.Get[string](input, "name"),
+					Description:       patch.Get[string](input, "description"),
+					Price:             patch.Map(patch.Get[map[string]interface{}](input, "price"), priceInputOf),
+					ImageURL:          patch.Get[string](input, "imageUrl"),
+					ImageAssetID:      patch.Map(patch.Get[int](input, "imageAssetId"), func(v int) uint { return uint(v) }),
+					IsActive:          patch.Get[bool](input, "isActive"),
+					Position:          patch.Get[int](input, "position"),
+					Tags:              patch.Map(patch.Get[[]interface{}](input, "tags"), func(v []interface{}) database.StringArray { return toStrings(v) }),
+					TrackStock:        patch.Get[bool](input, "trackStock"),
+					LowStockThreshold: patch.Get[int](input, "lowStockThreshold"),
+					AutoDeactivate:    patch.Get[bool](input, "autoDeactivate"),
+					SKU:               patch.Get[string](input, "sku"),
+					Barcode:           patch.Get[string](input, "barcode"),
+					ExternalRefs:      patch.Map(patch.Get[[]interface{}](input, "externalRefs"), externalRefsOf),
 				}
 				agentId := uint(p.Args["agentId"].(int))
 				id := uint(p.Args["id"].(int))
@@ -193,5 +449,264 @@ func GetMutationFields(service Service) graphql.Fields {
 				return map[string]interface{}{"deletedId": id, "success": true}, nil
 			},
 		},
+		"restoreProduct": &graphql.Field{
+			Type:        ProductType,
+			Description: "Restaura um produto excluído de um agente.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.RestoreProduct(uint(agentId), uint(id))
+			},
+		},
+		"uploadProductImage": &graphql.Field{
+			Type:        ProductType,
+			Description: "Envia a imagem de um produto (requisição multipart) e a coloca no lugar da capa da galeria.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"file":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(upload.Scalar)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				productId, _ := p.Args["productId"].(int)
+				identity, err := session.RequireAgent(p.Context, uint(agentId))
+				if err != nil {
+					return nil, err
+				}
+				file, err := upload.FromArgs(p.Context, "file", p.Args["file"])
+				if err != nil {
+					return nil, err
+				}
+				return service.UploadImage(uint(agentId), uint(productId), file, &identity.UserID)
+			},
+		},
+		"addProductImage": &graphql.Field{
+			Type:        ProductType,
+			Description: "Inclui uma imagem no fim da galeria do produto: um asset da biblioteca de mídia (assetId) ou um arquivo novo (file, em requisição multipart).",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"assetId":   &graphql.ArgumentConfig{Type: graphql.Int},
+				"file":      &graphql.ArgumentConfig{Type: upload.Scalar},
+				"altText":   &graphql.ArgumentConfig{Type: graphql.String, Description: "Texto alternativo; sem ele, vale o do asset."},
+				"cover":     &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false, Description: "Torna a imagem a capa."},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				productId, _ := p.Args["productId"].(int)
+				identity, err := session.RequireAgent(p.Context, uint(agentId))
+				if err != nil {
+					return nil, err
+				}
+				dto := AddProductImageDTO{UploadedBy: &identity.UserID}
+				if v, ok := p.Args["assetId"].(int); ok {
+					dto.AssetID = uint(v)
+				}
+				if v, ok := p.Args["file"]; ok && v != nil {
+					file, err := upload.FromArgs(p.Context, "file", v)
+					if err != nil {
+						return nil, err
+					}
+					dto.File = &file
+				}
+				dto.AltText, _ = p.Args["altText"].(string)
+				dto.Cover, _ = p.Args["cover"].(bool)
+				return service.AddImage(uint(agentId), uint(productId), dto)
+			},
+		},
+		"reorderProductImages": &graphql.Field{
+			Type:        ProductType,
+			Description: "Define a ordem da galeria do produto. imageIds deve listar todas as imagens dele.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"imageIds":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.Int)))},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				productId, _ := p.Args["productId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				values, _ := p.Args["imageIds"].([]interface{})
+				imageIDs := make([]uint, 0, len(values))
+				for _, v := range values {
+					id, _ := v.(int)
+					imageIDs = append(imageIDs, uint(id))
+				}
+				return service.ReorderImages(uint(agentId), uint(productId), imageIDs)
+			},
+		},
+		"setProductCoverImage": &graphql.Field{
+			Type:        ProductType,
+			Description: "Torna uma imagem da galeria a capa do produto.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"imageId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				productId, _ := p.Args["productId"].(int)
+				imageId, _ := p.Args["imageId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.SetCoverImage(uint(agentId), uint(productId), uint(imageId))
+			},
+		},
+		"removeProductImage": &graphql.Field{
+			Type:        ProductType,
+			Description: "Tira uma imagem da galeria do produto. Se era a capa, a próxima na ordem assume.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"imageId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				productId, _ := p.Args["productId"].(int)
+				imageId, _ := p.Args["imageId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.RemoveImage(uint(agentId), uint(productId), uint(imageId))
+			},
+		},
+	}
+	for name, field := range optionMutationFields(service) {
+		fields[name] = field
+	}
+	for name, field := range stockMutationFields(service) {
+		fields[name] = field
+	}
+	return fields
+}
+
+// createProductArgs são os argumentos de createProduct e upsertProductBySku.
+func createProductArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"agentId":           &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"categoryId":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"name":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		"description":       &graphql.ArgumentConfig{Type: graphql.String},
+		"price":             &graphql.ArgumentConfig{Type: graphql.NewNonNull(money.MoneyInputType)},
+		"imageUrl":          &graphql.ArgumentConfig{Type: graphql.String},
+		"imageAssetId":      &graphql.ArgumentConfig{Type: graphql.Int, Description: "Imagem da biblioteca de mídia; alternativa a imageUrl."},
+		"isActive":          &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: true},
+		"position":          &graphql.ArgumentConfig{Type: graphql.Int},
+		"tags":              &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		"trackStock":        &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false, Description: "Controla o estoque, que começa em zero; entradas vão pelo adjustStock."},
+		"lowStockThreshold": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Estoque em que sai o aviso de estoque baixo."},
+		"autoDeactivate":    &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false, Description: "Desativa o produto (ou a variação) quando o estoque zera."},
+		"sku":               &graphql.ArgumentConfig{Type: graphql.String, Description: "Único por agente; obrigatório no upsertProductBySku."},
+		"barcode":           &graphql.ArgumentConfig{Type: graphql.String, Description: "EAN/GTIN de 8, 12, 13 ou 14 dígitos."},
+		"externalRefs":      &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(externalRefInputType))},
+	}
+}
+
+// createDTOFromArgs lê os argumentos de createProductArgs.
+func createDTOFromArgs(args map[string]interface{}) CreateProductDTO {
+	priceInput, _ := money.InputFromArgs(args["price"])
+	dto := CreateProductDTO{
+		AgentID:     uint(args["agentId"].(int)),
+		CategoryID:  uint(args["categoryId"].(int)),
+		Name:        args["name"].(string),
+		Description: "",
+		Price:       priceInput,
+		ImageURL:    "",
+		IsActive:    true,
+	}
+	if v, ok := args["description"]; ok && v != nil {
+		dto.Description = v.(string)
+	}
+	if v, ok := args["imageUrl"]; ok && v != nil {
+		dto.ImageURL = v.(string)
+	}
+	if v, ok := args["imageAssetId"].(int); ok {
+		assetID := uint(v)
+		dto.ImageAssetID = &assetID
+	}
+	if v, ok := args["isActive"]; ok && v != nil {
+		dto.IsActive = v.(bool)
+	}
+	if v, ok := args["position"].(int); ok {
+		dto.Position = v
+	}
+	if v, ok := args["tags"].([]interface{}); ok {
+		dto.Tags = toStrings(v)
+	}
+	dto.TrackStock, _ = args["trackStock"].(bool)
+	dto.LowStockThreshold, _ = args["lowStockThreshold"].(int)
+	dto.AutoDeactivate, _ = args["autoDeactivate"].(bool)
+	dto.SKU, _ = args["sku"].(string)
+	dto.Barcode, _ = args["barcode"].(string)
+	if v, ok := args["externalRefs"].([]interface{}); ok {
+		dto.ExternalRefs = externalRefsOf(v)
+	}
+	return dto
+}
+
+// externalRefsOf converte a lista de ProductExternalRefInput. Um sistema repetido fica
+// com a última referência.
+func externalRefsOf(values []interface{}) ExternalRefs {
+	refs := make(ExternalRefs, len(values))
+	for _, value := range values {
+		fields, _ := value.(map[string]interface{})
+		system, _ := fields["system"].(string)
+		ref, _ := fields["ref"].(string)
+		refs[system] = ref
+	}
+	return refs
+}
+
+// toStrings converte uma lista recebida do GraphQL.
+func toStrings(values []interface{}) database.StringArray {
+	result := make(database.StringArray, 0, len(values))
+	for _, value := range values {
+		if text, ok := value.(string); ok {
+			result = append(result, text)
+		}
+	}
+	return result
+}
+
+// priceInputOf converte o MoneyInput do input de atualização.
+func priceInputOf(value map[string]interface{}) money.Input {
+	input, _ := money.InputFromArgs(value)
+	return input
+}
+
+var productChangeEventType = events.ChangeEventType("Product", "product", ProductType)
+
+// GetSubscriptionFields expõe as mudanças nos produtos de um agente e os avisos de estoque.
+// Só usuários do próprio agente (ou administradores) podem se inscrever.
+func GetSubscriptionFields(bus *events.Bus) graphql.Fields {
+	return graphql.Fields{
+		"productChanged": &graphql.Field{
+			Type:        graphql.NewNonNull(productChangeEventType),
+			Description: "Avisa quando um produto do agente é criado, alterado, excluído ou restaurado.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return bus.Subscribe(p.Context, events.TopicProduct, uint(agentId)), nil
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source, nil
+			},
+		},
+		"stockAlert": stockAlertField(bus),
 	}
 }
@@ -0,0 +1,280 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/image_migration.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/storage"
+)
+
+// ImageMigrationResult resume uma execução de ImageMigration.
+type ImageMigrationResult struct {
+	Products int // Produtos com as URLs reescritas.
+	Copied   int // Objetos gravados no layout novo.
+	Deleted  int // Objetos do layout antigo apagados.
+	Adopted  int // Produtos ligados a um asset da biblioteca de mídia.
+	Failed   int // Produtos que ficaram no layout antigo por erro.
+}
+
+// ImageMigration move as imagens gravadas no layout antigo (products/<arquivo> e
+// products/<produto>/<n>/<variante>) para agents/<agente>/products/<uuid>.<ext>, reescreve
+// as URLs dos produtos e registra cada imagem como asset da biblioteca de mídia, ligado
+// ao produto. Pode ser executada de novo: produtos já migrados são ignorados.
+type ImageMigration struct {
+	repo    Repository
+	assets  asset.Linker
+	store   storage.Store
+	dryRun  bool
+	adopted int
+
+	names    map[string]uuid.UUID // Chave antiga do original -> nome derivado do conteúdo.
+	obsolete map[string]bool      // Chaves antigas que não são mais usadas.
+	retained map[string]bool      // Chaves antigas ainda usadas por produtos que falharam.
+}
+
+// NewImageMigration cria a migração. Com dryRun, só informa o que seria feito.
+func NewImageMigration(repo Repository, assets asset.Linker, store storage.Store, dryRun bool) *ImageMigration {
+	return &ImageMigration{
+		repo:     repo,
+		assets:   assets,
+		store:    store,
+		dryRun:   dryRun,
+		names:    map[string]uuid.UUID{},
+		obsolete: map[string]bool{},
+		retained: map[string]bool{},
+	}
+}
+
+// Run percorre todos os produtos com imagem, de todos os agentes e inclusive da lixeira.
+// Os objetos antigos só são apagados no fim, porque no layout antigo o mesmo arquivo
+// podia ser a imagem de produtos de agentes diferentes.
+func (m *ImageMigration) Run(batchSize int) (ImageMigrationResult, error) {
+	var result ImageMigrationResult
+	var afterID uint
+	for {
+		products, err := m.repo.FindWithImages(afterID, batchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(products) == 0 {
+			break
+		}
+		for _, product := range products {
+			afterID = product.ID
+			migrated, copied, err := m.migrate(product)
+			result.Copied += copied
+			if err != nil {
+				log.Printf("Falha ao migrar as imagens do produto %d: %v", product.ID, err)
+				result.Failed++
+				continue
+			}
+			if migrated {
+				result.Products++
+			}
+		}
+	}
+
+	result.Adopted = m.adopted
+
+	for key := range m.obsolete {
+		if m.retained[key] {
+			continue
+		}
+		if m.dryRun {
+			log.Printf("apagaria %s", key)
+			result.Deleted++
+			continue
+		}
+		if err := m.store.Delete(key); err != nil {
+			log.Printf("Falha ao apagar %s: %v", key, err)
+			continue
+		}
+		result.Deleted++
+	}
+	return result, nil
+}
+
+// migrate copia as imagens de um produto para a pasta do agente e troca as URLs.
+func (m *ImageMigration) migrate(product Product) (bool, int, error) {
+	legacy := product.Images.Original == ""
+	set := product.Images
+	if legacy {
+		set = ImageSet{Original: product.ImageURL}
+	}
+	originalKey, ok := m.legacyKey(product.AgentID, set.Original)
+	if !ok {
+		return false, 0, m.adopt(product)
+	}
+
+	variants := map[string]*string{"original": &set.Original, "thumb": &set.Thumb, "card": &set.Card, "full": &set.Full}
+	oldKeys := map[string]string{}
+	for variant, url := range variants {
+		if key, ok := m.legacyKey(product.AgentID, *url); ok {
+			oldKeys[variant] = key
+		}
+	}
+	var copied int
+	fail := func(err error) (bool, int, error) {
+		for _, key := range oldKeys {
+			m.retained[key] = true
+		}
+		return false, copied, err
+	}
+
+	name, err := m.name(originalKey)
+	if err != nil {
+		return fail(err)
+	}
+	moved := map[string]string{}
+	newKeys := map[string]string{}
+	for variant, oldKey := range oldKeys {
+		newKey := asset.ImageKey(product.AgentID, asset.KindProductImage, name, variant, path.Ext(oldKey))
+		newKeys[variant] = newKey
+		done, err := m.copy(oldKey, newKey)
+		if err != nil {
+			return fail(err)
+		}
+		if done {
+			copied++
+		}
+		url := variants[variant]
+		moved[*url] = m.store.URL(newKey)
+		*url = moved[*url]
+	}
+
+	imageURL := set.Original
+	if !legacy {
+		imageURL = product.ImageURL
+		if url, ok := moved[imageURL]; ok {
+			imageURL = url
+		}
+	} else {
+		set = ImageSet{}
+	}
+	if m.dryRun {
+		log.Printf("produto %d: %s -> %s", product.ID, product.ImageURL, imageURL)
+	} else if err := m.link(product, imageURL, set, newKeys); err != nil {
+		return fail(err)
+	}
+	for _, key := range oldKeys {
+		m.obsolete[key] = true
+	}
+	return true, copied, nil
+}
+
+// adopt registra como asset a imagem de um produto que já está na pasta do agente, mas
+// foi gravada antes da biblioteca de mídia existir. Sem isso, a coleta de assets sem uso
+// poderia apagar um arquivo que o produto ainda mostra.
+func (m *ImageMigration) adopt(product Product) error {
+	if product.ImageAssetID != nil {
+		return nil
+	}
+	set := product.Images
+	if set.Original == "" {
+		set = ImageSet{Original: product.ImageURL}
+	}
+	variants := map[string]string{"original": set.Original, "thumb": set.Thumb, "card": set.Card, "full": set.Full}
+	keys := map[string]string{}
+	for variant, url := range variants {
+		if key, ok := storage.KeyFromURL(m.store, url); ok && strings.HasPrefix(key, asset.ImagePrefix(product.AgentID, asset.KindProductImage)) {
+			keys[variant] = key
+		}
+	}
+	if _, ok := keys["original"]; !ok {
+		return nil // URL externa: não há arquivo nosso para registrar.
+	}
+	if m.dryRun {
+		log.Printf("produto %d: registraria %s como asset", product.ID, keys["original"])
+		m.adopted++
+		return nil
+	}
+	return m.link(product, product.ImageURL, product.Images, keys)
+}
+
+// link registra as chaves como asset, liga o asset ao produto e grava as URLs. O vínculo
+// vem antes da gravação para que, se ela falhar, o asset não fique sem uso.
+func (m *ImageMigration) link(product Product, imageURL string, set ImageSet, keys map[string]string) error {
+	image, err := m.assets.AdoptImage(product.AgentID, asset.KindProductImage, keys)
+	if err != nil {
+		return err
+	}
+	if _, err := m.assets.Link(product.AgentID, image.ID, asset.Owner{Type: asset.OwnerProduct, ID: product.ID}); err != nil {
+		return err
+	}
+	if err := m.repo.SetImages(product.ID, imageURL, set, &image.ID); err != nil {
+		return err
+	}
+	m.adopted++
+	return nil
+}
+
+// legacyKey devolve a chave de uma URL do store que ainda não está na pasta do agente.
+func (m *ImageMigration) legacyKey(agentID uint, url string) (string, bool) {
+	key, ok := storage.KeyFromURL(m.store, url)
+	if !ok || strings.HasPrefix(key, asset.ImagePrefix(agentID, asset.KindProductImage)) {
+		return "", false
+	}
+	return key, true
+}
+
+// name deriva o nome da imagem do conteúdo do original, como no upload.
+func (m *ImageMigration) name(key string) (uuid.UUID, error) {
+	if name, ok := m.names[key]; ok {
+		return name, nil
+	}
+	data, err := m.read(key)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	name := asset.ImageName(data)
+	m.names[key] = name
+	return name, nil
+}
+
+// copy grava o objeto em newKey, a menos que ele já exista (migração repetida ou imagem
+// igual já enviada pelo agente).
+func (m *ImageMigration) copy(oldKey, newKey string) (bool, error) {
+	exists, err := m.store.Exists(newKey)
+	if err != nil || exists {
+		return false, err
+	}
+	if m.dryRun {
+		log.Printf("copiaria %s -> %s", oldKey, newKey)
+		return true, nil
+	}
+	data, err := m.read(oldKey)
+	if err != nil {
+		return false, err
+	}
+	contentType := mime.TypeByExtension(path.Ext(oldKey))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if err := m.store.Put(newKey, contentType, bytes.NewReader(data)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *ImageMigration) read(key string) ([]byte, error) {
+	body, err := m.store.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("ler %s: %w", key, err)
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
@@ -0,0 +1,64 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/images.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
+)
+
+// ImageSet são as URLs da imagem do produto no tamanho original e em cada variante.
+type ImageSet struct {
+	Original string `json:"original"`
+	Thumb    string `json:"thumb"`
+	Card     string `json:"card"`
+	Full     string `json:"full"`
+}
+
+// GormDataType faz o AutoMigrate criar a coluna como jsonb.
+func (ImageSet) GormDataType() string {
+	return "jsonb"
+}
+
+func (s ImageSet) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	return string(data), err
+}
+
+func (s *ImageSet) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*s = ImageSet{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), s)
+	case []byte:
+		return json.Unmarshal(value, s)
+	default:
+		return fmt.Errorf("ImageSet: tipo não suportado %T", src)
+	}
+}
+
+// URLs lista as URLs preenchidas.
+func (s ImageSet) URLs() []string {
+	var urls []string
+	for _, url := range []string{s.Original, s.Thumb, s.Card, s.Full} {
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// imageSetOf monta as URLs da imagem a partir de um asset da biblioteca de mídia.
+func imageSetOf(a asset.Asset) ImageSet {
+	return ImageSet{Original: a.URL, Thumb: a.VariantURL("thumb"), Card: a.VariantURL("card"), Full: a.VariantURL("full")}
+}
@@ -7,48 +7,90 @@
 */
 package product
 
-import "time"
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"gorm.io/gorm"
+)
 
 // Product representa o produto no banco de dados.
 type Product struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	AgentID     uint      `gorm:"not null" json:"agent_id"`
-	CategoryID  uint      `gorm:"not null" json:"category_id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `gorm:"not null" json:"price"`
-	ImageURL    string    `json:"image_url"`
-	IsActive    bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uint                 `gorm:"primaryKey" json:"id"`
+	AgentID           uint                 `gorm:"not null;index:idx_products_agent_barcode,priority:1" json:"agent_id"`
+	CategoryID        uint                 `gorm:"not null" json:"category_id"`
+	Name              string               `gorm:"not null" json:"name"`
+	Description       string               `json:"description"`
+	SKU               string               `gorm:"size:64;not null;default:''" json:"sku"`                                                 // Único por agente; vazio é sem SKU.
+	Barcode           string               `gorm:"size:14;not null;default:'';index:idx_products_agent_barcode,priority:2" json:"barcode"` // EAN/GTIN com o dígito verificador conferido.
+	ExternalRefs      ExternalRefs         `gorm:"not null;default:'{}'" json:"external_refs"`                                             // Código do produto em cada sistema externo.
+	Price             money.Money          `gorm:"embedded;embeddedPrefix:price_" json:"price"`                                            // Colunas price_amount (centavos) e price_currency.
+	ImageURL          string               `json:"image_url"`
+	Images            ImageSet             `gorm:"not null;default:'{}'" json:"images"` // Variantes geradas no upload; vazio para URLs externas.
+	ImageAssetID      *uint                `gorm:"index" json:"image_asset_id"`         // Asset da biblioteca de mídia de onde vem a imagem.
+	IsActive          bool                 `gorm:"default:true" json:"is_active"`
+	Position          int                  `gorm:"not null;default:0" json:"position"` // Ordem de exibição definida pelo lojista.
+	Tags              database.StringArray `gorm:"not null;default:'{}'" json:"tags"`
+	TrackStock        bool                 `gorm:"not null;default:false" json:"track_stock"`     // Sem controle, o estoque não é conferido nem baixado.
+	Stock             int                  `gorm:"not null;default:0" json:"stock"`               // Só muda por StockMovement; com variações, o estoque é o delas.
+	LowStockThreshold int                  `gorm:"not null;default:0" json:"low_stock_threshold"` // Avisa quando o estoque chega a este valor; 0 não avisa.
+	AutoDeactivate    bool                 `gorm:"not null;default:false" json:"auto_deactivate"` // Desativa o produto (ou a variação) quando o estoque zera.
+	Version           uint                 `gorm:"not null;default:1" json:"version"`             // Incrementada a cada atualização (controle otimista).
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt       `gorm:"index" json:"deleted_at"`
 }
 
 // CreateProductDTO - dados para criar produto
 type CreateProductDTO struct {
-	AgentID     uint    `json:"agent_id"`
-	CategoryID  uint    `json:"category_id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	ImageURL    string  `json:"image_url"`
-	IsActive    bool    `json:"is_active"`
+	AgentID           uint         `json:"agent_id"`
+	CategoryID        uint         `json:"category_id"`
+	Name              string       `json:"name"`
+	Description       string       `json:"description"`
+	Price             money.Input  `json:"price"`
+	ImageURL          string       `json:"image_url"`
+	ImageAssetID      *uint        `json:"image_asset_id"` // Alternativa a ImageURL: usa uma imagem da biblioteca de mídia.
+	IsActive          bool         `json:"is_active"`
+	Position          int          `json:"position"`
+	Tags              []string     `json:"tags"`
+	TrackStock        bool         `json:"track_stock"`
+	LowStockThreshold int          `json:"low_stock_threshold"`
+	AutoDeactivate    bool         `json:"auto_deactivate"`
+	SKU               string       `json:"sku"`
+	Barcode           string       `json:"barcode"`
+	ExternalRefs      ExternalRefs `json:"external_refs"`
 }
 
-// UpdateProductDTO - dados para atualizar produto
+// UpdateProductDTO - dados para atualizar produto. Campos não enviados ficam como estão;
+// null explícito limpa description, image_url, image_asset_id, sku, barcode e external_refs.
 type UpdateProductDTO struct {
-	CategoryID  uint    `json:"category_id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	ImageURL    string  `json:"image_url"`
-	IsActive    bool    `json:"is_active"`
+	Version           uint                              `json:"version"` // Versão lida pelo cliente; se mudou, a atualização é recusada.
+	CategoryID        patch.Field[uint]                 `json:"category_id"`
+	Name              patch.Field[string]               `json:"name"`
+	Description       patch.Field[string]               `json:"description"`
+	Price             patch.Field[money.Input]          `json:"price"`
+	ImageURL          patch.Field[string]               `json:"image_url"`
+	ImageAssetID      patch.Field[uint]                 `json:"image_asset_id"`
+	IsActive          patch.Field[bool]                 `json:"is_active"`
+	Position          patch.Field[int]                  `json:"position"`
+	Tags              patch.Field[database.StringArray] `json:"tags"`
+	TrackStock        patch.Field[bool]                 `json:"track_stock"`
+	LowStockThreshold patch.Field[int]                  `json:"low_stock_threshold"`
+	AutoDeactivate    patch.Field[bool]                 `json:"auto_deactivate"`
+	SKU               patch.Field[string]               `json:"sku"`
+	Barcode           patch.Field[string]               `json:"barcode"`
+	ExternalRefs      patch.Field[ExternalRefs]         `json:"external_refs"` // Substitui todas as referências.
 }
 
-// PaginatedProducts - resposta paginada
-type PaginatedProducts struct {
-	Data       []Product `json:"data"`
-	Total      int64     `json:"total"`
-	Page       int       `json:"page"`
-	PerPage    int       `json:"per_page"`
-	TotalPages int       `json:"total_pages"`
+// ProductFilter restringe a lista de produtos. Campos vazios não filtram.
+type ProductFilter struct {
+	CategoryIDs []uint
+	IsActive    *bool
+	PriceMin    *money.Money // Só produtos na mesma moeda, a partir deste valor.
+	PriceMax    *money.Money
+	Text        string // Procura no nome e na descrição.
+	CreatedAt   pagination.TimeRange
 }
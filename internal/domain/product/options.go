@@ -0,0 +1,151 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/options.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
+
+// Limites por produto, para o cardápio continuar utilizável.
+const (
+	maxOptionGroups    = 20
+	maxOptionsPerGroup = 50
+	maxVariants        = 100
+)
+
+// OptionGroup é um grupo de opções do produto, como "Borda" ou "Adicionais". O cliente
+// escolhe entre MinSelect e MaxSelect opções do grupo.
+type OptionGroup struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AgentID   uint      `gorm:"not null;index" json:"agent_id"`
+	ProductID uint      `gorm:"not null;index" json:"product_id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Multiple  bool      `gorm:"not null;default:false" json:"multiple"` // Falso: escolha única.
+	MinSelect int       `gorm:"not null;default:0" json:"min_select"`
+	MaxSelect int       `gorm:"not null;default:0" json:"max_select"`   // 0 é sem limite; na escolha única vale 1.
+	Required  bool      `gorm:"not null;default:false" json:"required"` // Exige pelo menos uma opção, mesmo com MinSelect 0.
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	Options   []Option  `gorm:"foreignKey:GroupID" json:"options"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Option é uma escolha dentro de um grupo. PriceDelta é somado ao preço do produto e
+// está sempre na moeda dele.
+type Option struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	AgentID     uint        `gorm:"not null;index" json:"agent_id"`
+	GroupID     uint        `gorm:"not null;index" json:"group_id"`
+	Name        string      `gorm:"not null" json:"name"`
+	PriceDelta  money.Money `gorm:"embedded;embeddedPrefix:price_delta_" json:"price_delta"`
+	IsAvailable bool        `gorm:"not null;default:true" json:"is_available"`
+	Position    int         `gorm:"not null;default:0" json:"position"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// Variant é uma versão vendável do produto, como o tamanho G de uma pizza, com preço,
+// SKU e estoque próprios. Produto com variações ativas exige a escolha de uma delas.
+type Variant struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	AgentID   uint        `gorm:"not null;index" json:"agent_id"`
+	ProductID uint        `gorm:"not null;index" json:"product_id"`
+	Name      string      `gorm:"not null" json:"name"`
+	SKU       string      `gorm:"not null;default:''" json:"sku"`
+	Price     money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"` // Substitui o preço do produto.
+	Stock     int         `gorm:"not null;default:0" json:"stock"`             // Só muda por StockMovement.
+	IsActive  bool        `gorm:"not null;default:true" json:"is_active"`
+	Position  int         `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func (Variant) TableName() string {
+	return "product_variants"
+}
+
+func (OptionGroup) TableName() string {
+	return "product_option_groups"
+}
+
+func (Option) TableName() string {
+	return "product_options"
+}
+
+// minChoices e maxChoices são os limites efetivos do grupo; maxChoices 0 é sem limite.
+func (g OptionGroup) minChoices() int {
+	if g.Required && g.MinSelect < 1 {
+		return 1
+	}
+	return g.MinSelect
+}
+
+func (g OptionGroup) maxChoices() int {
+	if !g.Multiple {
+		return 1
+	}
+	return g.MaxSelect
+}
+
+// OptionDTO - dados de uma opção, na criação do grupo ou incluída depois.
+type OptionDTO struct {
+	Name        string
+	PriceDelta  money.Input // Zero quando a opção não muda o preço.
+	IsAvailable bool
+	Position    int
+}
+
+// CreateOptionGroupDTO - dados para criar um grupo de opções, já com as opções.
+type CreateOptionGroupDTO struct {
+	Name      string
+	Multiple  bool
+	MinSelect int
+	MaxSelect int
+	Required  bool
+	Position  int
+	Options   []OptionDTO
+}
+
+// UpdateOptionGroupDTO - campos não enviados ficam como estão.
+type UpdateOptionGroupDTO struct {
+	Name      patch.Field[string]
+	Multiple  patch.Field[bool]
+	MinSelect patch.Field[int]
+	MaxSelect patch.Field[int]
+	Required  patch.Field[bool]
+	Position  patch.Field[int]
+}
+
+// UpdateOptionDTO - campos não enviados ficam como estão.
+type UpdateOptionDTO struct {
+	Name        patch.Field[string]
+	PriceDelta  patch.Field[money.Input]
+	IsAvailable patch.Field[bool]
+	Position    patch.Field[int]
+}
+
+// CreateVariantDTO - dados para criar uma variação.
+type CreateVariantDTO struct {
+	Name     string
+	SKU      string
+	Price    money.Input
+	IsActive bool
+	Position int
+}
+
+// UpdateVariantDTO - campos não enviados ficam como estão; null limpa o SKU.
+type UpdateVariantDTO struct {
+	Name     patch.Field[string]
+	SKU      patch.Field[string]
+	Price    patch.Field[money.Input]
+	IsActive patch.Field[bool]
+	Position patch.Field[int]
+}
@@ -0,0 +1,409 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/options_graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+// Os campos de valor (ProductOption.price_delta, ProductVariant.price e os da cotação)
+// ficam em internal/graphql, que os formata na localidade do agente.
+
+// ProductOptionType é uma opção de um grupo, exportado para os campos de relacionamento.
+var ProductOptionType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "ProductOption",
+		Fields: graphql.Fields{
+			"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"group_id":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"is_available": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"position":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	},
+)
+
+// ProductOptionGroupType é um grupo de opções, exportado para Product.optionGroups.
+var ProductOptionGroupType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "ProductOptionGroup",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"product_id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"multiple":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Falso: o cliente escolhe no máximo uma opção."},
+			"min_select": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"max_select": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "0 é sem limite."},
+			"required":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"position":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"options":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(ProductOptionType)))},
+		},
+	},
+)
+
+// ProductVariantType é uma variação do produto, exportado para Product.variants.
+var ProductVariantType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "ProductVariant",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"product_id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"sku":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"stock":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Muda só pelo adjustStock."},
+			"is_active":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"position":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	},
+)
+
+// ProductPriceQuoteType é o resultado de productPrice.
+var ProductPriceQuoteType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "ProductPriceQuote",
+		Fields: graphql.Fields{
+			"product_id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"variant_id": &graphql.Field{Type: graphql.Int},
+			"quantity":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	},
+)
+
+var productOptionInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "ProductOptionInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"priceDelta":  &graphql.InputObjectFieldConfig{Type: money.MoneyInputType, Description: "Somado ao preço; sem ele, a opção não muda o preço."},
+			"isAvailable": &graphql.InputObjectFieldConfig{Type: graphql.Boolean, Description: "Padrão: true."},
+			"position":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	},
+)
+
+var createOptionGroupInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "CreateProductOptionGroupInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"multiple":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean, Description: "Padrão: false (escolha única)."},
+			"minSelect": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"maxSelect": &graphql.InputObjectFieldConfig{Type: graphql.Int, Description: "0 é sem limite."},
+			"required":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"position":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"options":   &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(productOptionInputType))},
+		},
+	},
+)
+
+var updateOptionGroupInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UpdateProductOptionGroupInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"multiple":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"minSelect": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"maxSelect": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"required":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"position":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	},
+)
+
+var updateOptionInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UpdateProductOptionInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"priceDelta":  &graphql.InputObjectFieldConfig{Type: money.MoneyInputType, Description: "null zera o acréscimo."},
+			"isAvailable": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"position":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	},
+)
+
+var createVariantInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "CreateProductVariantInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"sku":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"price":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(money.MoneyInputType), Description: "Na moeda do produto."},
+			"isActive": &graphql.InputObjectFieldConfig{Type: graphql.Boolean, Description: "Padrão: true."},
+			"position": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	},
+)
+
+var updateVariantInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UpdateProductVariantInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"sku":      &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "null limpa o SKU."},
+			"price":    &graphql.InputObjectFieldConfig{Type: money.MoneyInputType},
+			"isActive": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"position": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	},
+)
+
+// optionDTOFromArgs lê um ProductOptionInput.
+func optionDTOFromArgs(value interface{}) OptionDTO {
+	fields, _ := value.(map[string]interface{})
+	dto := OptionDTO{IsAvailable: true}
+	dto.Name, _ = fields["name"].(string)
+	dto.PriceDelta, _ = money.InputFromArgs(fields["priceDelta"])
+	if v, ok := fields["isAvailable"].(bool); ok {
+		dto.IsAvailable = v
+	}
+	dto.Position, _ = fields["position"].(int)
+	return dto
+}
+
+func optionQueryFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"productPrice": &graphql.Field{
+			Type:        ProductPriceQuoteType,
+			Description: "Calcula o preço de um produto com a variação e as opções escolhidas, conferindo a seleção contra os grupos do produto.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"variantId": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Obrigatório se o produto tiver variações ativas."},
+				"optionIds": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+				"quantity":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				productId, _ := p.Args["productId"].(int)
+				selection := Selection{Quantity: 1}
+				if v, ok := p.Args["variantId"].(int); ok {
+					variantID := uint(v)
+					selection.VariantID = &variantID
+				}
+				if values, ok := p.Args["optionIds"].([]interface{}); ok {
+					for _, v := range values {
+						if id, ok := v.(int); ok {
+							selection.OptionIDs = append(selection.OptionIDs, uint(id))
+						}
+					}
+				}
+				if v, ok := p.Args["quantity"].(int); ok {
+					selection.Quantity = v
+				}
+				return service.CalculatePrice(uint(agentId), uint(productId), selection)
+			},
+		},
+	}
+}
+
+// optionMutationFields editam os grupos de opções e as variações. Todas devolvem o
+// produto atualizado, como as mutations da galeria.
+func optionMutationFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"createProductOptionGroup": &graphql.Field{
+			Type:        ProductType,
+			Description: "Cria um grupo de opções no produto, já com as opções.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"input":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(createOptionGroupInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				productId, _ := p.Args["productId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				input, _ := p.Args["input"].(map[string]interface{})
+				dto := CreateOptionGroupDTO{}
+				dto.Name, _ = input["name"].(string)
+				dto.Multiple, _ = input["multiple"].(bool)
+				dto.MinSelect, _ = input["minSelect"].(int)
+				dto.MaxSelect, _ = input["maxSelect"].(int)
+				dto.Required, _ = input["required"].(bool)
+				dto.Position, _ = input["position"].(int)
+				if options, ok := input["options"].([]interface{}); ok {
+					for _, option := range options {
+						dto.Options = append(dto.Options, optionDTOFromArgs(option))
+					}
+				}
+				return service.CreateOptionGroup(uint(agentId), uint(productId), dto)
+			},
+		},
+		"updateProductOptionGroup": &graphql.Field{
+			Type:        ProductType,
+			Description: "Atualiza parcialmente um grupo de opções.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateOptionGroupInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				input := patch.InputFromArgs(p, "input")
+				dto := UpdateOptionGroupDTO{
+					Name:      patch.Get[string](input, "name"),
+					Multiple:  patch.Get[bool](input, "multiple"),
+					MinSelect: patch.Get[int](input, "minSelect"),
+					MaxSelect: patch.Get[int](input, "maxSelect"),
+					Required:  patch.Get[bool](input, "required"),
+					Position:  patch.Get[int](input, "position"),
+				}
+				return service.UpdateOptionGroup(uint(agentId), uint(id), dto)
+			},
+		},
+		"deleteProductOptionGroup": &graphql.Field{
+			Type:        ProductType,
+			Description: "Apaga um grupo de opções e as opções dele.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.DeleteOptionGroup(uint(agentId), uint(id))
+			},
+		},
+		"addProductOption": &graphql.Field{
+			Type:        ProductType,
+			Description: "Inclui uma opção num grupo.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"groupId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(productOptionInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				groupId, _ := p.Args["groupId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.AddOption(uint(agentId), uint(groupId), optionDTOFromArgs(p.Args["input"]))
+			},
+		},
+		"updateProductOption": &graphql.Field{
+			Type:        ProductType,
+			Description: "Atualiza parcialmente uma opção.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateOptionInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				input := patch.InputFromArgs(p, "input")
+				dto := UpdateOptionDTO{
+					Name:        patch.Get[string](input, "name"),
+					PriceDelta:  patch.Map(patch.Get[map[string]interface{}](input, "priceDelta"), priceInputOf),
+					IsAvailable: patch.Get[bool](input, "isAvailable"),
+					Position:    patch.Get[int](input, "position"),
+				}
+				return service.UpdateOption(uint(agentId), uint(id), dto)
+			},
+		},
+		"deleteProductOption": &graphql.Field{
+			Type:        ProductType,
+			Description: "Apaga uma opção.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.DeleteOption(uint(agentId), uint(id))
+			},
+		},
+		"createProductVariant": &graphql.Field{
+			Type:        ProductType,
+			Description: "Cria uma variação do produto, com preço próprio. O estoque começa em zero.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"input":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(createVariantInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				productId, _ := p.Args["productId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				input, _ := p.Args["input"].(map[string]interface{})
+				dto := CreateVariantDTO{IsActive: true}
+				dto.Name, _ = input["name"].(string)
+				dto.SKU, _ = input["sku"].(string)
+				dto.Price, _ = money.InputFromArgs(input["price"])
+				if v, ok := input["isActive"].(bool); ok {
+					dto.IsActive = v
+				}
+				dto.Position, _ = input["position"].(int)
+				return service.CreateVariant(uint(agentId), uint(productId), dto)
+			},
+		},
+		"updateProductVariant": &graphql.Field{
+			Type:        ProductType,
+			Description: "Atualiza parcialmente uma variação.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateVariantInputType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				input := patch.InputFromArgs(p, "input")
+				dto := UpdateVariantDTO{
+					Name:     patch.Get[string](input, "name"),
+					SKU:      patch.Get[string](input, "sku"),
+					Price:    patch.Map(patch.Get[map[string]interface{}](input, "price"), priceInputOf),
+					IsActive: patch.Get[bool](input, "isActive"),
+					Position: patch.Get[int](input, "position"),
+				}
+				return service.UpdateVariant(uint(agentId), uint(id), dto)
+			},
+		},
+		"deleteProductVariant": &graphql.Field{
+			Type:        ProductType,
+			Description: "Apaga uma variação.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.DeleteVariant(uint(agentId), uint(id))
+			},
+		},
+	}
+}
@@ -0,0 +1,32 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/price.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import "github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+
+// PriceMigrations convertem o preço antigo, um float em reais na coluna price, para
+// centavos em price_amount. O AutoMigrate cria as colunas novas com zero; aqui elas
+// são preenchidas e a coluna antiga, que é NOT NULL e barraria os inserts, é removida.
+// Bancos criados depois da mudança não têm a coluna price e não são alterados.
+var PriceMigrations = []database.SQLMigration{
+	{
+		ID: "0004_products_price_minor_units",
+		SQL: `
+DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'products' AND column_name = 'price'
+	) THEN
+		UPDATE products SET price_amount = round(price::numeric * 100)::bigint, price_currency = 'BRL';
+		ALTER TABLE products DROP COLUMN price;
+	END IF;
+END
+$$;`,
+	},
+}
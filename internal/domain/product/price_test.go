@@ -0,0 +1,47 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/price_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+)
+
+// A migração roda em SQL e não há banco nos testes; aqui se confere o que ela precisa
+// garantir. Os valores convertidos estão nos casos de money.TestFromFloat.
+func TestPriceMigrations(t *testing.T) {
+	if len(PriceMigrations) != 1 {
+		t.Fatalf("esperava uma migração, vieram %d", len(PriceMigrations))
+	}
+	migration := PriceMigrations[0]
+	decimals, _ := money.Decimals(money.DefaultCurrency)
+	factor := strconv.Itoa(int(math.Pow10(decimals)))
+
+	cases := []struct {
+		name string
+		want string
+	}{
+		// O ID é o que marca a migração como aplicada; mudá-lo a faria rodar de novo.
+		{"ID estável", "0004_products_price_minor_units"},
+		{"só altera bancos com a coluna antiga", "column_name = 'price'"},
+		{"arredonda em numeric, não em float", "round(price::numeric * " + factor + ")::bigint"},
+		{"grava a moeda padrão", "price_currency = '" + money.DefaultCurrency + "'"},
+		{"remove a coluna antiga", "DROP COLUMN price"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(migration.ID+"\n"+migration.SQL, tc.want) {
+				t.Fatalf("esperava %q na migração", tc.want)
+			}
+		})
+	}
+}
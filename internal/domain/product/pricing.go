@@ -0,0 +1,119 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/pricing.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+)
+
+// Selection é o que o cliente escolheu de um produto: a variação (obrigatória se o
+// produto tiver variações ativas), as opções e a quantidade.
+type Selection struct {
+	VariantID *uint
+	OptionIDs []uint
+	Quantity  int
+}
+
+// PriceQuote é o preço de uma seleção. Unit = Base + Options; Total = Unit * Quantity.
+type PriceQuote struct {
+	AgentID   uint        `json:"agent_id"`
+	ProductID uint        `json:"product_id"`
+	VariantID *uint       `json:"variant_id"`
+	Base      money.Money `json:"base"` // Preço da variação ou, sem ela, do produto.
+	Options   money.Money `json:"options"`
+	Unit      money.Money `json:"unit"`
+	Quantity  int         `json:"quantity"`
+	Total     money.Money `json:"total"`
+}
+
+// CalculatePrice valida a seleção contra as variações, os grupos de opções e, se o
+// produto controla estoque, o estoque disponível, e calcula o total. Todos os problemas
+// da seleção voltam juntos, num erro VALIDATION.
+func CalculatePrice(product Product, variants []Variant, groups []OptionGroup, selection Selection) (PriceQuote, error) {
+	var failures []apperror.FieldError
+	fail := func(field, key string, args ...interface{}) {
+		failures = append(failures, apperror.FieldError{Field: field, Key: key, Args: args})
+	}
+
+	if selection.Quantity < 1 {
+		fail("quantity", apperror.MsgPositiveAmount)
+	}
+
+	base := product.Price
+	stock := &product.Stock // Estoque de onde sai a seleção; nil se a variação for inválida.
+	hasVariants := false
+	for _, variant := range variants {
+		hasVariants = hasVariants || variant.IsActive
+	}
+	if selection.VariantID == nil {
+		if hasVariants {
+			fail("variantId", apperror.MsgVariantRequired)
+			stock = nil
+		}
+	} else {
+		stock = nil
+		for _, variant := range variants {
+			if variant.ID == *selection.VariantID && variant.IsActive {
+				base, stock = variant.Price, &variant.Stock
+			}
+		}
+		if stock == nil {
+			fail("variantId", apperror.MsgInvalidChoice)
+		}
+	}
+	if product.TrackStock && stock != nil && selection.Quantity > *stock {
+		fail("quantity", apperror.MsgInsufficientStock, *stock)
+	}
+
+	options := map[uint]Option{}
+	for _, group := range groups {
+		for _, option := range group.Options {
+			if option.IsAvailable {
+				options[option.ID] = option
+			}
+		}
+	}
+	chosen := map[uint]int{} // Opções escolhidas por grupo.
+	seen := map[uint]bool{}
+	extras := money.Money{Currency: base.Currency}
+	for _, id := range selection.OptionIDs {
+		option, ok := options[id]
+		if !ok || seen[id] {
+			fail("optionIds", apperror.MsgInvalidChoice)
+			continue
+		}
+		seen[id] = true
+		chosen[option.GroupID]++
+		extras.Amount += option.PriceDelta.Amount
+	}
+	for _, group := range groups {
+		count := chosen[group.ID]
+		if min := group.minChoices(); count < min {
+			fail("optionIds", apperror.MsgSelectionMin, min, group.Name)
+		}
+		if max := group.maxChoices(); max > 0 && count > max {
+			fail("optionIds", apperror.MsgSelectionMax, max, group.Name)
+		}
+	}
+
+	if len(failures) > 0 {
+		return PriceQuote{}, apperror.Validation(failures...)
+	}
+	unit := money.Money{Amount: base.Amount + extras.Amount, Currency: base.Currency}
+	return PriceQuote{
+		AgentID:   product.AgentID,
+		ProductID: product.ID,
+		VariantID: selection.VariantID,
+		Base:      base,
+		Options:   extras,
+		Unit:      unit,
+		Quantity:  selection.Quantity,
+		Total:     money.Money{Amount: unit.Amount * int64(selection.Quantity), Currency: base.Currency},
+	}, nil
+}
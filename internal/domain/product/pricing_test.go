@@ -0,0 +1,189 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/pricing_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+)
+
+func brl(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "BRL"}
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+// pizzaFixture é uma pizza com tamanhos (variações), uma massa obrigatória de escolha
+// única e até dois adicionais.
+func pizzaFixture() (Product, []Variant, []OptionGroup) {
+	product := Product{ID: 1, AgentID: 7, Name: "Pizza", Price: brl(4000)}
+	product.Stock = 100
+	variants := []Variant{
+		{ID: 10, ProductID: 1, Name: "Média", Price: brl(4500), Stock: 5, IsActive: true},
+		{ID: 11, ProductID: 1, Name: "Grande", Price: brl(5500), Stock: 1, IsActive: true},
+		{ID: 12, ProductID: 1, Name: "Broto", Price: brl(3000), Stock: 9, IsActive: false},
+	}
+	groups := []OptionGroup{
+		{ID: 20, ProductID: 1, Name: "Massa", Required: true, Options: []Option{
+			{ID: 200, GroupID: 20, Name: "Tradicional", PriceDelta: brl(0), IsAvailable: true},
+			{ID: 201, GroupID: 20, Name: "Integral", PriceDelta: brl(300), IsAvailable: true},
+		}},
+		{ID: 21, ProductID: 1, Name: "Adicionais", Multiple: true, MaxSelect: 2, Options: []Option{
+			{ID: 210, GroupID: 21, Name: "Bacon", PriceDelta: brl(500), IsAvailable: true},
+			{ID: 211, GroupID: 21, Name: "Catupiry", PriceDelta: brl(450), IsAvailable: true},
+			{ID: 212, GroupID: 21, Name: "Cheddar", PriceDelta: brl(400), IsAvailable: true},
+			{ID: 213, GroupID: 21, Name: "Palmito", PriceDelta: brl(600), IsAvailable: false},
+		}},
+	}
+	return product, variants, groups
+}
+
+func TestCalculatePrice(t *testing.T) {
+	const otherProductOption = 300 // Opção de um grupo de outro produto.
+	cases := []struct {
+		name       string
+		trackStock bool
+		selection  Selection
+		want       []string
+	}{
+		{
+			name:      "seleção válida",
+			selection: Selection{VariantID: uintPtr(10), OptionIDs: []uint{200}, Quantity: 1},
+		},
+		{
+			name:      "grupo obrigatório sem escolha",
+			selection: Selection{VariantID: uintPtr(10), Quantity: 1},
+			want:      []string{"optionIds:" + apperror.MsgSelectionMin},
+		},
+		{
+			name:      "duas escolhas num grupo de escolha única",
+			selection: Selection{VariantID: uintPtr(10), OptionIDs: []uint{200, 201}, Quantity: 1},
+			want:      []string{"optionIds:" + apperror.MsgSelectionMax},
+		},
+		{
+			name:      "acima do máximo do grupo",
+			selection: Selection{VariantID: uintPtr(10), OptionIDs: []uint{200, 210, 211, 212}, Quantity: 1},
+			want:      []string{"optionIds:" + apperror.MsgSelectionMax},
+		},
+		{
+			name:      "opção de outro produto",
+			selection: Selection{VariantID: uintPtr(10), OptionIDs: []uint{200, otherProductOption}, Quantity: 1},
+			want:      []string{"optionIds:" + apperror.MsgInvalidChoice},
+		},
+		{
+			name:      "opção indisponível",
+			selection: Selection{VariantID: uintPtr(10), OptionIDs: []uint{200, 213}, Quantity: 1},
+			want:      []string{"optionIds:" + apperror.MsgInvalidChoice},
+		},
+		{
+			name:      "opção repetida",
+			selection: Selection{VariantID: uintPtr(10), OptionIDs: []uint{200, 210, 210}, Quantity: 1},
+			want:      []string{"optionIds:" + apperror.MsgInvalidChoice},
+		},
+		{
+			name:      "variação inativa",
+			selection: Selection{VariantID: uintPtr(12), OptionIDs: []uint{200}, Quantity: 1},
+			want:      []string{"variantId:" + apperror.MsgInvalidChoice},
+		},
+		{
+			name:      "variação de outro produto",
+			selection: Selection{VariantID: uintPtr(99), OptionIDs: []uint{200}, Quantity: 1},
+			want:      []string{"variantId:" + apperror.MsgInvalidChoice},
+		},
+		{
+			name:      "sem variação num produto com variações",
+			selection: Selection{OptionIDs: []uint{200}, Quantity: 1},
+			want:      []string{"variantId:" + apperror.MsgVariantRequired},
+		},
+		{
+			name:      "quantidade zero",
+			selection: Selection{VariantID: uintPtr(10), OptionIDs: []uint{200}, Quantity: 0},
+			want:      []string{"quantity:" + apperror.MsgPositiveAmount},
+		},
+		{
+			name:      "quantidade negativa",
+			selection: Selection{VariantID: uintPtr(10), OptionIDs: []uint{200}, Quantity: -2},
+			want:      []string{"quantity:" + apperror.MsgPositiveAmount},
+		},
+		{
+			name:       "acima do estoque da variação",
+			trackStock: true,
+			selection:  Selection{VariantID: uintPtr(11), OptionIDs: []uint{200}, Quantity: 2},
+			want:       []string{"quantity:" + apperror.MsgInsufficientStock},
+		},
+		{
+			name:      "estoque não conferido sem controle",
+			selection: Selection{VariantID: uintPtr(11), OptionIDs: []uint{200}, Quantity: 2},
+		},
+		{
+			name:      "vários problemas juntos",
+			selection: Selection{VariantID: uintPtr(12), Quantity: 0},
+			want: []string{
+				"optionIds:" + apperror.MsgSelectionMin,
+				"quantity:" + apperror.MsgPositiveAmount,
+				"variantId:" + apperror.MsgInvalidChoice,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			product, variants, groups := pizzaFixture()
+			product.TrackStock = tc.trackStock
+			_, err := CalculatePrice(product, variants, groups, tc.selection)
+			if got := invalidFields(t, err); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("esperava %v, veio %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCalculatePriceTotal(t *testing.T) {
+	product, variants, groups := pizzaFixture()
+	// Grande (55,00) + integral (3,00) + bacon (5,00) + catupiry (4,50), duas unidades.
+	quote, err := CalculatePrice(product, variants, groups, Selection{
+		VariantID: uintPtr(11),
+		OptionIDs: []uint{201, 210, 211},
+		Quantity:  2,
+	})
+	if err != nil {
+		t.Fatalf("esperava passar, veio %v", err)
+	}
+	want := PriceQuote{
+		AgentID:   7,
+		ProductID: 1,
+		VariantID: quote.VariantID,
+		Base:      brl(5500),
+		Options:   brl(1250),
+		Unit:      brl(6750),
+		Quantity:  2,
+		Total:     brl(13500),
+	}
+	if quote.VariantID == nil || *quote.VariantID != 11 {
+		t.Fatalf("esperava a variação 11, veio %v", quote.VariantID)
+	}
+	if quote != want {
+		t.Fatalf("esperava %+v, veio %+v", want, quote)
+	}
+}
+
+func TestCalculatePriceWithoutVariants(t *testing.T) {
+	product, _, groups := pizzaFixture()
+	// Sem variações, o preço base é o do produto.
+	quote, err := CalculatePrice(product, nil, groups, Selection{OptionIDs: []uint{200, 212}, Quantity: 3})
+	if err != nil {
+		t.Fatalf("esperava passar, veio %v", err)
+	}
+	if quote.Base != brl(4000) || quote.Unit != brl(4400) || quote.Total != brl(13200) {
+		t.Fatalf("esperava base 4000, unidade 4400 e total 13200, veio %+v", quote)
+	}
+}
@@ -7,16 +7,60 @@
 */
 package product
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type Repository interface {
-	FindAll(agentID uint, page, perPage int) ([]Product, int64, error)
+	FindAll(agentID uint, filter ProductFilter, sort pagination.Sort, page pagination.Page) ([]Product, error)
+	Count(agentID uint, filter ProductFilter) (int64, error)
 	FindByID(agentID, id uint) (Product, error)
-	Search(agentID uint, name string) ([]Product, error)
+	FindBySKU(agentID uint, sku string) (Product, error)
+	FindByBarcode(agentID uint, barcode string) (Product, error)
+	Search(agentID uint, text string, page pagination.Page) ([]SearchResult, error)
+	CountSearch(agentID uint, text string) (int64, error)
 	SearchByCategory(agentID, categoryID uint) ([]Product, error) // NOVO
-	Create(product Product) (Product, error)
-	Update(product Product) (Product, error)
+	Create(product Product, cover *ProductImage, quota plan.QuotaGuard) (Product, error)
+	Update(agentID, id, version uint, changes map[string]interface{}) (Product, error)
 	Delete(agentID, id uint) error
+	FindTrashed(agentID uint) ([]Product, error)
+	Restore(agentID, id uint, quota plan.QuotaGuard) error
+	Purge(before time.Time) ([]uint, error)
+	PurgeAgents(agentIDs []uint) ([]uint, error)
+	FindByCategoryIDs(agentID uint, categoryIDs []uint, first int, afterID uint) ([]Product, error)
+	CountByCategoryIDs(agentID uint, categoryIDs []uint) (map[uint]int64, error)
+	FindWithImages(afterID uint, limit int) ([]Product, error)
+	SetImages(id uint, imageURL string, images ImageSet, assetID *uint) error
+	FindImages(productIDs []uint) ([]ProductImage, error)
+	AddImage(agentID, productID uint, image ProductImage) (ProductImage, error)
+	ReplaceCover(agentID, productID uint, image ProductImage) ([]ProductImage, error)
+	RemoveImage(agentID, productID, imageID uint) (ProductImage, error)
+	ReorderImages(agentID, productID uint, imageIDs []uint) error
+	SetCoverImage(agentID, productID, imageID uint) error
+	FindOptionGroups(productIDs []uint) ([]OptionGroup, error)
+	FindOptionGroup(agentID, id uint) (OptionGroup, error)
+	CreateOptionGroup(group OptionGroup) (OptionGroup, error)
+	UpdateOptionGroup(agentID, id uint, changes map[string]interface{}) (OptionGroup, error)
+	DeleteOptionGroup(agentID, id uint) (OptionGroup, error)
+	FindOption(agentID, id uint) (Option, error)
+	AddOption(option Option) (Option, error)
+	UpdateOption(agentID, id uint, changes map[string]interface{}) (Option, error)
+	DeleteOption(agentID, id uint) (Option, error)
+	FindVariants(productIDs []uint) ([]Variant, error)
+	FindVariant(agentID, id uint) (Variant, error)
+	CreateVariant(variant Variant) (Variant, error)
+	UpdateVariant(agentID, id uint, changes map[string]interface{}) (Variant, error)
+	DeleteVariant(agentID, id uint) (Variant, error)
+	AdjustStock(agentID uint, dto AdjustStockDTO) (StockMovement, error)
+	FindStockMovements(agentID uint, filter StockMovementFilter, page pagination.Page) ([]StockMovement, error)
+	CountStockMovements(agentID uint, filter StockMovementFilter) (int64, error)
 }
 
 type repository struct {
@@ -27,15 +71,40 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) FindAll(agentID uint, page, perPage int) ([]Product, int64, error) {
+func (r *repository) FindAll(agentID uint, filter ProductFilter, sort pagination.Sort, page pagination.Page) ([]Product, error) {
 	var products []Product
+	sort = sort.Allowed("name", "price_amount", "created_at", "position")
+	err := r.db.Where("agent_id = ?", agentID).Scopes(filterScope(filter), page.Scope(sort)).Find(&products).Error
+	return products, err
+}
+
+func (r *repository) Count(agentID uint, filter ProductFilter) (int64, error) {
 	var total int64
-	if err := r.db.Model(&Product{}).Where("agent_id = ?", agentID).Count(&total).Error; err != nil {
-		return nil, 0, err
+	err := r.db.Model(&Product{}).Where("agent_id = ?", agentID).Scopes(filterScope(filter)).Count(&total).Error
+	return total, err
+}
+
+// filterScope traduz o filtro em condições parametrizadas.
+func filterScope(filter ProductFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(filter.CategoryIDs) > 0 {
+			db = db.Where("category_id IN ?", filter.CategoryIDs)
+		}
+		if filter.IsActive != nil {
+			db = db.Where("is_active = ?", *filter.IsActive)
+		}
+		if filter.PriceMin != nil {
+			db = db.Where("price_currency = ? AND price_amount >= ?", filter.PriceMin.Currency, filter.PriceMin.Amount)
+		}
+		if filter.PriceMax != nil {
+			db = db.Where("price_currency = ? AND price_amount <= ?", filter.PriceMax.Currency, filter.PriceMax.Amount)
+		}
+		if filter.Text != "" {
+			pattern := pagination.ContainsPattern(filter.Text)
+			db = db.Where("(name ILIKE ? OR description ILIKE ?)", pattern, pattern)
+		}
+		return db.Scopes(filter.CreatedAt.Scope("created_at"))
 	}
-	offset := (page - 1) * perPage
-	err := r.db.Where("agent_id = ?", agentID).Limit(perPage).Offset(offset).Order("id asc").Find(&products).Error
-	return products, total, err
 }
 
 func (r *repository) FindByID(agentID, id uint) (Product, error) {
@@ -44,10 +113,68 @@ func (r *repository) FindByID(agentID, id uint) (Product, error) {
 	return product, err
 }
 
-func (r *repository) Search(agentID uint, name string) ([]Product, error) {
-	var products []Product
-	err := r.db.Where("agent_id = ? AND name ILIKE ?", agentID, "%"+name+"%").Find(&products).Error
-	return products, err
+func (r *repository) FindBySKU(agentID uint, sku string) (Product, error) {
+	var product Product
+	err := r.db.Where("agent_id = ? AND sku = ?", agentID, sku).First(&product).Error
+	return product, err
+}
+
+// FindByBarcode retorna o produto com o código de barras. O código não é único; se mais
+// de um produto o tiver, vale o mais antigo.
+func (r *repository) FindByBarcode(agentID uint, barcode string) (Product, error) {
+	var product Product
+	err := r.db.Where("agent_id = ? AND barcode = ?", agentID, barcode).Order("id").First(&product).Error
+	return product, err
+}
+
+// Ordenações fixas da busca (mais relevantes primeiro) e do histórico de estoque (mais
+// recentes primeiro).
+var (
+	searchSort   = pagination.Sort{Column: "rank", Desc: true}
+	movementSort = pagination.Sort{Column: "id", Desc: true}
+)
+
+// searchMatch encontra o texto pelo search_vector (nome, tags e descrição, sem acentos e
+// reduzidos ao radical) ou, para tolerar erros de digitação, por trigramas do nome.
+const searchMatch = `(search_vector @@ websearch_to_tsquery('portuguese_unaccent', @text)
+	OR immutable_unaccent(lower(@text)) <% immutable_unaccent(lower(name)))`
+
+// searchColumns escapa o HTML do nome e da descrição antes do ts_headline: o cliente
+// exibe os destaques como HTML, e só as marcas <mark> podem chegar sem escape.
+const searchColumns = `products.*,
+	(ts_rank(search_vector, websearch_to_tsquery('portuguese_unaccent', @text))
+		+ word_similarity(immutable_unaccent(lower(@text)), immutable_unaccent(lower(name))) * 0.5)::float8 AS rank,
+	ts_headline('portuguese_unaccent',
+		replace(replace(replace(name, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		websearch_to_tsquery('portuguese_unaccent', @text),
+		'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS name_highlight,
+	ts_headline('portuguese_unaccent',
+		replace(replace(replace(coalesce(description, ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		websearch_to_tsquery('portuguese_unaccent', @text),
+		'StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=10, MaxFragments=2') AS snippet`
+
+// Search faz a busca textual, com os resultados mais relevantes primeiro. As colunas são
+// calculadas numa subconsulta para que a paginação por keyset possa usar rank.
+func (r *repository) Search(agentID uint, text string, page pagination.Page) ([]SearchResult, error) {
+	var results []SearchResult
+	args := map[string]interface{}{"text": text}
+	matches := r.db.Model(&Product{}).
+		Select(searchColumns, args).
+		Where("agent_id = ?", agentID).
+		Where(searchMatch, args)
+	err := r.db.Table("(?) AS results", matches).
+		Scopes(page.Scope(searchSort)).
+		Find(&results).Error
+	return results, err
+}
+
+func (r *repository) CountSearch(agentID uint, text string) (int64, error) {
+	var total int64
+	err := r.db.Model(&Product{}).
+		Where("agent_id = ?", agentID).
+		Where(searchMatch, map[string]interface{}{"text": text}).
+		Count(&total).Error
+	return total, err
 }
 
 func (r *repository) SearchByCategory(agentID, categoryID uint) ([]Product, error) {
@@ -56,16 +183,727 @@ func (r *repository) SearchByCategory(agentID, categoryID uint) ([]Product, erro
 	return products, err
 }
 
-func (r *repository) Create(product Product) (Product, error) {
-	err := r.db.Create(&product).Error
-	return product, err
+// Create grava o produto e, se cover vier, a capa da galeria, na mesma transação da cota: se
+// a capa falhar, o produto não fica criado.
+func (r *repository) Create(product Product, cover *ProductImage, quota plan.QuotaGuard) (Product, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := quota(tx); err != nil {
+			return err
+		}
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		if cover == nil {
+			return nil
+		}
+		image := *cover
+		image.ProductID, image.IsCover = product.ID, true
+		if err := tx.Create(&image).Error; err != nil {
+			return err
+		}
+		return syncCover(tx, product.ID)
+	})
+	if err != nil || cover == nil {
+		return product, skuTaken(err)
+	}
+	return r.FindByID(product.AgentID, product.ID)
 }
 
-func (r *repository) Update(product Product) (Product, error) {
-	err := r.db.Save(&product).Error
-	return product, err
+// Update altera só as colunas em changes, e somente se a versão no banco ainda for version,
+// incrementando-a. Se outra atualização chegou antes, retorna apperror.ErrVersionMismatch.
+func (r *repository) Update(agentID, id, version uint, changes map[string]interface{}) (Product, error) {
+	changes["version"] = gorm.Expr("version + 1")
+	result := r.db.Model(&Product{}).Where("agent_id = ? AND id = ? AND version = ?", agentID, id, version).Updates(changes)
+	if result.Error != nil {
+		return Product{}, skuTaken(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return Product{}, apperror.ErrVersionMismatch
+	}
+	return r.FindByID(agentID, id)
 }
 
 func (r *repository) Delete(agentID, id uint) error {
 	return r.db.Where("agent_id = ?", agentID).Delete(&Product{}, id).Error
 }
+
+// FindTrashed lista os produtos excluídos (soft delete) de um agente.
+func (r *repository) FindTrashed(agentID uint) ([]Product, error) {
+	var products []Product
+	err := r.db.Unscoped().Where("agent_id = ? AND deleted_at IS NOT NULL", agentID).Order("deleted_at desc").Find(&products).Error
+	return products, err
+}
+
+// Restore tira um produto da lixeira.
+func (r *repository) Restore(agentID, id uint, quota plan.QuotaGuard) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := quota(tx); err != nil {
+			return err
+		}
+		result := tx.Unscoped().Model(&Product{}).
+			Where("agent_id = ? AND id = ? AND deleted_at IS NOT NULL", agentID, id).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return skuTaken(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Purge remove definitivamente os produtos excluídos antes de before, com as galerias, as
+// opções, as variações e o histórico de estoque, e retorna os IDs removidos.
+func (r *repository) Purge(before time.Time) ([]uint, error) {
+	return r.purge(func(db *gorm.DB) *gorm.DB {
+		return db.Where("deleted_at IS NOT NULL AND deleted_at < ?", before)
+	})
+}
+
+// PurgeAgents remove definitivamente todos os produtos dos agentes, inclusive os ativos, com
+// os mesmos registros dependentes do Purge.
+func (r *repository) PurgeAgents(agentIDs []uint) ([]uint, error) {
+	return r.purge(func(db *gorm.DB) *gorm.DB {
+		return db.Where("agent_id IN ?", agentIDs)
+	})
+}
+
+func (r *repository) purge(scope func(db *gorm.DB) *gorm.DB) ([]uint, error) {
+	var ids []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var purged []Product
+		err := tx.Unscoped().Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+			Scopes(scope).Delete(&purged).Error
+		if err != nil || len(purged) == 0 {
+			return err
+		}
+		ids = make([]uint, len(purged))
+		for i, item := range purged {
+			ids[i] = item.ID
+		}
+		if err := tx.Where("product_id IN ?", ids).Delete(&ProductImage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id IN ?", ids).Delete(&StockMovement{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id IN ?", ids).Delete(&Variant{}).Error; err != nil {
+			return err
+		}
+		groups := tx.Model(&OptionGroup{}).Select("id").Where("product_id IN ?", ids)
+		if err := tx.Where("group_id IN (?)", groups).Delete(&Option{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("product_id IN ?", ids).Delete(&OptionGroup{}).Error
+	})
+	return ids, err
+}
+
+// FindByCategoryIDs retorna, numa única consulta, até first produtos do agente em cada
+// categoria com ID maior que afterID, ordenados por ID.
+func (r *repository) FindByCategoryIDs(agentID uint, categoryIDs []uint, first int, afterID uint) ([]Product, error) {
+	var products []Product
+	ranked := r.db.Model(&Product{}).
+		Select("products.*, ROW_NUMBER() OVER (PARTITION BY category_id ORDER BY id) AS position_in_category").
+		Where("agent_id = ? AND category_id IN ? AND id > ?", agentID, categoryIDs, afterID)
+	err := r.db.Table("(?) AS ranked", ranked).
+		Where("position_in_category <= ?", first).
+		Order("category_id asc, id asc").
+		Find(&products).Error
+	return products, err
+}
+
+// CountByCategoryIDs conta, numa única consulta, os produtos do agente em cada categoria.
+func (r *repository) CountByCategoryIDs(agentID uint, categoryIDs []uint) (map[uint]int64, error) {
+	var rows []struct {
+		CategoryID uint
+		Total      int64
+	}
+	err := r.db.Model(&Product{}).
+		Select("category_id, COUNT(*) AS total").
+		Where("agent_id = ? AND category_id IN ?", agentID, categoryIDs).
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Total
+	}
+	return counts, nil
+}
+
+// FindWithImages lista, de todos os agentes e inclusive da lixeira, até limit produtos com
+// imagem e ID maior que afterID, ordenados por ID.
+func (r *repository) FindWithImages(afterID uint, limit int) ([]Product, error) {
+	var products []Product
+	err := r.db.Unscoped().
+		Where("id > ? AND (image_url <> '' OR images->>'original' <> '')", afterID).
+		Order("id asc").Limit(limit).
+		Find(&products).Error
+	return products, err
+}
+
+// SetImages troca as URLs das imagens e o asset sem mexer na versão nem em updated_at: é
+// usada só para mover arquivos de lugar, sem mudar a imagem que o produto mostra. Com
+// assetID, o asset vira a capa da galeria, se o produto ainda não tiver uma.
+func (r *repository) SetImages(id uint, imageURL string, images ImageSet, assetID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&Product{}).Where("id = ?", id).
+			UpdateColumns(map[string]interface{}{"image_url": imageURL, "images": images, "image_asset_id": assetID}).Error
+		if err != nil || assetID == nil {
+			return err
+		}
+		var covers int64
+		if err := tx.Model(&ProductImage{}).Where("product_id = ? AND is_cover", id).Count(&covers).Error; err != nil || covers > 0 {
+			return err
+		}
+		urls := images
+		if urls.Original == "" {
+			urls = ImageSet{Original: imageURL, Thumb: imageURL, Card: imageURL, Full: imageURL}
+		}
+		cover := ProductImage{ProductID: id, AssetID: *assetID, URLs: urls, IsCover: true}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&cover).Error
+	})
+}
+
+// FindImages lista as galerias dos produtos, na ordem de exibição.
+func (r *repository) FindImages(productIDs []uint) ([]ProductImage, error) {
+	var images []ProductImage
+	err := r.db.Where("product_id IN ?", productIDs).Order("product_id, position, id").Find(&images).Error
+	return images, err
+}
+
+// AddImage inclui a imagem no fim da galeria; com IsCover, ela vira a capa. A primeira
+// imagem da galeria é sempre a capa.
+func (r *repository) AddImage(agentID, productID uint, image ProductImage) (ProductImage, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, agentID, productID); err != nil {
+			return err
+		}
+		var stats struct {
+			Total   int64
+			Same    int64
+			NextPos int
+		}
+		err := tx.Model(&ProductImage{}).
+			Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE asset_id = ?) AS same, COALESCE(MAX(position) + 1, 0) AS next_pos", image.AssetID).
+			Where("product_id = ?", productID).
+			Scan(&stats).Error
+		if err != nil {
+			return err
+		}
+		if stats.Same > 0 {
+			return apperror.Invalid("assetId", apperror.MsgImageInGallery)
+		}
+		if stats.Total >= maxGalleryImages {
+			return apperror.Invalid("assetId", apperror.MsgMaxItems, maxGalleryImages)
+		}
+		image.ID, image.ProductID, image.Position = 0, productID, stats.NextPos
+		if image.IsCover {
+			if err := tx.Model(&ProductImage{}).Where("product_id = ?", productID).Update("is_cover", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&image).Error; err != nil {
+			return err
+		}
+		if err := syncCover(tx, productID); err != nil {
+			return err
+		}
+		return tx.First(&image, image.ID).Error
+	})
+	return image, err
+}
+
+// ReplaceCover troca a capa pela imagem, na mesma posição, e retorna as imagens que saíram
+// da galeria. Se o asset já está na galeria, ele vira a capa no lugar da anterior. É o
+// comportamento dos campos de imagem única (imageUrl, imageAssetId e uploadProductImage).
+func (r *repository) ReplaceCover(agentID, productID uint, image ProductImage) ([]ProductImage, error) {
+	var removed []ProductImage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, agentID, productID); err != nil {
+			return err
+		}
+		var gallery []ProductImage
+		if err := tx.Where("product_id = ?", productID).Order("is_cover DESC, position, id").Find(&gallery).Error; err != nil {
+			return err
+		}
+		image.ID, image.ProductID, image.IsCover = 0, productID, true
+		for _, current := range gallery {
+			if current.AssetID == image.AssetID {
+				image = current
+			}
+		}
+		if len(gallery) > 0 && gallery[0].ID != image.ID {
+			removed = append(removed, gallery[0])
+			if err := tx.Delete(&gallery[0]).Error; err != nil {
+				return err
+			}
+			if image.ID == 0 {
+				image.Position = gallery[0].Position
+			}
+		}
+		if err := tx.Model(&ProductImage{}).Where("product_id = ?", productID).Update("is_cover", false).Error; err != nil {
+			return err
+		}
+		image.IsCover = true
+		if err := tx.Save(&image).Error; err != nil {
+			return err
+		}
+		return syncCover(tx, productID)
+	})
+	return removed, err
+}
+
+// RemoveImage tira a imagem da galeria. Se era a capa, a próxima na ordem assume.
+func (r *repository) RemoveImage(agentID, productID, imageID uint) (ProductImage, error) {
+	var image ProductImage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, agentID, productID); err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", productID).First(&image, imageID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		return syncCover(tx, productID)
+	})
+	return image, err
+}
+
+// ReorderImages grava a ordem da galeria. imageIDs precisa ter todas as imagens do
+// produto, cada uma uma vez.
+func (r *repository) ReorderImages(agentID, productID uint, imageIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, agentID, productID); err != nil {
+			return err
+		}
+		var current []uint
+		if err := tx.Model(&ProductImage{}).Where("product_id = ?", productID).Pluck("id", &current).Error; err != nil {
+			return err
+		}
+		if !sameIDs(current, imageIDs) {
+			return apperror.Invalid("imageIds", apperror.MsgGalleryOrder)
+		}
+		for position, id := range imageIDs {
+			if err := tx.Model(&ProductImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return syncCover(tx, productID)
+	})
+}
+
+// SetCoverImage torna a imagem a capa da galeria.
+func (r *repository) SetCoverImage(agentID, productID, imageID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, agentID, productID); err != nil {
+			return err
+		}
+		var image ProductImage
+		if err := tx.Where("product_id = ?", productID).First(&image, imageID).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&ProductImage{}).Where("product_id = ?", productID).
+			Update("is_cover", gorm.Expr("id = ?", imageID)).Error
+		if err != nil {
+			return err
+		}
+		return syncCover(tx, productID)
+	})
+}
+
+// skuTaken traduz a violação do índice único de SKU, o único dos produtos além da chave
+// primária, para um erro no campo sku.
+func skuTaken(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return apperror.Invalid("sku", apperror.MsgSKUTaken)
+	}
+	return err
+}
+
+// lockProduct trava o produto até o fim da transação, serializando as mudanças na galeria,
+// nas opções e nas variações.
+func lockProduct(tx *gorm.DB, agentID, id uint) error {
+	var product Product
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("agent_id = ?", agentID).First(&product, id).Error
+}
+
+// syncCover garante uma única capa na galeria e copia as URLs dela para o produto, que
+// muda de versão. Sem imagens, a imagem do produto é limpa.
+func syncCover(tx *gorm.DB, productID uint) error {
+	changes := map[string]interface{}{"image_url": "", "images": ImageSet{}, "image_asset_id": nil, "version": gorm.Expr("version + 1")}
+	var cover ProductImage
+	err := tx.Where("product_id = ?", productID).Order("is_cover DESC, position, id").First(&cover).Error
+	switch {
+	case err == nil:
+		err = tx.Model(&ProductImage{}).Where("product_id = ?", productID).Update("is_cover", gorm.Expr("id = ?", cover.ID)).Error
+		if err != nil {
+			return err
+		}
+		changes["image_url"], changes["images"], changes["image_asset_id"] = cover.URLs.Full, cover.URLs, cover.AssetID
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	return tx.Model(&Product{}).Where("id = ?", productID).Updates(changes).Error
+}
+
+// touchProduct muda a versão do produto, para os clientes verem que as opções ou as
+// variações mudaram.
+func touchProduct(tx *gorm.DB, productID uint) error {
+	return tx.Model(&Product{}).Where("id = ?", productID).Update("version", gorm.Expr("version + 1")).Error
+}
+
+// sameIDs informa se as listas têm os mesmos IDs, sem repetição.
+func sameIDs(current, requested []uint) bool {
+	if len(current) != len(requested) {
+		return false
+	}
+	seen := make(map[uint]bool, len(current))
+	for _, id := range current {
+		seen[id] = true
+	}
+	for _, id := range requested {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
+
+// FindOptionGroups busca, numa única consulta por tabela, os grupos de vários produtos
+// com as opções, na ordem de exibição.
+func (r *repository) FindOptionGroups(productIDs []uint) ([]OptionGroup, error) {
+	var groups []OptionGroup
+	err := r.db.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Where("product_id IN ?", productIDs).Order("product_id, position, id").Find(&groups).Error
+	return groups, err
+}
+
+func (r *repository) FindOptionGroup(agentID, id uint) (OptionGroup, error) {
+	var group OptionGroup
+	err := r.db.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Where("agent_id = ?", agentID).First(&group, id).Error
+	return group, err
+}
+
+// CreateOptionGroup grava o grupo com as opções. Select("*") grava também os booleanos
+// falsos, que o default das colunas ignoraria.
+func (r *repository) CreateOptionGroup(group OptionGroup) (OptionGroup, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, group.AgentID, group.ProductID); err != nil {
+			return err
+		}
+		var total int64
+		if err := tx.Model(&OptionGroup{}).Where("product_id = ?", group.ProductID).Count(&total).Error; err != nil {
+			return err
+		}
+		if total >= maxOptionGroups {
+			return apperror.Invalid("productId", apperror.MsgMaxItems, maxOptionGroups)
+		}
+		options := group.Options
+		group.Options = nil
+		if err := tx.Select("*").Omit("id", "Options").Create(&group).Error; err != nil {
+			return err
+		}
+		for _, option := range options {
+			option.GroupID, option.AgentID = group.ID, group.AgentID
+			if err := tx.Select("*").Omit("id").Create(&option).Error; err != nil {
+				return err
+			}
+		}
+		return touchProduct(tx, group.ProductID)
+	})
+	if err != nil {
+		return OptionGroup{}, err
+	}
+	return r.FindOptionGroup(group.AgentID, group.ID)
+}
+
+func (r *repository) UpdateOptionGroup(agentID, id uint, changes map[string]interface{}) (OptionGroup, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var group OptionGroup
+		if err := tx.Where("agent_id = ?", agentID).First(&group, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&group).Updates(changes).Error; err != nil {
+			return err
+		}
+		return touchProduct(tx, group.ProductID)
+	})
+	if err != nil {
+		return OptionGroup{}, err
+	}
+	return r.FindOptionGroup(agentID, id)
+}
+
+// DeleteOptionGroup apaga o grupo e as opções dele.
+func (r *repository) DeleteOptionGroup(agentID, id uint) (OptionGroup, error) {
+	var group OptionGroup
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_id = ?", agentID).First(&group, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&Option{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&group).Error; err != nil {
+			return err
+		}
+		return touchProduct(tx, group.ProductID)
+	})
+	return group, err
+}
+
+func (r *repository) FindOption(agentID, id uint) (Option, error) {
+	var option Option
+	err := r.db.Where("agent_id = ?", agentID).First(&option, id).Error
+	return option, err
+}
+
+// AddOption inclui a opção no grupo, respeitando o limite de opções por grupo.
+func (r *repository) AddOption(option Option) (Option, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var group OptionGroup
+		err := tx.Where("agent_id = ?", option.AgentID).First(&group, option.GroupID).Error
+		if err != nil {
+			return err
+		}
+		if err := lockProduct(tx, group.AgentID, group.ProductID); err != nil {
+			return err
+		}
+		var total int64
+		if err := tx.Model(&Option{}).Where("group_id = ?", group.ID).Count(&total).Error; err != nil {
+			return err
+		}
+		if total >= maxOptionsPerGroup {
+			return apperror.Invalid("groupId", apperror.MsgMaxItems, maxOptionsPerGroup)
+		}
+		if err := tx.Select("*").Omit("id").Create(&option).Error; err != nil {
+			return err
+		}
+		return touchProduct(tx, group.ProductID)
+	})
+	if err != nil {
+		return Option{}, err
+	}
+	return r.FindOption(option.AgentID, option.ID)
+}
+
+func (r *repository) UpdateOption(agentID, id uint, changes map[string]interface{}) (Option, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var option Option
+		if err := tx.Where("agent_id = ?", agentID).First(&option, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&option).Updates(changes).Error; err != nil {
+			return err
+		}
+		return touchOptionProduct(tx, option.GroupID)
+	})
+	if err != nil {
+		return Option{}, err
+	}
+	return r.FindOption(agentID, id)
+}
+
+func (r *repository) DeleteOption(agentID, id uint) (Option, error) {
+	var option Option
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_id = ?", agentID).First(&option, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&option).Error; err != nil {
+			return err
+		}
+		return touchOptionProduct(tx, option.GroupID)
+	})
+	return option, err
+}
+
+// touchOptionProduct muda a versão do produto dono do grupo.
+func touchOptionProduct(tx *gorm.DB, groupID uint) error {
+	products := tx.Model(&OptionGroup{}).Select("product_id").Where("id = ?", groupID)
+	return tx.Model(&Product{}).Where("id = (?)", products).Update("version", gorm.Expr("version + 1")).Error
+}
+
+// FindVariants busca as variações de vários produtos numa única consulta.
+func (r *repository) FindVariants(productIDs []uint) ([]Variant, error) {
+	var variants []Variant
+	err := r.db.Where("product_id IN ?", productIDs).Order("product_id, position, id").Find(&variants).Error
+	return variants, err
+}
+
+func (r *repository) FindVariant(agentID, id uint) (Variant, error) {
+	var variant Variant
+	err := r.db.Where("agent_id = ?", agentID).First(&variant, id).Error
+	return variant, err
+}
+
+func (r *repository) CreateVariant(variant Variant) (Variant, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, variant.AgentID, variant.ProductID); err != nil {
+			return err
+		}
+		var total int64
+		if err := tx.Model(&Variant{}).Where("product_id = ?", variant.ProductID).Count(&total).Error; err != nil {
+			return err
+		}
+		if total >= maxVariants {
+			return apperror.Invalid("productId", apperror.MsgMaxItems, maxVariants)
+		}
+		if err := tx.Select("*").Omit("id").Create(&variant).Error; err != nil {
+			return err
+		}
+		return touchProduct(tx, variant.ProductID)
+	})
+	if err != nil {
+		return Variant{}, err
+	}
+	return r.FindVariant(variant.AgentID, variant.ID)
+}
+
+func (r *repository) UpdateVariant(agentID, id uint, changes map[string]interface{}) (Variant, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var variant Variant
+		if err := tx.Where("agent_id = ?", agentID).First(&variant, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&variant).Updates(changes).Error; err != nil {
+			return err
+		}
+		return touchProduct(tx, variant.ProductID)
+	})
+	if err != nil {
+		return Variant{}, err
+	}
+	return r.FindVariant(agentID, id)
+}
+
+func (r *repository) DeleteVariant(agentID, id uint) (Variant, error) {
+	var variant Variant
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_id = ?", agentID).First(&variant, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		return touchProduct(tx, variant.ProductID)
+	})
+	return variant, err
+}
+
+// AdjustStock lança a movimentação e atualiza o estoque na mesma transação. O produto e a
+// variação ficam travados até o fim, então vendas simultâneas são aplicadas uma de cada
+// vez e nenhuma leva o estoque abaixo de zero. Se o estoque zerar e o produto desativar
+// sozinho, a variação (ou o produto, sem variações) é desativada junto.
+func (r *repository) AdjustStock(agentID uint, dto AdjustStockDTO) (StockMovement, error) {
+	movement := StockMovement{
+		AgentID:   agentID,
+		ProductID: dto.ProductID,
+		VariantID: dto.VariantID,
+		Kind:      dto.Kind,
+		Quantity:  dto.Quantity,
+		Reason:    dto.Reason,
+		ActorID:   dto.ActorID,
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var product Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ?", agentID).First(&product, dto.ProductID).Error
+		if err != nil {
+			return err
+		}
+		if !product.TrackStock {
+			return apperror.Invalid("productId", apperror.MsgStockNotTracked)
+		}
+
+		target := tx.Model(&Product{}).Where("id = ?", product.ID)
+		current := product.Stock
+		if dto.VariantID != nil {
+			var variant Variant
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("product_id = ?", product.ID).First(&variant, *dto.VariantID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.Invalid("variantId", apperror.MsgInvalidChoice)
+			}
+			if err != nil {
+				return err
+			}
+			target = tx.Model(&Variant{}).Where("id = ?", variant.ID)
+			current = variant.Stock
+		} else {
+			var variants int64
+			if err := tx.Model(&Variant{}).Where("product_id = ?", product.ID).Count(&variants).Error; err != nil {
+				return err
+			}
+			if variants > 0 {
+				return apperror.Invalid("variantId", apperror.MsgVariantRequired)
+			}
+		}
+
+		switch dto.Kind {
+		case MovementIn:
+			movement.Delta = dto.Quantity
+		case MovementOut:
+			movement.Delta = -dto.Quantity
+		default:
+			movement.Delta = dto.Quantity - current
+		}
+		movement.Balance = current + movement.Delta
+		if movement.Balance < 0 {
+			return apperror.Invalid("quantity", apperror.MsgInsufficientStock, current)
+		}
+
+		changes := map[string]interface{}{"stock": movement.Balance}
+		if movement.Balance == 0 && product.AutoDeactivate {
+			changes["is_active"] = false
+		}
+		if err := target.Updates(changes).Error; err != nil {
+			return err
+		}
+		if err := touchProduct(tx, product.ID); err != nil {
+			return err
+		}
+		return tx.Create(&movement).Error
+	})
+	return movement, err
+}
+
+func (r *repository) FindStockMovements(agentID uint, filter StockMovementFilter, page pagination.Page) ([]StockMovement, error) {
+	var movements []StockMovement
+	err := r.db.Where("agent_id = ?", agentID).
+		Scopes(movementScope(filter), page.Scope(movementSort)).
+		Find(&movements).Error
+	return movements, err
+}
+
+func (r *repository) CountStockMovements(agentID uint, filter StockMovementFilter) (int64, error) {
+	var total int64
+	err := r.db.Model(&StockMovement{}).Where("agent_id = ?", agentID).Scopes(movementScope(filter)).Count(&total).Error
+	return total, err
+}
+
+// movementScope traduz o filtro do histórico em condições parametrizadas.
+func movementScope(filter StockMovementFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.ProductID != nil {
+			db = db.Where("product_id = ?", *filter.ProductID)
+		}
+		if filter.VariantID != nil {
+			db = db.Where("variant_id = ?", *filter.VariantID)
+		}
+		if filter.Kind != "" {
+			db = db.Where("kind = ?", filter.Kind)
+		}
+		return db.Scopes(filter.CreatedAt.Scope("created_at"))
+	}
+}
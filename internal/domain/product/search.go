@@ -0,0 +1,63 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/search.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import "github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+
+// SearchResult é um produto encontrado pela busca textual, com a relevância e os trechos
+// em que os termos aparecem, marcados com <mark>.
+type SearchResult struct {
+	Product
+	Rank          float64 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
+
+// SearchMigrations preparam a busca textual: a configuração portuguese_unaccent (o
+// dicionário portuguese precedido do unaccent, para "acai" achar "Açaí"), a coluna
+// search_vector com nome, descrição e tags, e os índices GIN da busca e do trigrama.
+var SearchMigrations = []database.SQLMigration{
+	{
+		ID: "0001_products_search_extensions",
+		SQL: `
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'portuguese_unaccent') THEN
+		CREATE TEXT SEARCH CONFIGURATION portuguese_unaccent (COPY = portuguese);
+		ALTER TEXT SEARCH CONFIGURATION portuguese_unaccent
+			ALTER MAPPING FOR hword, hword_part, word WITH unaccent, portuguese_stem;
+	END IF;
+END
+$$;
+
+-- unaccent() não é IMMUTABLE, então não pode ser usado direto em índices.
+CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text
+	AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$
+	LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;`,
+	},
+	{
+		ID: "0002_products_search_vector",
+		SQL: `
+CREATE OR REPLACE FUNCTION product_search_vector(name text, description text, tags text[]) RETURNS tsvector
+	AS $$
+		SELECT setweight(to_tsvector('portuguese_unaccent'::regconfig, coalesce(name, '')), 'A')
+			|| setweight(to_tsvector('portuguese_unaccent'::regconfig, coalesce(array_to_string(tags, ' '), '')), 'B')
+			|| setweight(to_tsvector('portuguese_unaccent'::regconfig, coalesce(description, '')), 'C')
+	$$
+	LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (product_search_vector(name, description, tags)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (immutable_unaccent(lower(name)) gin_trgm_ops);`,
+	},
+}
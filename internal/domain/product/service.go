@@ -7,49 +7,134 @@
 */
 package product
 
-import "math"
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
-const PageSize = 8
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/category"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/events"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/upload"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+	"gorm.io/gorm"
+)
 
 type Service interface {
-	GetAllProducts(agentID uint, page int) (PaginatedProducts, error)
+	GetAllProducts(agentID uint, filter ProductFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[Product], error)
 	GetProductByID(agentID, id uint) (Product, error)
-	SearchProducts(agentID uint, name string) ([]Product, error)
+	GetProductBySKU(agentID uint, sku string) (Product, error)
+	GetProductByBarcode(agentID uint, barcode string) (Product, error)
+	SearchProducts(agentID uint, text string, page pagination.Page) (pagination.Connection[SearchResult], error)
 	SearchByCategory(agentID, categoryID uint) ([]Product, error) // NOVO
 	CreateProduct(dto CreateProductDTO) (Product, error)
 	UpdateProduct(agentID, id uint, dto UpdateProductDTO) (Product, error)
+	UpsertProductBySKU(dto CreateProductDTO) (Product, bool, error)
 	DeleteProduct(agentID, id uint) error
+	GetTrashedProducts(agentID uint) ([]Product, error)
+	RestoreProduct(agentID, id uint) (Product, error)
+	UploadImage(agentID, id uint, file upload.File, uploadedBy *uint) (Product, error)
+	GetImagesByProductIDs(productIDs []uint) ([]ProductImage, error)
+	AddImage(agentID, productID uint, dto AddProductImageDTO) (Product, error)
+	ReorderImages(agentID, productID uint, imageIDs []uint) (Product, error)
+	SetCoverImage(agentID, productID, imageID uint) (Product, error)
+	RemoveImage(agentID, productID, imageID uint) (Product, error)
+	GetOptionGroupsByProductIDs(productIDs []uint) ([]OptionGroup, error)
+	CreateOptionGroup(agentID, productID uint, dto CreateOptionGroupDTO) (Product, error)
+	UpdateOptionGroup(agentID, id uint, dto UpdateOptionGroupDTO) (Product, error)
+	DeleteOptionGroup(agentID, id uint) (Product, error)
+	AddOption(agentID, groupID uint, dto OptionDTO) (Product, error)
+	UpdateOption(agentID, id uint, dto UpdateOptionDTO) (Product, error)
+	DeleteOption(agentID, id uint) (Product, error)
+	GetVariantsByProductIDs(productIDs []uint) ([]Variant, error)
+	CreateVariant(agentID, productID uint, dto CreateVariantDTO) (Product, error)
+	UpdateVariant(agentID, id uint, dto UpdateVariantDTO) (Product, error)
+	DeleteVariant(agentID, id uint) (Product, error)
+	CalculatePrice(agentID, productID uint, selection Selection) (PriceQuote, error)
+	AdjustStock(agentID uint, dto AdjustStockDTO) (StockMovement, Product, error)
+	GetStockMovements(agentID uint, filter StockMovementFilter, page pagination.Page) (pagination.Connection[StockMovement], error)
+	PurgeTrash(before time.Time) (int64, error)
+	PurgeAgents(agentIDs []uint) error
+	GetProductsByCategoryIDs(agentID uint, categoryIDs []uint, first int, afterID uint) ([]Product, error)
+	CountProductsByCategoryIDs(agentID uint, categoryIDs []uint) (map[uint]int64, error)
+}
+
+// CategoryFinder é a parte do serviço de categorias usada para conferir a categoria do produto.
+type CategoryFinder interface {
+	GetCategoryByID(agentID, id uint) (category.Category, error)
 }
 
 type service struct {
-	repo Repository
+	repo       Repository
+	quota      plan.QuotaChecker
+	events     events.Publisher
+	assets     asset.Linker
+	categories CategoryFinder
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// NewService cria o serviço. As mudanças nos registros são publicadas em publisher e as
+// imagens ficam na biblioteca de mídia (assets).
+func NewService(repo Repository, quota plan.QuotaChecker, publisher events.Publisher, assets asset.Linker, categories CategoryFinder) Service {
+	return &service{repo: repo, quota: quota, events: publisher, assets: assets, categories: categories}
 }
 
-func (s *service) GetAllProducts(agentID uint, page int) (PaginatedProducts, error) {
-	products, total, err := s.repo.FindAll(agentID, page, PageSize)
+func (s *service) GetAllProducts(agentID uint, filter ProductFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[Product], error) {
+	products, err := s.repo.FindAll(agentID, filter, sort, page)
 	if err != nil {
-		return PaginatedProducts{}, err
+		return pagination.Connection[Product]{}, err
 	}
-	totalPages := int(math.Ceil(float64(total) / float64(PageSize)))
-	return PaginatedProducts{
-		Data:       products,
-		Total:      total,
-		Page:       page,
-		PerPage:    PageSize,
-		TotalPages: totalPages,
-	}, nil
+	conn := pagination.NewConnection(products, page, func(item Product) pagination.Cursor {
+		cursor := sort.Cursor(item.ID)
+		switch sort.Column {
+		case "name":
+			cursor.Value = item.Name
+		case "price_amount":
+			cursor.Value = item.Price.Amount
+		case "created_at":
+			cursor.Value = item.CreatedAt
+		case "position":
+			cursor.Value = item.Position
+		}
+		return cursor
+	})
+	return conn.WithCount(func() (int64, error) { return s.repo.Count(agentID, filter) }), nil
 }
 
 func (s *service) GetProductByID(agentID, id uint) (Product, error) {
 	return s.repo.FindByID(agentID, id)
 }
 
-func (s *service) SearchProducts(agentID uint, name string) ([]Product, error) {
-	return s.repo.Search(agentID, name)
+func (s *service) GetProductBySKU(agentID uint, sku string) (Product, error) {
+	return s.repo.FindBySKU(agentID, strings.TrimSpace(sku))
+}
+
+func (s *service) GetProductByBarcode(agentID uint, barcode string) (Product, error) {
+	return s.repo.FindByBarcode(agentID, strings.TrimSpace(barcode))
+}
+
+func (s *service) SearchProducts(agentID uint, text string, page pagination.Page) (pagination.Connection[SearchResult], error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return pagination.Connection[SearchResult]{}, apperror.Invalid("query", apperror.MsgEmptySearch)
+	}
+	results, err := s.repo.Search(agentID, text, page)
+	if err != nil {
+		return pagination.Connection[SearchResult]{}, err
+	}
+	conn := pagination.NewConnection(results, page, func(item SearchResult) pagination.Cursor {
+		cursor := searchSort.Cursor(item.ID)
+		cursor.Value = item.Rank
+		return cursor
+	})
+	return conn.WithCount(func() (int64, error) { return s.repo.CountSearch(agentID, text) }), nil
 }
 
 func (s *service) SearchByCategory(agentID, categoryID uint) ([]Product, error) {
@@ -57,36 +142,802 @@ func (s *service) SearchByCategory(agentID, categoryID uint) ([]Product, error)
 }
 
 func (s *service) CreateProduct(dto CreateProductDTO) (Product, error) {
+	dto.SKU, dto.Barcode, dto.ExternalRefs = strings.TrimSpace(dto.SKU), strings.TrimSpace(dto.Barcode), dto.ExternalRefs.trimmed()
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
+	if err := s.checkCategory(dto.AgentID, dto.CategoryID); err != nil {
+		return Product{}, err
+	}
+	price, _ := dto.Price.Parse() // Já validado.
 	product := Product{
-		AgentID:     dto.AgentID,
-		CategoryID:  dto.CategoryID,
-		Name:        dto.Name,
-		Description: dto.Description,
-		Price:       dto.Price,
-		ImageURL:    dto.ImageURL,
-		IsActive:    dto.IsActive,
+		AgentID:           dto.AgentID,
+		CategoryID:        dto.CategoryID,
+		Name:              dto.Name,
+		Description:       dto.Description,
+		Price:             price,
+		ImageURL:          dto.ImageURL,
+		IsActive:          dto.IsActive,
+		Position:          dto.Position,
+		Tags:              dto.Tags,
+		TrackStock:        dto.TrackStock,
+		LowStockThreshold: dto.LowStockThreshold,
+		AutoDeactivate:    dto.AutoDeactivate,
+		SKU:               dto.SKU,
+		Barcode:           dto.Barcode,
+		ExternalRefs:      dto.ExternalRefs,
+	}
+	var cover *asset.Asset
+	if dto.ImageAssetID != nil {
+		image, err := s.imageAsset(dto.AgentID, *dto.ImageAssetID)
+		if err != nil {
+			return Product{}, err
+		}
+		cover = &image
+	} else if dto.ImageURL != "" {
+		image, found, err := s.assetByURL(dto.AgentID, dto.ImageURL)
+		if err != nil {
+			return Product{}, err
+		}
+		if found {
+			cover = &image
+		}
+	}
+	var coverImage *ProductImage
+	if cover != nil {
+		product.ImageURL = "" // Vem da capa da galeria.
+		coverImage = &ProductImage{AssetID: cover.ID, URLs: imageSetOf(*cover), AltText: cover.AltText}
 	}
-	return s.repo.Create(product)
+	created, err := s.repo.Create(product, coverImage, s.quota.Reserve(dto.AgentID, plan.ResourceProducts, 1))
+	if err == nil && cover != nil {
+		s.linkImages(created.AgentID, created.ID, cover.ID, nil)
+	}
+	return s.publish(events.ActionCreated, created, err)
 }
 
 func (s *service) UpdateProduct(agentID, id uint, dto UpdateProductDTO) (Product, error) {
+	dto.SKU = patch.Map(dto.SKU, strings.TrimSpace)
+	dto.Barcode = patch.Map(dto.Barcode, strings.TrimSpace)
+	dto.ExternalRefs = patch.Map(dto.ExternalRefs, ExternalRefs.trimmed)
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
 	productToUpdate, err := s.repo.FindByID(agentID, id)
 	if err != nil {
 		return Product{}, err
 	}
-	productToUpdate.CategoryID = dto.CategoryID
-	productToUpdate.Name = dto.Name
-	productToUpdate.Description = dto.Description
-	productToUpdate.Price = dto.Price
-	productToUpdate.ImageURL = dto.ImageURL
-	productToUpdate.IsActive = dto.IsActive
-	return s.repo.Update(productToUpdate)
+	if productToUpdate.Version != dto.Version {
+		return Product{}, apperror.Conflict(productToUpdate)
+	}
+
+	if dto.CategoryID.Set && !dto.CategoryID.Null {
+		if err := s.checkCategory(agentID, dto.CategoryID.Value); err != nil {
+			return Product{}, err
+		}
+	}
+	changes := patch.Changes{}
+	if err := patch.Required(changes, "category_id", dto.CategoryID); err != nil {
+		return Product{}, err
+	}
+	if err := patch.Required(changes, "name", dto.Name); err != nil {
+		return Product{}, err
+	}
+	if dto.Price.Set {
+		if dto.Price.Null {
+			return Product{}, apperror.Invalid("price", apperror.MsgFieldNotNull)
+		}
+		price, _ := dto.Price.Value.Parse() // Já validado.
+		if price.Currency != productToUpdate.Price.Currency {
+			if err := s.checkCurrencyChange(productToUpdate); err != nil {
+				return Product{}, err
+			}
+		}
+		changes["price_amount"], changes["price_currency"] = price.Amount, price.Currency
+	}
+	if err := patch.Required(changes, "is_active", dto.IsActive); err != nil {
+		return Product{}, err
+	}
+	patch.Nullable(changes, "position", dto.Position)
+	patch.Nullable(changes, "tags", dto.Tags)
+	patch.Nullable(changes, "description", dto.Description)
+	if err := patch.Required(changes, "track_stock", dto.TrackStock); err != nil {
+		return Product{}, err
+	}
+	if err := patch.Required(changes, "auto_deactivate", dto.AutoDeactivate); err != nil {
+		return Product{}, err
+	}
+	patch.Nullable(changes, "low_stock_threshold", dto.LowStockThreshold)
+	patch.Nullable(changes, "sku", dto.SKU)
+	patch.Nullable(changes, "barcode", dto.Barcode)
+	patch.Nullable(changes, "external_refs", dto.ExternalRefs)
+	cover, err := s.coverChange(agentID, id, dto, changes)
+	if err != nil {
+		return Product{}, err
+	}
+	if len(changes) == 0 && cover == nil {
+		return productToUpdate, nil
+	}
+
+	updated := productToUpdate
+	if len(changes) > 0 {
+		updated, err = s.repo.Update(agentID, id, dto.Version, changes)
+		if errors.Is(err, apperror.ErrVersionMismatch) {
+			return s.conflict(agentID, id)
+		}
+		if err != nil {
+			return Product{}, err
+		}
+	}
+	if cover != nil {
+		updated, err = cover()
+	}
+	return s.publish(events.ActionUpdated, updated, err)
+}
+
+// UpsertProductBySKU cria o produto ou, se o agente já tiver um com o mesmo SKU, substitui
+// os dados dele pelos enviados; o bool informa se o produto foi criado. A imagem só muda
+// quando enviada, e o estoque continua mudando só pelo adjustStock.
+func (s *service) UpsertProductBySKU(dto CreateProductDTO) (Product, bool, error) {
+	dto.SKU = strings.TrimSpace(dto.SKU)
+	if dto.SKU == "" {
+		return Product{}, false, apperror.Invalid("sku", apperror.MsgRequired)
+	}
+	existing, err := s.repo.FindBySKU(dto.AgentID, dto.SKU)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		created, err := s.CreateProduct(dto)
+		return created, err == nil, err
+	}
+	if err != nil {
+		return Product{}, false, err
+	}
+
+	update := UpdateProductDTO{
+		Version:           existing.Version,
+		CategoryID:        patch.Of(dto.CategoryID),
+		Name:              patch.Of(dto.Name),
+		Description:       patch.Of(dto.Description),
+		Price:             patch.Of(dto.Price),
+		IsActive:          patch.Of(dto.IsActive),
+		Position:          patch.Of(dto.Position),
+		Tags:              patch.Of(database.StringArray(dto.Tags)),
+		TrackStock:        patch.Of(dto.TrackStock),
+		LowStockThreshold: patch.Of(dto.LowStockThreshold),
+		AutoDeactivate:    patch.Of(dto.AutoDeactivate),
+		Barcode:           patch.Of(dto.Barcode),
+		ExternalRefs:      patch.Of(dto.ExternalRefs),
+	}
+	if dto.ImageAssetID != nil {
+		update.ImageAssetID = patch.Of(*dto.ImageAssetID)
+	} else if dto.ImageURL != "" {
+		update.ImageURL = patch.Of(dto.ImageURL)
+	}
+	updated, err := s.UpdateProduct(dto.AgentID, existing.ID, update)
+	return updated, false, err
+}
+
+// coverChange trata imageUrl e imageAssetId, que alteram a capa da galeria. URLs externas
+// só são aceitas em produtos sem galeria e vão direto para changes; o resto volta como a
+// operação na galeria, feita depois das demais mudanças.
+func (s *service) coverChange(agentID, id uint, dto UpdateProductDTO, changes patch.Changes) (func() (Product, error), error) {
+	var image *asset.Asset
+	switch {
+	case dto.ImageAssetID.Set && !dto.ImageAssetID.Null:
+		found, err := s.imageAsset(agentID, dto.ImageAssetID.Value)
+		if err != nil {
+			return nil, err
+		}
+		image = &found
+	case dto.ImageURL.Set && dto.ImageURL.Value != "":
+		found, ok, err := s.assetByURL(agentID, dto.ImageURL.Value)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			image = &found
+		}
+	case !dto.ImageAssetID.Set && !dto.ImageURL.Set:
+		return nil, nil
+	}
+	if image != nil {
+		return func() (Product, error) { return s.setCover(agentID, id, *image) }, nil
+	}
+
+	gallery, err := s.repo.FindImages([]uint{id})
+	if err != nil {
+		return nil, err
+	}
+	url := dto.ImageURL.Value
+	if url == "" && len(gallery) > 0 {
+		// Sem imagem: a capa sai da galeria e a próxima assume.
+		return func() (Product, error) { return s.removeImage(agentID, id, gallery[0].ID) }, nil
+	}
+	if len(gallery) > 0 {
+		return nil, apperror.Invalid("imageUrl", apperror.MsgProductHasGallery)
+	}
+	// URL externa (ou nenhuma) num produto sem galeria: não tem variantes nem asset.
+	changes["image_url"], changes["images"], changes["image_asset_id"] = url, ImageSet{}, nil
+	return nil, nil
+}
+
+// conflict relê o produto para devolver ao cliente o estado que venceu a disputa.
+func (s *service) conflict(agentID, id uint) (Product, error) {
+	current, err := s.repo.FindByID(agentID, id)
+	if err != nil {
+		return Product{}, err
+	}
+	return Product{}, apperror.Conflict(current)
 }
 
 func (s *service) DeleteProduct(agentID, id uint) error {
-	_, err := s.repo.FindByID(agentID, id)
+	existing, err := s.repo.FindByID(agentID, id)
 	if err != nil {
 		return err
 	}
-	return s.repo.Delete(agentID, id)
+	_, err = s.publish(events.ActionDeleted, existing, s.repo.Delete(agentID, id))
+	return err
+}
+
+func (s *service) GetTrashedProducts(agentID uint) ([]Product, error) {
+	return s.repo.FindTrashed(agentID)
+}
+
+func (s *service) RestoreProduct(agentID, id uint) (Product, error) {
+	if err := s.repo.Restore(agentID, id, s.quota.Reserve(agentID, plan.ResourceProducts, 1)); err != nil {
+		return Product{}, err
+	}
+	restored, err := s.repo.FindByID(agentID, id)
+	return s.publish(events.ActionRestored, restored, err)
+}
+
+// UploadImage grava a imagem na biblioteca de mídia e a coloca no lugar da capa da galeria.
+func (s *service) UploadImage(agentID, id uint, file upload.File, uploadedBy *uint) (Product, error) {
+	if _, err := s.repo.FindByID(agentID, id); err != nil {
+		return Product{}, err
+	}
+	image, err := s.uploadAsset(agentID, file, "", uploadedBy)
+	if err != nil {
+		return Product{}, err
+	}
+	updated, err := s.setCover(agentID, id, image)
+	return s.publish(events.ActionUpdated, updated, err)
+}
+
+func (s *service) GetImagesByProductIDs(productIDs []uint) ([]ProductImage, error) {
+	return s.repo.FindImages(productIDs)
+}
+
+// AddImage inclui na galeria um asset da biblioteca de mídia ou um arquivo enviado agora.
+func (s *service) AddImage(agentID, productID uint, dto AddProductImageDTO) (Product, error) {
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
+	if _, err := s.repo.FindByID(agentID, productID); err != nil {
+		return Product{}, err
+	}
+	var image asset.Asset
+	var err error
+	if dto.File != nil {
+		image, err = s.uploadAsset(agentID, *dto.File, dto.AltText, dto.UploadedBy)
+	} else {
+		image, err = s.galleryAsset(agentID, dto.AssetID)
+	}
+	if err != nil {
+		return Product{}, err
+	}
+	if dto.AltText == "" {
+		dto.AltText = image.AltText
+	}
+
+	_, err = s.repo.AddImage(agentID, productID, ProductImage{AssetID: image.ID, URLs: imageSetOf(image), AltText: dto.AltText, IsCover: dto.Cover})
+	if err != nil {
+		return Product{}, err
+	}
+	s.linkImages(agentID, productID, image.ID, nil)
+	updated, err := s.repo.FindByID(agentID, productID)
+	return s.publish(events.ActionUpdated, updated, err)
+}
+
+// ReorderImages define a ordem da galeria; imageIDs deve listar todas as imagens do produto.
+func (s *service) ReorderImages(agentID, productID uint, imageIDs []uint) (Product, error) {
+	if err := s.repo.ReorderImages(agentID, productID, imageIDs); err != nil {
+		return Product{}, err
+	}
+	updated, err := s.repo.FindByID(agentID, productID)
+	return s.publish(events.ActionUpdated, updated, err)
+}
+
+func (s *service) SetCoverImage(agentID, productID, imageID uint) (Product, error) {
+	if err := s.repo.SetCoverImage(agentID, productID, imageID); err != nil {
+		return Product{}, err
+	}
+	updated, err := s.repo.FindByID(agentID, productID)
+	return s.publish(events.ActionUpdated, updated, err)
+}
+
+// RemoveImage tira a imagem da galeria. O asset continua na biblioteca de mídia e, se não
+// for usado em outro lugar, é apagado depois do prazo de retenção.
+func (s *service) RemoveImage(agentID, productID, imageID uint) (Product, error) {
+	updated, err := s.removeImage(agentID, productID, imageID)
+	return s.publish(events.ActionUpdated, updated, err)
+}
+
+func (s *service) removeImage(agentID, productID, imageID uint) (Product, error) {
+	removed, err := s.repo.RemoveImage(agentID, productID, imageID)
+	if err != nil {
+		return Product{}, err
+	}
+	s.linkImages(agentID, productID, 0, []ProductImage{removed})
+	return s.repo.FindByID(agentID, productID)
+}
+
+// setCover coloca o asset no lugar da capa da galeria e retorna o produto atualizado.
+func (s *service) setCover(agentID, id uint, image asset.Asset) (Product, error) {
+	removed, err := s.repo.ReplaceCover(agentID, id, ProductImage{AssetID: image.ID, URLs: imageSetOf(image), AltText: image.AltText})
+	if err != nil {
+		return Product{}, err
+	}
+	s.linkImages(agentID, id, image.ID, removed)
+	return s.repo.FindByID(agentID, id)
+}
+
+// uploadAsset grava o arquivo como imagem de produto na biblioteca de mídia.
+func (s *service) uploadAsset(agentID uint, file upload.File, altText string, uploadedBy *uint) (asset.Asset, error) {
+	src, err := file.Open()
+	if err != nil {
+		return asset.Asset{}, err
+	}
+	defer src.Close()
+	return s.assets.UploadImage(agentID, asset.UploadImageDTO{
+		Kind:       asset.KindProductImage,
+		Body:       src,
+		Size:       file.Size,
+		AltText:    altText,
+		UploadedBy: uploadedBy,
+	})
+}
+
+// imageAsset busca o asset escolhido como imagem do produto.
+func (s *service) imageAsset(agentID, assetID uint) (asset.Asset, error) {
+	return s.findAsset(agentID, assetID, "imageAssetId")
+}
+
+// galleryAsset busca o asset incluído na galeria.
+func (s *service) galleryAsset(agentID, assetID uint) (asset.Asset, error) {
+	return s.findAsset(agentID, assetID, "assetId")
+}
+
+func (s *service) findAsset(agentID, assetID uint, field string) (asset.Asset, error) {
+	image, err := s.assets.GetAssetByID(agentID, assetID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return asset.Asset{}, apperror.Invalid(field, apperror.MsgNotFound)
+	}
+	return image, err
+}
+
+// assetByURL procura o asset de uma URL informada à mão. Clientes antigos gravam a URL
+// devolvida pelo upload em vez do ID; com o vínculo, o asset não é apagado pela coleta.
+func (s *service) assetByURL(agentID uint, url string) (asset.Asset, bool, error) {
+	image, err := s.assets.GetAssetByURL(agentID, url)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return asset.Asset{}, false, nil
+	}
+	return image, err == nil, err
+}
+
+// linkImages atualiza o uso dos assets quando a galeria muda: added (se não for zero)
+// passa a ser usado pelo produto e os removed deixam de ser. Falhas só são registradas:
+// no pior caso, um asset ainda usado começa a contar o prazo de retenção.
+func (s *service) linkImages(agentID, id, added uint, removed []ProductImage) {
+	owner := asset.Owner{Type: asset.OwnerProduct, ID: id}
+	for _, image := range removed {
+		if image.AssetID == added {
+			continue
+		}
+		if err := s.assets.Unlink(owner, image.AssetID); err != nil {
+			log.Printf("Falha ao desvincular o asset %d do produto %d: %v", image.AssetID, id, err)
+		}
+	}
+	if added != 0 {
+		if _, err := s.assets.Link(agentID, added, owner); err != nil {
+			log.Printf("Falha ao vincular o asset %d ao produto %d: %v", added, id, err)
+		}
+	}
+}
+
+// PurgeTrash apaga os produtos da lixeira e libera os assets que eles usavam.
+func (s *service) PurgeTrash(before time.Time) (int64, error) {
+	ids, err := s.repo.Purge(before)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.assets.UnlinkOwners(asset.OwnerProduct, ids); err != nil {
+		log.Printf("Falha ao desvincular os assets dos produtos apagados: %v", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// PurgeAgents apaga todos os produtos dos agentes removidos e os usos de assets deles.
+func (s *service) PurgeAgents(agentIDs []uint) error {
+	ids, err := s.repo.PurgeAgents(agentIDs)
+	if err != nil {
+		return err
+	}
+	return s.assets.UnlinkOwners(asset.OwnerProduct, ids)
+}
+
+func (s *service) GetProductsByCategoryIDs(agentID uint, categoryIDs []uint, first int, afterID uint) ([]Product, error) {
+	return s.repo.FindByCategoryIDs(agentID, categoryIDs, first, afterID)
+}
+
+func (s *service) CountProductsByCategoryIDs(agentID uint, categoryIDs []uint) (map[uint]int64, error) {
+	return s.repo.CountByCategoryIDs(agentID, categoryIDs)
+}
+
+// checkCategory confere se a categoria escolhida é do agente.
+func (s *service) checkCategory(agentID, categoryID uint) error {
+	_, err := s.categories.GetCategoryByID(agentID, categoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Invalid("categoryId", apperror.MsgNotFound)
+	}
+	return err
+}
+
+func (s *service) GetOptionGroupsByProductIDs(productIDs []uint) ([]OptionGroup, error) {
+	return s.repo.FindOptionGroups(productIDs)
+}
+
+func (s *service) CreateOptionGroup(agentID, productID uint, dto CreateOptionGroupDTO) (Product, error) {
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
+	product, err := s.repo.FindByID(agentID, productID)
+	if err != nil {
+		return Product{}, err
+	}
+	group := OptionGroup{
+		AgentID:   agentID,
+		ProductID: productID,
+		Name:      dto.Name,
+		Multiple:  dto.Multiple,
+		MinSelect: dto.MinSelect,
+		MaxSelect: dto.MaxSelect,
+		Required:  dto.Required,
+		Position:  dto.Position,
+	}
+	for i, option := range dto.Options {
+		delta, err := priceDelta(product, option.PriceDelta, fmt.Sprintf("options.%d.priceDelta", i))
+		if err != nil {
+			return Product{}, err
+		}
+		group.Options = append(group.Options, Option{Name: option.Name, PriceDelta: delta, IsAvailable: option.IsAvailable, Position: option.Position})
+	}
+	if _, err := s.repo.CreateOptionGroup(group); err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, productID)
+}
+
+func (s *service) UpdateOptionGroup(agentID, id uint, dto UpdateOptionGroupDTO) (Product, error) {
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
+	group, err := s.repo.FindOptionGroup(agentID, id)
+	if err != nil {
+		return Product{}, err
+	}
+
+	changes := patch.Changes{}
+	if err := patch.Required(changes, "name", dto.Name); err != nil {
+		return Product{}, err
+	}
+	if err := patch.Required(changes, "multiple", dto.Multiple); err != nil {
+		return Product{}, err
+	}
+	if err := patch.Required(changes, "required", dto.Required); err != nil {
+		return Product{}, err
+	}
+	patch.Nullable(changes, "min_select", dto.MinSelect)
+	patch.Nullable(changes, "max_select", dto.MaxSelect)
+	patch.Nullable(changes, "position", dto.Position)
+	if len(changes) == 0 {
+		return s.repo.FindByID(agentID, group.ProductID)
+	}
+
+	// min e max podem vir em atualizações separadas; o grupo resultante precisa fazer sentido.
+	merged := group
+	if v, ok := changes["multiple"].(bool); ok {
+		merged.Multiple = v
+	}
+	if v, ok := changes["min_select"].(int); ok {
+		merged.MinSelect = v
+	}
+	if v, ok := changes["max_select"].(int); ok {
+		merged.MaxSelect = v
+	}
+	if err := validate.All(selectionRange(merged)); err != nil {
+		return Product{}, err
+	}
+	if _, err := s.repo.UpdateOptionGroup(agentID, id, changes); err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, group.ProductID)
+}
+
+// DeleteOptionGroup apaga o grupo e as opções dele.
+func (s *service) DeleteOptionGroup(agentID, id uint) (Product, error) {
+	group, err := s.repo.DeleteOptionGroup(agentID, id)
+	if err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, group.ProductID)
+}
+
+func (s *service) AddOption(agentID, groupID uint, dto OptionDTO) (Product, error) {
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
+	group, err := s.repo.FindOptionGroup(agentID, groupID)
+	if err != nil {
+		return Product{}, err
+	}
+	product, err := s.repo.FindByID(agentID, group.ProductID)
+	if err != nil {
+		return Product{}, err
+	}
+	delta, err := priceDelta(product, dto.PriceDelta, "priceDelta")
+	if err != nil {
+		return Product{}, err
+	}
+	option := Option{AgentID: agentID, GroupID: groupID, Name: dto.Name, PriceDelta: delta, IsAvailable: dto.IsAvailable, Position: dto.Position}
+	if _, err := s.repo.AddOption(option); err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, group.ProductID)
+}
+
+func (s *service) UpdateOption(agentID, id uint, dto UpdateOptionDTO) (Product, error) {
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
+	option, err := s.repo.FindOption(agentID, id)
+	if err != nil {
+		return Product{}, err
+	}
+	group, err := s.repo.FindOptionGroup(agentID, option.GroupID)
+	if err != nil {
+		return Product{}, err
+	}
+
+	changes := patch.Changes{}
+	if err := patch.Required(changes, "name", dto.Name); err != nil {
+		return Product{}, err
+	}
+	if err := patch.Required(changes, "is_available", dto.IsAvailable); err != nil {
+		return Product{}, err
+	}
+	patch.Nullable(changes, "position", dto.Position)
+	if dto.PriceDelta.Set {
+		product, err := s.repo.FindByID(agentID, group.ProductID)
+		if err != nil {
+			return Product{}, err
+		}
+		// null volta a opção para preço zero.
+		delta, err := priceDelta(product, dto.PriceDelta.Value, "priceDelta")
+		if err != nil {
+			return Product{}, err
+		}
+		changes["price_delta_amount"], changes["price_delta_currency"] = delta.Amount, delta.Currency
+	}
+	if len(changes) == 0 {
+		return s.repo.FindByID(agentID, group.ProductID)
+	}
+	if _, err := s.repo.UpdateOption(agentID, id, changes); err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, group.ProductID)
+}
+
+func (s *service) DeleteOption(agentID, id uint) (Product, error) {
+	option, err := s.repo.FindOption(agentID, id)
+	if err != nil {
+		return Product{}, err
+	}
+	group, err := s.repo.FindOptionGroup(agentID, option.GroupID)
+	if err != nil {
+		return Product{}, err
+	}
+	if _, err := s.repo.DeleteOption(agentID, id); err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, group.ProductID)
+}
+
+func (s *service) GetVariantsByProductIDs(productIDs []uint) ([]Variant, error) {
+	return s.repo.FindVariants(productIDs)
+}
+
+func (s *service) CreateVariant(agentID, productID uint, dto CreateVariantDTO) (Product, error) {
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
+	product, err := s.repo.FindByID(agentID, productID)
+	if err != nil {
+		return Product{}, err
+	}
+	price, err := priceIn(product, dto.Price, "price")
+	if err != nil {
+		return Product{}, err
+	}
+	variant := Variant{
+		AgentID:   agentID,
+		ProductID: productID,
+		Name:      dto.Name,
+		SKU:       strings.TrimSpace(dto.SKU),
+		Price:     price,
+		IsActive:  dto.IsActive,
+		Position:  dto.Position,
+	}
+	if _, err := s.repo.CreateVariant(variant); err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, productID)
+}
+
+func (s *service) UpdateVariant(agentID, id uint, dto UpdateVariantDTO) (Product, error) {
+	if err := dto.Validate(); err != nil {
+		return Product{}, err
+	}
+	variant, err := s.repo.FindVariant(agentID, id)
+	if err != nil {
+		return Product{}, err
+	}
+
+	changes := patch.Changes{}
+	if err := patch.Required(changes, "name", dto.Name); err != nil {
+		return Product{}, err
+	}
+	if err := patch.Required(changes, "is_active", dto.IsActive); err != nil {
+		return Product{}, err
+	}
+	patch.Nullable(changes, "sku", patch.Map(dto.SKU, strings.TrimSpace))
+	patch.Nullable(changes, "position", dto.Position)
+	if dto.Price.Set {
+		if dto.Price.Null {
+			return Product{}, apperror.Invalid("price", apperror.MsgFieldNotNull)
+		}
+		product, err := s.repo.FindByID(agentID, variant.ProductID)
+		if err != nil {
+			return Product{}, err
+		}
+		price, err := priceIn(product, dto.Price.Value, "price")
+		if err != nil {
+			return Product{}, err
+		}
+		changes["price_amount"], changes["price_currency"] = price.Amount, price.Currency
+	}
+	if len(changes) == 0 {
+		return s.repo.FindByID(agentID, variant.ProductID)
+	}
+	if _, err := s.repo.UpdateVariant(agentID, id, changes); err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, variant.ProductID)
+}
+
+func (s *service) DeleteVariant(agentID, id uint) (Product, error) {
+	variant, err := s.repo.DeleteVariant(agentID, id)
+	if err != nil {
+		return Product{}, err
+	}
+	return s.changed(agentID, variant.ProductID)
+}
+
+// CalculatePrice calcula o preço de uma seleção com as variações e opções atuais do
+// produto. Produtos inativos não são vendidos e não têm preço.
+func (s *service) CalculatePrice(agentID, productID uint, selection Selection) (PriceQuote, error) {
+	product, err := s.repo.FindByID(agentID, productID)
+	if err != nil {
+		return PriceQuote{}, err
+	}
+	if !product.IsActive {
+		return PriceQuote{}, apperror.NotFound()
+	}
+	variants, err := s.repo.FindVariants([]uint{productID})
+	if err != nil {
+		return PriceQuote{}, err
+	}
+	groups, err := s.repo.FindOptionGroups([]uint{productID})
+	if err != nil {
+		return PriceQuote{}, err
+	}
+	return CalculatePrice(product, variants, groups, selection)
+}
+
+// AdjustStock lança uma movimentação de estoque e devolve o produto atualizado. Quando a
+// movimentação faz o estoque cruzar o limite de estoque baixo ou zerar, um aviso sai em
+// events.TopicStock.
+func (s *service) AdjustStock(agentID uint, dto AdjustStockDTO) (StockMovement, Product, error) {
+	dto.Reason = strings.TrimSpace(dto.Reason)
+	if err := dto.Validate(); err != nil {
+		return StockMovement{}, Product{}, err
+	}
+	movement, err := s.repo.AdjustStock(agentID, dto)
+	if err != nil {
+		return StockMovement{}, Product{}, err
+	}
+	updated, err := s.changed(agentID, dto.ProductID)
+	if err != nil {
+		return StockMovement{}, Product{}, err
+	}
+	if alert, ok := stockAlert(updated, movement); ok {
+		s.events.Publish(events.Event{Topic: events.TopicStock, Action: alert.Kind, AgentID: agentID, Payload: alert})
+	}
+	return movement, updated, nil
+}
+
+func (s *service) GetStockMovements(agentID uint, filter StockMovementFilter, page pagination.Page) (pagination.Connection[StockMovement], error) {
+	movements, err := s.repo.FindStockMovements(agentID, filter, page)
+	if err != nil {
+		return pagination.Connection[StockMovement]{}, err
+	}
+	conn := pagination.NewConnection(movements, page, func(item StockMovement) pagination.Cursor {
+		return movementSort.Cursor(item.ID)
+	})
+	return conn.WithCount(func() (int64, error) { return s.repo.CountStockMovements(agentID, filter) }), nil
+}
+
+// checkCurrencyChange recusa trocar a moeda de um produto com variações ou opções, cujos
+// preços estão na moeda atual e deixariam de somar com o do produto.
+func (s *service) checkCurrencyChange(product Product) error {
+	variants, err := s.repo.FindVariants([]uint{product.ID})
+	if err != nil {
+		return err
+	}
+	groups, err := s.repo.FindOptionGroups([]uint{product.ID})
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if len(group.Options) > 0 {
+			return apperror.Invalid("price", apperror.MsgCurrencyMismatch, product.Price.Currency)
+		}
+	}
+	if len(variants) > 0 {
+		return apperror.Invalid("price", apperror.MsgCurrencyMismatch, product.Price.Currency)
+	}
+	return nil
+}
+
+// changed relê o produto depois de uma mudança nas opções ou variações e avisa os assinantes.
+func (s *service) changed(agentID, productID uint) (Product, error) {
+	updated, err := s.repo.FindByID(agentID, productID)
+	return s.publish(events.ActionUpdated, updated, err)
+}
+
+// priceIn converte um valor já validado, exigindo a moeda do produto.
+func priceIn(product Product, input money.Input, field string) (money.Money, error) {
+	price, err := input.Parse()
+	if err != nil {
+		return money.Money{}, err
+	}
+	if price.Currency != product.Price.Currency {
+		return money.Money{}, apperror.Invalid(field, apperror.MsgCurrencyMismatch, product.Price.Currency)
+	}
+	return price, nil
+}
+
+// priceDelta é como priceIn, mas um valor vazio vale zero na moeda do produto.
+func priceDelta(product Product, input money.Input, field string) (money.Money, error) {
+	if input.Amount == "" {
+		return money.Money{Currency: product.Price.Currency}, nil
+	}
+	return priceIn(product, input, field)
+}
+
+// publish avisa os inscritos quando a operação deu certo.
+func (s *service) publish(action string, product Product, err error) (Product, error) {
+	if err == nil {
+		s.events.Publish(events.Event{Topic: events.TopicProduct, Action: action, AgentID: product.AgentID, Payload: product})
+	}
+	return product, err
 }
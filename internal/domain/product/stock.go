@@ -0,0 +1,87 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/stock.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+)
+
+// Tipos de movimentação de estoque.
+const (
+	MovementIn         = "in"         // Entrada: soma Quantity.
+	MovementOut        = "out"        // Saída, como uma venda: subtrai Quantity.
+	MovementAdjustment = "adjustment" // Contagem: o estoque passa a ser Quantity.
+)
+
+// Avisos publicados em events.TopicStock quando o estoque cai.
+const (
+	AlertLowStock   = "low_stock"
+	AlertOutOfStock = "out_of_stock"
+)
+
+// StockMovement é um lançamento no histórico de estoque de um produto ou de uma das
+// variações dele. Os lançamentos não mudam depois de gravados.
+type StockMovement struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AgentID   uint      `gorm:"not null;index:idx_stock_movements_product,priority:1" json:"agent_id"`
+	ProductID uint      `gorm:"not null;index:idx_stock_movements_product,priority:2" json:"product_id"`
+	VariantID *uint     `gorm:"index" json:"variant_id"`
+	Kind      string    `gorm:"size:20;not null" json:"kind"`
+	Quantity  int       `gorm:"not null" json:"quantity"` // Como foi informado; no ajuste, a contagem.
+	Delta     int       `gorm:"not null" json:"delta"`    // Quanto o estoque mudou.
+	Balance   int       `gorm:"not null" json:"balance"`  // Estoque depois do lançamento.
+	Reason    string    `gorm:"not null;default:''" json:"reason"`
+	ActorID   *uint     `gorm:"index" json:"actor_id"` // Usuário que lançou; vazio para integrações.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AdjustStockDTO - dados de uma movimentação de estoque.
+type AdjustStockDTO struct {
+	ProductID uint
+	VariantID *uint // Obrigatória em produtos com variações.
+	Kind      string
+	Quantity  int
+	Reason    string
+	ActorID   *uint
+}
+
+// StockMovementFilter restringe o histórico. Campos vazios não filtram.
+type StockMovementFilter struct {
+	ProductID *uint
+	VariantID *uint
+	Kind      string
+	CreatedAt pagination.TimeRange
+}
+
+// StockAlert é o aviso de que o estoque de um produto, ou de uma variação, chegou ao
+// limite de estoque baixo ou zerou.
+type StockAlert struct {
+	Kind      string  `json:"kind"`
+	Product   Product `json:"product"`
+	VariantID *uint   `json:"variant_id"`
+	Stock     int     `json:"stock"`
+	Threshold int     `json:"threshold"`
+}
+
+// stockAlert decide se a movimentação cruzou um limite para baixo. Movimentações que
+// deixam o estoque onde já estava abaixo do limite não avisam de novo.
+func stockAlert(product Product, movement StockMovement) (StockAlert, bool) {
+	before := movement.Balance - movement.Delta
+	alert := StockAlert{Product: product, VariantID: movement.VariantID, Stock: movement.Balance, Threshold: product.LowStockThreshold}
+	switch {
+	case movement.Balance == 0 && before > 0:
+		alert.Kind = AlertOutOfStock
+	case product.LowStockThreshold > 0 && movement.Balance <= product.LowStockThreshold && before > product.LowStockThreshold:
+		alert.Kind = AlertLowStock
+	default:
+		return StockAlert{}, false
+	}
+	return alert, true
+}
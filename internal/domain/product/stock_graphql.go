@@ -0,0 +1,203 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/stock_graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/events"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+var stockMovementKindEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "StockMovementKind",
+	Values: graphql.EnumValueConfigMap{
+		"IN":         &graphql.EnumValueConfig{Value: MovementIn, Description: "Entrada: soma a quantidade."},
+		"OUT":        &graphql.EnumValueConfig{Value: MovementOut, Description: "Saída, como uma venda: subtrai a quantidade."},
+		"ADJUSTMENT": &graphql.EnumValueConfig{Value: MovementAdjustment, Description: "Contagem: o estoque passa a ser a quantidade."},
+	},
+})
+
+var stockAlertKindEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "StockAlertKind",
+	Values: graphql.EnumValueConfigMap{
+		"LOW_STOCK":    &graphql.EnumValueConfig{Value: AlertLowStock},
+		"OUT_OF_STOCK": &graphql.EnumValueConfig{Value: AlertOutOfStock},
+	},
+})
+
+// StockMovementType é um lançamento do histórico de estoque.
+var StockMovementType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "StockMovement",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"product_id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"variant_id": &graphql.Field{Type: graphql.Int},
+			"kind":       &graphql.Field{Type: graphql.NewNonNull(stockMovementKindEnum)},
+			"quantity":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Como foi informada; no ajuste, a contagem."},
+			"delta":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Quanto o estoque mudou."},
+			"balance":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Estoque depois do lançamento."},
+			"reason":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"actor_id":   &graphql.Field{Type: graphql.Int, Description: "Usuário que lançou."},
+			"created_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	},
+)
+
+var stockMovementConnectionType = pagination.ConnectionType("StockMovement", StockMovementType)
+
+var adjustStockPayloadType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "AdjustStockPayload",
+		Fields: graphql.Fields{
+			"movement": &graphql.Field{Type: graphql.NewNonNull(StockMovementType)},
+			"product":  &graphql.Field{Type: graphql.NewNonNull(ProductType)},
+		},
+	},
+)
+
+var stockAlertType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "StockAlert",
+		Fields: graphql.Fields{
+			"kind":       &graphql.Field{Type: graphql.NewNonNull(stockAlertKindEnum)},
+			"product":    &graphql.Field{Type: graphql.NewNonNull(ProductType)},
+			"variant_id": &graphql.Field{Type: graphql.Int, Description: "Variação cujo estoque caiu; vazio quando é o do produto."},
+			"stock":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"threshold":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Limite de estoque baixo do produto."},
+		},
+	},
+)
+
+var stockMovementFilterInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "StockMovementFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"productId": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"variantId": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"kind":      &graphql.InputObjectFieldConfig{Type: stockMovementKindEnum},
+			"createdAt": &graphql.InputObjectFieldConfig{Type: pagination.TimeRangeInput},
+		},
+	},
+)
+
+// movementFilterFromArgs lê o argumento filter da query stockMovements.
+func movementFilterFromArgs(args map[string]interface{}) (StockMovementFilter, error) {
+	var filter StockMovementFilter
+	input, ok := args["filter"].(map[string]interface{})
+	if !ok {
+		return filter, nil
+	}
+	if v, ok := input["productId"].(int); ok {
+		id := uint(v)
+		filter.ProductID = &id
+	}
+	if v, ok := input["variantId"].(int); ok {
+		id := uint(v)
+		filter.VariantID = &id
+	}
+	filter.Kind, _ = input["kind"].(string)
+	createdAt, err := pagination.TimeRangeFromArgs(input["createdAt"])
+	if err != nil {
+		return filter, err
+	}
+	filter.CreatedAt = createdAt
+	return filter, nil
+}
+
+func stockQueryFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"stockMovements": &graphql.Field{
+			Type:        stockMovementConnectionType,
+			Description: "Histórico de estoque do agente, do lançamento mais recente para o mais antigo.",
+			Args: pagination.Args(graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"filter":  &graphql.ArgumentConfig{Type: stockMovementFilterInputType},
+			}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				page, err := pagination.PageFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				filter, err := movementFilterFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return service.GetStockMovements(uint(agentId), filter, page)
+			},
+		},
+	}
+}
+
+func stockMutationFields(service Service) graphql.Fields {
+	return graphql.Fields{
+		"adjustStock": &graphql.Field{
+			Type:        adjustStockPayloadType,
+			Description: "Lança uma entrada, saída ou contagem no estoque de um produto (ou de uma variação). Saídas maiores que o estoque são recusadas.",
+			Args: graphql.FieldConfigArgument{
+				"agentId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"productId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"variantId": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Obrigatório se o produto tiver variações."},
+				"kind":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(stockMovementKindEnum)},
+				"quantity":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"reason":    &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				identity, err := session.RequireAgent(p.Context, uint(agentId))
+				if err != nil {
+					return nil, err
+				}
+				productId, _ := p.Args["productId"].(int)
+				dto := AdjustStockDTO{ProductID: uint(productId), ActorID: &identity.UserID}
+				if v, ok := p.Args["variantId"].(int); ok {
+					variantID := uint(v)
+					dto.VariantID = &variantID
+				}
+				dto.Kind, _ = p.Args["kind"].(string)
+				dto.Quantity, _ = p.Args["quantity"].(int)
+				dto.Reason, _ = p.Args["reason"].(string)
+				movement, product, err := service.AdjustStock(uint(agentId), dto)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"movement": movement, "product": product}, nil
+			},
+		},
+	}
+}
+
+// stockAlertField avisa quando o estoque de um produto do agente chega ao limite de
+// estoque baixo ou zera.
+func stockAlertField(bus *events.Bus) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(stockAlertType),
+		Description: "Avisa quando o estoque de um produto do agente, ou de uma variação, chega ao limite de estoque baixo ou zera.",
+		Args: graphql.FieldConfigArgument{
+			"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+		Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+			agentId, _ := p.Args["agentId"].(int)
+			if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+				return nil, err
+			}
+			return bus.Subscribe(p.Context, events.TopicStock, uint(agentId)), nil
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if event, ok := p.Source.(events.Event); ok {
+				return event.Payload, nil
+			}
+			return nil, nil
+		},
+	}
+}
@@ -8,31 +8,41 @@
 package product
 
 import (
-	"fmt"
-	"os"
+	"log"
+	"strconv"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/gofiber/fiber/v2"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
 )
 
-// Endpoint para upload: POST /upload
-func UploadImageHandler(c *fiber.Ctx) error {
-	// Leitura das variáveis de ambiente (.env)
-	region := os.Getenv("AWS_REGION")
-	if region == "" {
-		region = "us-east-2"
-	}
-	bucket := os.Getenv("AWS_S3_BUCKET")
-	if bucket == "" {
-		bucket = "sabiosystem-produtos"
+// NewUploadImageHandler cria o endpoint de upload (POST /upload), que registra a imagem na
+// biblioteca de mídia do agente autenticado respeitando a cota de armazenamento do plano.
+// Deve vir depois de auth.FiberMiddleware. Administradores podem informar outro agente no
+// campo "agentId" do formulário.
+func NewUploadImageHandler(assets asset.Linker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return uploadImage(c, assets)
 	}
+}
 
-	fmt.Println("AWS_ACCESS_KEY_ID:", os.Getenv("AWS_ACCESS_KEY_ID"))
-	fmt.Println("AWS_SECRET_ACCESS_KEY:", os.Getenv("AWS_SECRET_ACCESS_KEY"))
-	fmt.Println("AWS_S3_BUCKET:", bucket)
-	fmt.Println("AWS_REGION:", region)
+func uploadImage(c *fiber.Ctx, assets asset.Linker) error {
+	identity, err := session.RequireAuth(c.UserContext())
+	if err != nil {
+		return writeError(c, fiber.StatusUnauthorized, err)
+	}
+	agentID := identity.AgentID
+	if value := c.FormValue("agentId"); value != "" {
+		requested, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || requested == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "agentId inválido")
+		}
+		if _, err := session.RequireAgent(c.UserContext(), uint(requested)); err != nil {
+			return writeError(c, fiber.StatusForbidden, err)
+		}
+		agentID = uint(requested)
+	}
 
 	// Recebe o arquivo do formulário
 	file, err := c.FormFile("file")
@@ -45,30 +55,34 @@ func UploadImageHandler(c *fiber.Ctx) error {
 	}
 	defer src.Close()
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(region),
+	image, err := assets.UploadImage(agentID, asset.UploadImageDTO{
+		Kind:       asset.KindProductImage,
+		Body:       src,
+		Size:       file.Size,
+		AltText:    c.FormValue("altText"),
+		UploadedBy: &identity.UserID,
 	})
-	if err != nil {
-		fmt.Printf("Erro AWS Session: %v\n", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Erro ao inicializar sessão AWS")
+	switch {
+	case apperror.HasCode(err, apperror.CodeQuotaExceeded):
+		return writeError(c, fiber.StatusForbidden, err)
+	case apperror.HasCode(err, apperror.CodeValidation):
+		return writeError(c, fiber.StatusBadRequest, err)
+	case err != nil:
+		log.Printf("Falha ao gravar a imagem do agente %d: %v", agentID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Falha ao gravar o arquivo")
 	}
-	uploader := s3manager.NewUploader(sess)
 
-	key := fmt.Sprintf("products/%s", file.Filename)
-
-	result, err := uploader.Upload(&s3manager.UploadInput{
-		Bucket:      aws.String(bucket),
-		Key:         aws.String(key),
-		Body:        src,
-		ContentType: aws.String(file.Header.Get("Content-Type")), // Corrige o Content-Type
-	})
+	// "url" continua apontando para a imagem exibida, para os clientes antigos. O asset
+	// fica sem uso até ser escolhido como imagem de um produto (imageAssetId).
+	return c.JSON(fiber.Map{"url": image.VariantURL("full"), "images": imageSetOf(image), "assetId": image.ID})
+}
 
-	if err != nil {
-		fmt.Printf("Erro upload S3: %v\n", err)
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Falha ao enviar para S3: %v", err))
+// writeError responde com o erro traduzido para o idioma do cliente.
+func writeError(c *fiber.Ctx, status int, err error) error {
+	appErr := apperror.From(err).Localize(apperror.ParseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage)))
+	body := fiber.Map{"error": appErr.Error(), "code": appErr.Code}
+	if fields, ok := appErr.Extensions()["fields"]; ok {
+		body["fields"] = fields
 	}
-
-	fmt.Printf("Upload realizado com sucesso: %s\n", result.Location)
-	// Retorna a URL pública
-	return c.JSON(fiber.Map{"url": result.Location})
+	return c.Status(status).JSON(body)
 }
@@ -0,0 +1,195 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/validation.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"fmt"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/database"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+)
+
+const (
+	maxNameLength        = 120
+	maxDescriptionLength = 5000
+	maxURLLength         = 2048
+	maxAltTextLength     = 250
+	maxTags              = 20
+	maxTagLength         = 40
+	maxSKULength         = 64
+	maxReasonLength      = 250
+)
+
+var tagRules = []validate.Rule[[]string]{
+	validate.MaxItems[string](maxTags),
+	validate.Each(validate.Required, validate.MaxLength(maxTagLength)),
+}
+
+func (dto CreateProductDTO) Validate() error {
+	checks := []validate.Check{
+		validate.Field("agentId", dto.AgentID, validate.NotZero),
+		validate.Field("categoryId", dto.CategoryID, validate.NotZero),
+		validate.Field("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Field("description", dto.Description, validate.MaxLength(maxDescriptionLength)),
+		validate.Field("price", dto.Price, validate.Money, validate.PositiveMoney),
+		validate.Field("imageUrl", dto.ImageURL, validate.MaxLength(maxURLLength), validate.URL),
+		validate.Field("imageAssetId", dto.ImageAssetID != nil, validate.ExclusiveWith("imageUrl", dto.ImageURL != "")),
+		validate.Field("position", dto.Position, validate.NonNegative[int]),
+		validate.Field("tags", dto.Tags, tagRules...),
+		validate.Field("lowStockThreshold", dto.LowStockThreshold, validate.NonNegative[int]),
+		validate.Field("sku", dto.SKU, validate.MaxLength(maxSKULength)),
+		validate.Field("barcode", dto.Barcode, validate.GTIN),
+	}
+	return validate.All(append(checks, externalRefsChecks(dto.ExternalRefs)...)...)
+}
+
+func (dto UpdateProductDTO) Validate() error {
+	checks := []validate.Check{
+		validate.Patch("categoryId", dto.CategoryID, validate.NotZero),
+		validate.Patch("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Patch("description", dto.Description, validate.MaxLength(maxDescriptionLength)),
+		validate.Patch("price", dto.Price, validate.Money, validate.PositiveMoney),
+		validate.Patch("imageUrl", dto.ImageURL, validate.MaxLength(maxURLLength), validate.URL),
+		validate.Field("imageAssetId", dto.ImageAssetID.Set, validate.ExclusiveWith("imageUrl", dto.ImageURL.Set)),
+		validate.Patch("position", dto.Position, validate.NonNegative[int]),
+		validate.Patch("tags", patch.Map(dto.Tags, func(tags database.StringArray) []string { return tags }), tagRules...),
+		validate.Patch("lowStockThreshold", dto.LowStockThreshold, validate.NonNegative[int]),
+		validate.Patch("sku", dto.SKU, validate.MaxLength(maxSKULength)),
+		validate.Patch("barcode", dto.Barcode, validate.GTIN),
+	}
+	if dto.ExternalRefs.Set {
+		checks = append(checks, externalRefsChecks(dto.ExternalRefs.Value)...)
+	}
+	return validate.All(checks...)
+}
+
+func (dto AddProductImageDTO) Validate() error {
+	checks := []validate.Check{
+		validate.Field("assetId", dto.AssetID != 0, validate.ExclusiveWith("file", dto.File != nil)),
+		validate.Field("altText", dto.AltText, validate.MaxLength(maxAltTextLength)),
+	}
+	if dto.File == nil {
+		checks = append(checks, validate.Field("assetId", dto.AssetID, validate.NotZero))
+	}
+	return validate.All(checks...)
+}
+
+func (dto AdjustStockDTO) Validate() error {
+	quantity := validate.Rule[int](validate.Positive[int])
+	if dto.Kind == MovementAdjustment {
+		quantity = validate.NonNegative[int] // A contagem pode ser zero.
+	}
+	return validate.All(
+		validate.Field("productId", dto.ProductID, validate.NotZero),
+		validate.Field("kind", dto.Kind, validate.OneOf(MovementIn, MovementOut, MovementAdjustment)),
+		validate.Field("quantity", dto.Quantity, quantity),
+		validate.Field("reason", dto.Reason, validate.MaxLength(maxReasonLength)),
+	)
+}
+
+func (dto CreateOptionGroupDTO) Validate() error {
+	checks := []validate.Check{
+		validate.Field("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Field("minSelect", dto.MinSelect, validate.NonNegative[int]),
+		validate.Field("maxSelect", dto.MaxSelect, validate.NonNegative[int]),
+		validate.Field("position", dto.Position, validate.NonNegative[int]),
+		validate.Field("options", dto.Options, validate.MaxItems[OptionDTO](maxOptionsPerGroup)),
+		selectionRange(OptionGroup{Multiple: dto.Multiple, MinSelect: dto.MinSelect, MaxSelect: dto.MaxSelect}),
+	}
+	for i, option := range dto.Options {
+		checks = append(checks, option.checks(fmt.Sprintf("options.%d.", i))...)
+	}
+	return validate.All(checks...)
+}
+
+func (dto UpdateOptionGroupDTO) Validate() error {
+	return validate.All(
+		validate.Patch("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Patch("minSelect", dto.MinSelect, validate.NonNegative[int]),
+		validate.Patch("maxSelect", dto.MaxSelect, validate.NonNegative[int]),
+		validate.Patch("position", dto.Position, validate.NonNegative[int]),
+	)
+}
+
+func (dto OptionDTO) Validate() error {
+	return validate.All(dto.checks("")...)
+}
+
+func (dto OptionDTO) checks(prefix string) []validate.Check {
+	return []validate.Check{
+		validate.Field(prefix+"name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Field(prefix+"priceDelta", dto.PriceDelta, optionalMoney),
+		validate.Field(prefix+"position", dto.Position, validate.NonNegative[int]),
+	}
+}
+
+func (dto UpdateOptionDTO) Validate() error {
+	return validate.All(
+		validate.Patch("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Patch("priceDelta", dto.PriceDelta, optionalMoney),
+		validate.Patch("position", dto.Position, validate.NonNegative[int]),
+	)
+}
+
+func (dto CreateVariantDTO) Validate() error {
+	return validate.All(
+		validate.Field("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Field("sku", dto.SKU, validate.MaxLength(maxSKULength)),
+		validate.Field("price", dto.Price, validate.Money, validate.PositiveMoney),
+		validate.Field("position", dto.Position, validate.NonNegative[int]),
+	)
+}
+
+func (dto UpdateVariantDTO) Validate() error {
+	return validate.All(
+		validate.Patch("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Patch("sku", dto.SKU, validate.MaxLength(maxSKULength)),
+		validate.Patch("price", dto.Price, validate.Money, validate.PositiveMoney),
+		validate.Patch("position", dto.Position, validate.NonNegative[int]),
+	)
+}
+
+// externalRefsChecks valida as referências externas, cada uma no campo do seu sistema.
+func externalRefsChecks(refs ExternalRefs) []validate.Check {
+	systems := refs.Systems()
+	checks := []validate.Check{validate.Field("externalRefs", systems, validate.MaxItems[string](maxExternalRefs))}
+	for _, system := range systems {
+		field := "externalRefs." + system
+		checks = append(checks,
+			validate.Field(field, system, validate.Required, validate.MaxLength(maxRefSystemLength)),
+			validate.Field(field, refs[system], validate.Required, validate.MaxLength(maxRefValueLength)),
+		)
+	}
+	return checks
+}
+
+// optionalMoney aceita o valor vazio (ou null na atualização), que vale zero na moeda
+// do produto.
+func optionalMoney(value money.Input) *validate.Failure {
+	if value.Amount == "" {
+		return nil
+	}
+	return validate.Money(value)
+}
+
+// selectionRange confere se os limites do grupo fazem sentido juntos. Roda também na
+// atualização, sobre o grupo já com as mudanças, porque min e max podem vir separados.
+func selectionRange(group OptionGroup) validate.Check {
+	return func() *apperror.FieldError {
+		if !group.Multiple && group.MaxSelect > 1 {
+			return &apperror.FieldError{Field: "maxSelect", Key: apperror.MsgSingleSelection}
+		}
+		if max := group.maxChoices(); max > 0 && group.MinSelect > max {
+			return &apperror.FieldError{Field: "minSelect", Key: apperror.MsgSelectionRange}
+		}
+		return nil
+	}
+}
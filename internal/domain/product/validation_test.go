@@ -0,0 +1,114 @@
+/*
+|------------------------------------------------
+| File: internal/domain/product/validation_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package product
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
+
+// invalidFields lista "campo:chave" de um erro VALIDATION, em ordem.
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperror.CodeValidation {
+		t.Fatalf("esperava um erro VALIDATION, veio %v", err)
+	}
+	fields := make([]string, len(appErr.Fields))
+	for i, field := range appErr.Fields {
+		fields[i] = field.Field + ":" + field.Key
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestCreateProductDTOValidate(t *testing.T) {
+	valid := CreateProductDTO{
+		AgentID:    1,
+		CategoryID: 2,
+		Name:       "Pizza",
+		Price:      money.Input{Amount: "49.90", Currency: "BRL"},
+		Barcode:    "7891000315507",
+	}
+	cases := []struct {
+		name string
+		dto  func(CreateProductDTO) CreateProductDTO
+		want []string
+	}{
+		{"válido", func(d CreateProductDTO) CreateProductDTO { return d }, nil},
+		{"vários campos inválidos juntos", func(d CreateProductDTO) CreateProductDTO {
+			d.Name = ""
+			d.Price = money.Input{Amount: "0", Currency: "BRL"}
+			d.Barcode = "7891000315508"
+			d.Position = -1
+			return d
+		}, []string{
+			"barcode:" + apperror.MsgInvalidBarcode,
+			"name:" + apperror.MsgRequired,
+			"position:" + apperror.MsgNonNegative,
+			"price:" + apperror.MsgPositiveAmount,
+		}},
+		{"imagem por URL e da biblioteca ao mesmo tempo", func(d CreateProductDTO) CreateProductDTO {
+			assetID := uint(3)
+			d.ImageURL, d.ImageAssetID = "https://cdn.loja.com/a.jpg", &assetID
+			return d
+		}, []string{"imageAssetId:" + apperror.MsgExclusiveWith}},
+		{"referência externa vazia", func(d CreateProductDTO) CreateProductDTO {
+			d.ExternalRefs = ExternalRefs{"ifood": ""}
+			return d
+		}, []string{"externalRefs.ifood:" + apperror.MsgRequired}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := invalidFields(t, tc.dto(valid).Validate())
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("esperava %v, veio %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateProductDTOValidate(t *testing.T) {
+	null := func() patch.Field[string] { return patch.Field[string]{Set: true, Null: true} }
+	cases := []struct {
+		name string
+		dto  UpdateProductDTO
+		want []string
+	}{
+		{"nada enviado", UpdateProductDTO{Version: 1}, nil},
+		{"campos ausentes não são validados", UpdateProductDTO{Position: patch.Of(3)}, nil},
+		{"null limpa campos opcionais", UpdateProductDTO{Description: null(), SKU: null(), Barcode: null()}, nil},
+		{"null num campo obrigatório", UpdateProductDTO{Name: null()}, []string{"name:" + apperror.MsgRequired}},
+		{"vários inválidos", UpdateProductDTO{
+			Name:              patch.Of(strings.Repeat("a", maxNameLength+1)),
+			ImageURL:          patch.Of("ftp://loja.com/a.png"),
+			LowStockThreshold: patch.Of(-1),
+		}, []string{
+			"imageUrl:" + apperror.MsgInvalidURL,
+			"lowStockThreshold:" + apperror.MsgNonNegative,
+			"name:" + apperror.MsgMaxLength,
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := invalidFields(t, tc.dto.Validate())
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("esperava %v, veio %v", tc.want, got)
+			}
+		})
+	}
+}
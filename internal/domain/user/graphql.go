@@ -7,7 +7,12 @@
 */
 package user
 
-import "github.com/graphql-go/graphql"
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
 
 // UserType é o tipo GraphQL para a entidade User, agora exportado.
 var UserType = graphql.NewObject(
@@ -18,22 +23,66 @@ var UserType = graphql.NewObject(
 			"agent_id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
 			"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 			"email":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"role":       &graphql.Field{Type: graphql.String},
+			"version":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
 			"created_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 			"updated_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"deleted_at": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if m, ok := p.Source.(User); ok && m.DeletedAt.Valid {
+						return m.DeletedAt.Time, nil
+					}
+					return nil, nil
+				},
+			},
 		},
 	},
 )
 
-// PaginatedUsersType é o tipo GraphQL para a resposta paginada, agora exportado.
-var PaginatedUsersType = graphql.NewObject(
-	graphql.ObjectConfig{
-		Name: "PaginatedUsers",
-		Fields: graphql.Fields{
-			"data":        &graphql.Field{Type: graphql.NewList(UserType)},
-			"total":       &graphql.Field{Type: graphql.Int},
-			"page":        &graphql.Field{Type: graphql.Int},
-			"per_page":    &graphql.Field{Type: graphql.Int},
-			"total_pages": &graphql.Field{Type: graphql.Int},
+var userFilterInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UserFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"text":      &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Procura no nome e no email."},
+			"role":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"createdAt": &graphql.InputObjectFieldConfig{Type: pagination.TimeRangeInput},
+		},
+	},
+)
+
+var userSortInputType = pagination.SortInput("User", graphql.EnumValueConfigMap{
+	"NAME":       &graphql.EnumValueConfig{Value: "name"},
+	"EMAIL":      &graphql.EnumValueConfig{Value: "email"},
+	"CREATED_AT": &graphql.EnumValueConfig{Value: "created_at"},
+})
+
+// filterFromArgs lê o argumento filter da query users.
+func filterFromArgs(args map[string]interface{}) (UserFilter, error) {
+	var filter UserFilter
+	input, ok := args["filter"].(map[string]interface{})
+	if !ok {
+		return filter, nil
+	}
+	filter.Text, _ = input["text"].(string)
+	filter.Role, _ = input["role"].(string)
+	createdAt, err := pagination.TimeRangeFromArgs(input["createdAt"])
+	if err != nil {
+		return filter, err
+	}
+	filter.CreatedAt = createdAt
+	return filter, nil
+}
+
+var usersConnectionType = pagination.ConnectionType("User", UserType)
+
+// updateUserInputType tem todos os campos opcionais: o que não for enviado não muda.
+var updateUserInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UpdateUserInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"email": &graphql.InputObjectFieldConfig{Type: graphql.String},
 		},
 	},
 )
@@ -41,16 +90,24 @@ var PaginatedUsersType = graphql.NewObject(
 func GetQueryFields(service Service) graphql.Fields {
 	return graphql.Fields{
 		"users": &graphql.Field{
-			Type:        PaginatedUsersType,
+			Type:        usersConnectionType,
 			Description: "Obtém usuários de um agente específico.",
-			Args: graphql.FieldConfigArgument{
+			Args: pagination.Args(graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"page":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
-			},
+				"filter":  &graphql.ArgumentConfig{Type: userFilterInputType},
+				"sort":    &graphql.ArgumentConfig{Type: userSortInputType},
+			}),
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				agentId, _ := p.Args["agentId"].(int)
-				page, _ := p.Args["page"].(int)
-				return service.GetAllUsers(uint(agentId), page)
+				page, err := pagination.PageFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				filter, err := filterFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return service.GetAllUsers(uint(agentId), filter, pagination.SortFromArgs(p.Args), page)
 			},
 		},
 		"user": &graphql.Field{
@@ -67,8 +124,9 @@ func GetQueryFields(service Service) graphql.Fields {
 			},
 		},
 		"searchUsers": &graphql.Field{
-			Type:        graphql.NewList(UserType),
-			Description: "Busca usuários por nome/email, dentro de um agente.",
+			Type:              graphql.NewList(UserType),
+			Description:       "Busca usuários por nome/email, dentro de um agente.",
+			DeprecationReason: "Use users com filter.text.",
 			Args: graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
 				"name":    &graphql.ArgumentConfig{Type: graphql.String},
@@ -109,19 +167,21 @@ func GetMutationFields(service Service) graphql.Fields {
 		*/
 		"updateUser": &graphql.Field{
 			Type:        UserType,
-			Description: "Atualiza um usuário de um agente.",
+			Description: "Atualiza parcialmente um usuário de um agente.",
 			Args: graphql.FieldConfigArgument{
 				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
 				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
-				"name":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-				"email":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"version": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int), Description: "Versão lida pelo cliente."},
+				"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateUserInputType)},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				agentId, _ := p.Args["agentId"].(int)
 				id, _ := p.Args["id"].(int)
+				input := patch.InputFromArgs(p, "input")
 				dto := UpdateUserDTO{
-					Name:  p.Args["name"].(string),
-					Email: p.Args["email"].(string),
+					Version: uint(p.Args["version"].(int)),
+					Name:    patch.Get[string](input, "name"),
+					Email:   patch.Get[string](input, "email"),
 				}
 				return service.UpdateUser(uint(agentId), uint(id), dto)
 			},
@@ -146,5 +206,21 @@ func GetMutationFields(service Service) graphql.Fields {
 				return map[string]interface{}{"deletedId": id, "success": true}, nil
 			},
 		},
+		"restoreUser": &graphql.Field{
+			Type:        UserType,
+			Description: "Restaura um usuário excluído de um agente.",
+			Args: graphql.FieldConfigArgument{
+				"agentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				agentId, _ := p.Args["agentId"].(int)
+				id, _ := p.Args["id"].(int)
+				if _, err := session.RequireAgent(p.Context, uint(agentId)); err != nil {
+					return nil, err
+				}
+				return service.RestoreUser(uint(agentId), uint(id))
+			},
+		},
 	}
 }
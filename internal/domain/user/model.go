@@ -10,19 +10,24 @@ package user
 import (
 	"time"
 
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // User representa a entidade no banco de dados.
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	AgentID   uint      `gorm:"not null" json:"agent_id"` // <-- MUDANÇA: Adicionado AgentID
-	Name      string    `gorm:"not null" json:"name"`
-	Email     string    `gorm:"not null" json:"email"` // A unicidade do email agora deve ser por agente.
-	Password  string    `gorm:"not null" json:"-"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	AgentID   uint           `gorm:"not null" json:"agent_id"`
+	Name      string         `gorm:"not null" json:"name"`
+	Email     string         `gorm:"not null" json:"email"` // A unicidade do email agora deve ser por agente.
+	Password  string         `gorm:"not null" json:"-"`
+	Role      string         `gorm:"not null;default:member" json:"role"` // "admin" para administradores da plataforma.
+	Version   uint           `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 }
 
 // Antes de salvar, cria um hash da senha.
@@ -39,23 +44,23 @@ func (u *User) BeforeSave(tx *gorm.DB) (err error) {
 
 // CreateUserDTO é o DTO para a criação de um usuário.
 type CreateUserDTO struct {
-	AgentID  uint   `json:"agent_id"` // <-- MUDANÇA: Adicionado AgentID
+	AgentID  uint   `json:"agent_id"`
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
 // UpdateUserDTO é o DTO para a atualização de um usuário.
+// Campos não enviados ficam como estão.
 type UpdateUserDTO struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Version uint                `json:"version"`
+	Name    patch.Field[string] `json:"name"`
+	Email   patch.Field[string] `json:"email"`
 }
 
-// PaginatedUsers é a estrutura de resposta para a lista paginada de usuários.
-type PaginatedUsers struct {
-	Data       []User `json:"data"`
-	Total      int64  `json:"total"`
-	Page       int    `json:"page"`
-	PerPage    int    `json:"per_page"`
-	TotalPages int    `json:"total_pages"`
+// UserFilter restringe a lista de usuários. Campos vazios não filtram.
+type UserFilter struct {
+	Text      string // Procura no nome e no email.
+	Role      string
+	CreatedAt pagination.TimeRange
 }
@@ -7,15 +7,27 @@
 */
 package user
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"gorm.io/gorm"
+)
 
 type Repository interface {
-	FindAll(agentID uint, page, perPage int) ([]User, int64, error)
+	FindAll(agentID uint, filter UserFilter, sort pagination.Sort, page pagination.Page) ([]User, error)
+	Count(agentID uint, filter UserFilter) (int64, error)
 	FindByID(agentID, id uint) (User, error)
 	Search(agentID uint, name, email string) ([]User, error)
-	Create(user User) (User, error)
-	Update(user User) (User, error)
+	Create(user User, quota plan.QuotaGuard) (User, error)
+	Update(agentID, id, version uint, changes map[string]interface{}) (User, error)
 	Delete(agentID, id uint) error
+	Restore(agentID, id uint, quota plan.QuotaGuard) error
+	Purge(before time.Time) (int64, error)
+	PurgeAgents(agentIDs []uint) error
+	FindByAgentIDs(agentIDs []uint) ([]User, error)
 }
 
 type repository struct {
@@ -26,17 +38,31 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) FindAll(agentID uint, page, perPage int) ([]User, int64, error) {
+func (r *repository) FindAll(agentID uint, filter UserFilter, sort pagination.Sort, page pagination.Page) ([]User, error) {
 	var users []User
+	sort = sort.Allowed("name", "email", "created_at")
+	err := r.db.Where("agent_id = ?", agentID).Scopes(filterScope(filter), page.Scope(sort)).Find(&users).Error
+	return users, err
+}
+
+func (r *repository) Count(agentID uint, filter UserFilter) (int64, error) {
 	var total int64
-	// <-- MUDANÇA: Adicionado Where("agent_id = ?", agentID)
-	if err := r.db.Model(&User{}).Where("agent_id = ?", agentID).Count(&total).Error; err != nil {
-		return nil, 0, err
+	err := r.db.Model(&User{}).Where("agent_id = ?", agentID).Scopes(filterScope(filter)).Count(&total).Error
+	return total, err
+}
+
+// filterScope traduz o filtro em condições parametrizadas.
+func filterScope(filter UserFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Text != "" {
+			pattern := pagination.ContainsPattern(filter.Text)
+			db = db.Where("(name ILIKE ? OR email ILIKE ?)", pattern, pattern)
+		}
+		if filter.Role != "" {
+			db = db.Where("role = ?", filter.Role)
+		}
+		return db.Scopes(filter.CreatedAt.Scope("created_at"))
 	}
-	offset := (page - 1) * perPage
-	// <-- MUDANÇA: Adicionado Where("agent_id = ?", agentID)
-	err := r.db.Where("agent_id = ?", agentID).Limit(perPage).Offset(offset).Order("id asc").Find(&users).Error
-	return users, total, err
 }
 
 func (r *repository) FindByID(agentID, id uint) (User, error) {
@@ -63,17 +89,68 @@ func (r *repository) Search(agentID uint, name, email string) ([]User, error) {
 	return users, err
 }
 
-func (r *repository) Create(user User) (User, error) {
-	err := r.db.Create(&user).Error
+func (r *repository) Create(user User, quota plan.QuotaGuard) (User, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := quota(tx); err != nil {
+			return err
+		}
+		return tx.Create(&user).Error
+	})
 	return user, err
 }
 
-func (r *repository) Update(user User) (User, error) {
-	err := r.db.Save(&user).Error
-	return user, err
+// Update altera só as colunas em changes, e somente se a versão no banco ainda for version,
+// incrementando-a. Se outra atualização chegou antes, retorna apperror.ErrVersionMismatch.
+func (r *repository) Update(agentID, id, version uint, changes map[string]interface{}) (User, error) {
+	changes["version"] = gorm.Expr("version + 1")
+	result := r.db.Model(&User{}).Where("agent_id = ? AND id = ? AND version = ?", agentID, id, version).Updates(changes)
+	if result.Error != nil {
+		return User{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return User{}, apperror.ErrVersionMismatch
+	}
+	return r.FindByID(agentID, id)
 }
 
 func (r *repository) Delete(agentID, id uint) error {
 	// <-- MUDANÇA: Adicionado Where("agent_id = ?", agentID)
 	return r.db.Where("agent_id = ?", agentID).Delete(&User{}, id).Error
 }
+
+// Restore tira um usuário da lixeira.
+func (r *repository) Restore(agentID, id uint, quota plan.QuotaGuard) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := quota(tx); err != nil {
+			return err
+		}
+		result := tx.Unscoped().Model(&User{}).
+			Where("agent_id = ? AND id = ? AND deleted_at IS NOT NULL", agentID, id).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Purge remove definitivamente os usuários excluídos antes de before.
+func (r *repository) Purge(before time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&User{})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeAgents remove definitivamente todos os usuários dos agentes.
+func (r *repository) PurgeAgents(agentIDs []uint) error {
+	return r.db.Unscoped().Where("agent_id IN ?", agentIDs).Delete(&User{}).Error
+}
+
+// FindByAgentIDs busca os usuários de vários agentes numa única consulta.
+func (r *repository) FindByAgentIDs(agentIDs []uint) ([]User, error) {
+	var users []User
+	err := r.db.Where("agent_id IN ?", agentIDs).Order("id asc").Find(&users).Error
+	return users, err
+}
@@ -7,40 +7,56 @@
 */
 package user
 
-import "math"
+import (
+	"errors"
+	"time"
 
-const PageSize = 8
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
 
 type Service interface {
-	GetAllUsers(agentID uint, page int) (PaginatedUsers, error)
+	GetAllUsers(agentID uint, filter UserFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[User], error)
 	GetUserByID(agentID, id uint) (User, error)
 	SearchUsers(agentID uint, name, email string) ([]User, error)
 	CreateUser(dto CreateUserDTO) (User, error)
 	UpdateUser(agentID, id uint, dto UpdateUserDTO) (User, error)
 	DeleteUser(agentID, id uint) error
+	RestoreUser(agentID, id uint) (User, error)
+	PurgeTrash(before time.Time) (int64, error)
+	PurgeAgents(agentIDs []uint) error
+	GetUsersByAgentIDs(agentIDs []uint) ([]User, error)
 }
 
 type service struct {
-	repo Repository
+	repo  Repository
+	quota plan.QuotaChecker
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, quota plan.QuotaChecker) Service {
+	return &service{repo: repo, quota: quota}
 }
 
-func (s *service) GetAllUsers(agentID uint, page int) (PaginatedUsers, error) {
-	users, total, err := s.repo.FindAll(agentID, page, PageSize)
+func (s *service) GetAllUsers(agentID uint, filter UserFilter, sort pagination.Sort, page pagination.Page) (pagination.Connection[User], error) {
+	users, err := s.repo.FindAll(agentID, filter, sort, page)
 	if err != nil {
-		return PaginatedUsers{}, err
+		return pagination.Connection[User]{}, err
 	}
-	totalPages := int(math.Ceil(float64(total) / float64(PageSize)))
-	return PaginatedUsers{
-		Data:       users,
-		Total:      total,
-		Page:       page,
-		PerPage:    PageSize,
-		TotalPages: totalPages,
-	}, nil
+	conn := pagination.NewConnection(users, page, func(item User) pagination.Cursor {
+		cursor := sort.Cursor(item.ID)
+		switch sort.Column {
+		case "name":
+			cursor.Value = item.Name
+		case "email":
+			cursor.Value = item.Email
+		case "created_at":
+			cursor.Value = item.CreatedAt
+		}
+		return cursor
+	})
+	return conn.WithCount(func() (int64, error) { return s.repo.Count(agentID, filter) }), nil
 }
 
 func (s *service) GetUserByID(agentID, id uint) (User, error) {
@@ -52,23 +68,55 @@ func (s *service) SearchUsers(agentID uint, name, email string) ([]User, error)
 }
 
 func (s *service) CreateUser(dto CreateUserDTO) (User, error) {
+	if err := dto.Validate(); err != nil {
+		return User{}, err
+	}
 	user := User{
 		AgentID:  dto.AgentID, // <-- MUDANÇA
 		Name:     dto.Name,
 		Email:    dto.Email,
 		Password: dto.Password,
 	}
-	return s.repo.Create(user)
+	return s.repo.Create(user, s.quota.Reserve(dto.AgentID, plan.ResourceUsers, 1))
 }
 
 func (s *service) UpdateUser(agentID, id uint, dto UpdateUserDTO) (User, error) {
+	if err := dto.Validate(); err != nil {
+		return User{}, err
+	}
 	userToUpdate, err := s.repo.FindByID(agentID, id)
 	if err != nil {
 		return User{}, err
 	}
-	userToUpdate.Name = dto.Name
-	userToUpdate.Email = dto.Email
-	return s.repo.Update(userToUpdate)
+	if userToUpdate.Version != dto.Version {
+		return User{}, apperror.Conflict(userToUpdate)
+	}
+
+	changes := patch.Changes{}
+	if err := patch.Required(changes, "name", dto.Name); err != nil {
+		return User{}, err
+	}
+	if err := patch.Required(changes, "email", dto.Email); err != nil {
+		return User{}, err
+	}
+	if len(changes) == 0 {
+		return userToUpdate, nil
+	}
+
+	updated, err := s.repo.Update(agentID, id, dto.Version, changes)
+	if errors.Is(err, apperror.ErrVersionMismatch) {
+		return s.conflict(agentID, id)
+	}
+	return updated, err
+}
+
+// conflict relê o usuário para devolver ao cliente o estado que venceu a disputa.
+func (s *service) conflict(agentID, id uint) (User, error) {
+	current, err := s.repo.FindByID(agentID, id)
+	if err != nil {
+		return User{}, err
+	}
+	return User{}, apperror.Conflict(current)
 }
 
 func (s *service) DeleteUser(agentID, id uint) error {
@@ -78,3 +126,23 @@ func (s *service) DeleteUser(agentID, id uint) error {
 	}
 	return s.repo.Delete(agentID, id)
 }
+
+func (s *service) RestoreUser(agentID, id uint) (User, error) {
+	if err := s.repo.Restore(agentID, id, s.quota.Reserve(agentID, plan.ResourceUsers, 1)); err != nil {
+		return User{}, err
+	}
+	return s.repo.FindByID(agentID, id)
+}
+
+func (s *service) PurgeTrash(before time.Time) (int64, error) {
+	return s.repo.Purge(before)
+}
+
+// PurgeAgents apaga todos os usuários dos agentes removidos.
+func (s *service) PurgeAgents(agentIDs []uint) error {
+	return s.repo.PurgeAgents(agentIDs)
+}
+
+func (s *service) GetUsersByAgentIDs(agentIDs []uint) ([]User, error) {
+	return s.repo.FindByAgentIDs(agentIDs)
+}
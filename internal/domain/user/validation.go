@@ -0,0 +1,33 @@
+/*
+|------------------------------------------------
+| File: internal/domain/user/validation.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package user
+
+import "github.com/raimundocoelho-ti/sabiosystem-api/internal/validate"
+
+const (
+	maxNameLength     = 120
+	maxEmailLength    = 254
+	minPasswordLength = 8
+	maxPasswordLength = 72 // Limite do bcrypt; o excedente seria ignorado.
+)
+
+func (dto CreateUserDTO) Validate() error {
+	return validate.All(
+		validate.Field("agentId", dto.AgentID, validate.NotZero),
+		validate.Field("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Field("email", dto.Email, validate.Required, validate.MaxLength(maxEmailLength), validate.Email),
+		validate.Field("password", dto.Password, validate.MinLength(minPasswordLength), validate.MaxLength(maxPasswordLength)),
+	)
+}
+
+func (dto UpdateUserDTO) Validate() error {
+	return validate.All(
+		validate.Patch("name", dto.Name, validate.Required, validate.MaxLength(maxNameLength)),
+		validate.Patch("email", dto.Email, validate.Required, validate.MaxLength(maxEmailLength), validate.Email),
+	)
+}
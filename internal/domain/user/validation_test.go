@@ -0,0 +1,92 @@
+/*
+|------------------------------------------------
+| File: internal/domain/user/validation_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package user
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
+
+// invalidFields lista "campo:chave" de um erro VALIDATION, em ordem.
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperror.CodeValidation {
+		t.Fatalf("esperava um erro VALIDATION, veio %v", err)
+	}
+	fields := make([]string, len(appErr.Fields))
+	for i, field := range appErr.Fields {
+		fields[i] = field.Field + ":" + field.Key
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestCreateUserDTOValidate(t *testing.T) {
+	valid := CreateUserDTO{AgentID: 1, Name: "Ana", Email: "ana@loja.com", Password: "segredo123"}
+	cases := []struct {
+		name string
+		dto  func(CreateUserDTO) CreateUserDTO
+		want []string
+	}{
+		{"válido", func(d CreateUserDTO) CreateUserDTO { return d }, nil},
+		{"todos os campos inválidos juntos", func(d CreateUserDTO) CreateUserDTO {
+			return CreateUserDTO{Email: "ana", Password: "curta"}
+		}, []string{
+			"agentId:" + apperror.MsgRequired,
+			"email:" + apperror.MsgInvalidEmail,
+			"name:" + apperror.MsgRequired,
+			"password:" + apperror.MsgMinLength,
+		}},
+		{"senha acima do limite do bcrypt", func(d CreateUserDTO) CreateUserDTO {
+			d.Password = strings.Repeat("a", maxPasswordLength+1)
+			return d
+		}, []string{"password:" + apperror.MsgMaxLength}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := invalidFields(t, tc.dto(valid).Validate())
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("esperava %v, veio %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateUserDTOValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		dto  UpdateUserDTO
+		want []string
+	}{
+		{"nada enviado", UpdateUserDTO{}, nil},
+		{"só o nome", UpdateUserDTO{Name: patch.Of("Ana")}, nil},
+		{"null num campo obrigatório", UpdateUserDTO{Name: patch.Field[string]{Set: true, Null: true}}, []string{"name:" + apperror.MsgRequired}},
+		{"email inválido e nome ausente", UpdateUserDTO{Email: patch.Of("ana@")}, []string{"email:" + apperror.MsgInvalidEmail}},
+		{"os dois inválidos", UpdateUserDTO{Name: patch.Of(" "), Email: patch.Field[string]{Set: true, Null: true}}, []string{
+			"email:" + apperror.MsgRequired,
+			"name:" + apperror.MsgRequired,
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := invalidFields(t, tc.dto.Validate())
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("esperava %v, veio %v", tc.want, got)
+			}
+		})
+	}
+}
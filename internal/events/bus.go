@@ -0,0 +1,97 @@
+/*
+|------------------------------------------------
+| File: internal/events/bus.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Tópicos publicados pelos serviços.
+const (
+	TopicProduct  = "product"
+	TopicCategory = "category"
+	TopicStock    = "stock" // Avisos de estoque baixo; Action é o tipo do aviso.
+)
+
+// Ações de uma mudança.
+const (
+	ActionCreated  = "created"
+	ActionUpdated  = "updated"
+	ActionDeleted  = "deleted"
+	ActionRestored = "restored"
+)
+
+// bufferSize é quantos eventos um inscrito pode acumular antes de começar a perdê-los.
+const bufferSize = 32
+
+// Event é uma mudança num registro de um agente. Payload é o registro depois da mudança
+// (ou antes, numa exclusão).
+type Event struct {
+	Topic   string
+	Action  string
+	AgentID uint
+	Payload interface{}
+}
+
+// Publisher é implementado por quem distribui os eventos dos serviços.
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Bus distribui os eventos dentro do processo.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	topic   string
+	agentID uint
+	ch      chan interface{}
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: map[*subscriber]struct{}{}}
+}
+
+// Publish entrega o evento aos inscritos no tópico e no agente. Um inscrito lento perde o
+// evento em vez de travar o serviço que publicou.
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if sub.topic != event.Topic || sub.agentID != event.AgentID {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			log.Printf("Evento %s/%s do agente %d descartado: inscrito sem vazão", event.Topic, event.Action, event.AgentID)
+		}
+	}
+}
+
+// Subscribe recebe os eventos do tópico e do agente até ctx terminar, quando o canal é
+// fechado. O canal é chan interface{} porque vai direto para as subscriptions do graphql-go.
+func (b *Bus) Subscribe(ctx context.Context, topic string, agentID uint) chan interface{} {
+	sub := &subscriber{topic: topic, agentID: agentID, ch: make(chan interface{}, bufferSize)}
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		b.mu.Unlock()
+		close(sub.ch)
+	}()
+	return sub.ch
+}
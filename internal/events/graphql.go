@@ -0,0 +1,48 @@
+/*
+|------------------------------------------------
+| File: internal/events/graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package events
+
+import "github.com/graphql-go/graphql"
+
+var changeActionEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ChangeAction",
+	Values: graphql.EnumValueConfigMap{
+		"CREATED":  &graphql.EnumValueConfig{Value: ActionCreated},
+		"UPDATED":  &graphql.EnumValueConfig{Value: ActionUpdated},
+		"DELETED":  &graphql.EnumValueConfig{Value: ActionDeleted},
+		"RESTORED": &graphql.EnumValueConfig{Value: ActionRestored},
+	},
+})
+
+// ChangeEventType cria o tipo <Name>ChangeEvent entregue pelas subscriptions, com a ação e
+// o registro (campo field, do tipo itemType).
+func ChangeEventType(name, field string, itemType graphql.Output) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "ChangeEvent",
+		Fields: graphql.Fields{
+			"action": &graphql.Field{
+				Type: graphql.NewNonNull(changeActionEnum),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if event, ok := p.Source.(Event); ok {
+						return event.Action, nil
+					}
+					return nil, nil
+				},
+			},
+			field: &graphql.Field{
+				Type: graphql.NewNonNull(itemType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if event, ok := p.Source.(Event); ok {
+						return event.Payload, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+}
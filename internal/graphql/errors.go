@@ -0,0 +1,117 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/errors.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limita o ID aceito do cliente, já que ele vai para o log.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// RequestMiddleware identifica a requisição, reaproveitando o X-Request-ID enviado pelo
+// cliente ou gerando um, e guarda no contexto o idioma pedido em Accept-Language.
+func RequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+		ctx = apperror.WithLanguage(ctx, apperror.ParseAcceptLanguage(r.Header.Get("Accept-Language")))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func requestIDFrom(ctx context.Context) string {
+	if ctx != nil {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// handleErrors envolve todos os resolvers do schema, inclusive os dos relacionamentos e
+// das conexões, para que todo erro chegue ao cliente como um apperror traduzido.
+func handleErrors(schema graphql.Schema) {
+	for name, namedType := range schema.TypeMap() {
+		object, ok := namedType.(*graphql.Object)
+		if !ok || strings.HasPrefix(name, "__") {
+			continue
+		}
+		for _, field := range object.Fields() {
+			if field.Resolve != nil {
+				field.Resolve = withErrorHandling(field.Resolve)
+			}
+			if field.Subscribe != nil {
+				field.Subscribe = withErrorHandling(field.Subscribe)
+			}
+		}
+	}
+}
+
+func withErrorHandling(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		result, err := resolve(p)
+		if err != nil {
+			return nil, clientError(p.Context, err)
+		}
+		// Resultados adiados (batchers) só produzem o erro quando executados.
+		if thunk, ok := result.(func() (interface{}, error)); ok {
+			return func() (interface{}, error) {
+				value, err := thunk()
+				if err != nil {
+					return nil, clientError(p.Context, err)
+				}
+				return value, nil
+			}, nil
+		}
+		return result, nil
+	}
+}
+
+// clientError traduz o erro para o idioma da requisição. Erros internos são registrados
+// no log com o ID da requisição e chegam ao cliente só com esse ID.
+func clientError(ctx context.Context, err error) error {
+	appErr := apperror.From(err)
+	if appErr.Code == apperror.CodeInternal {
+		requestID := requestIDFrom(ctx)
+		log.Printf("[request %s] erro interno: %v", requestID, err)
+		appErr = apperror.New(apperror.CodeInternal, apperror.MsgInternal, requestID).WithCause(err)
+		appErr.Details = map[string]interface{}{"requestId": requestID}
+	}
+	return appErr.Localize(apperror.LanguageFrom(ctx))
+}
+
+// formatError formata um erro que não passou pela execução, mantendo as extensions.
+func formatError(err error) gqlerrors.FormattedError {
+	return gqlerrors.FormatError(gqlerrors.NewError(err.Error(), nil, "", nil, nil, err))
+}
@@ -0,0 +1,318 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/limits.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/handler"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+)
+
+// Limits são os limites aplicados a cada operação antes da execução.
+type Limits struct {
+	MaxDepth        int  // Níveis de campos aninhados.
+	MaxCost         int  // Orçamento de custo por requisição.
+	DefaultListSize int  // Multiplicador das listas sem first/last.
+	Introspection   bool // Permite __schema e __type.
+}
+
+// fieldCosts sobrescreve o custo padrão de campos mais caros que uma leitura simples.
+var fieldCosts = map[string]int{
+	"RootQuery.searchProducts":                 10,
+	"RootMutation.exportAgent":                 50,
+	"RootMutation.importAgent":                 50,
+	"ProductConnection.totalCount":             5,
+	"CategoryConnection.totalCount":            5,
+	"UserConnection.totalCount":                5,
+	"AgentConnection.totalCount":               5,
+	"ProductSearchResultConnection.totalCount": 10,
+}
+
+// LimitsMiddleware analisa a operação antes de executá-la e recusa as que passam da
+// profundidade ou do custo máximo, ou que usam introspecção quando ela está desligada.
+func LimitsMiddleware(schema *graphql.Schema, limits Limits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opts := requestOptions(r)
+			if appErr := checkLimits(schema, limits, opts); appErr != nil {
+				writeError(w, http.StatusBadRequest, appErr.Localize(apperror.LanguageFrom(r.Context())))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkLimits analisa a operação sem executá-la. Erros de sintaxe não são reportados aqui,
+// e sim pela execução.
+func checkLimits(schema *graphql.Schema, limits Limits, opts *handler.RequestOptions) *apperror.Error {
+	document, err := parser.Parse(parser.ParseParams{Source: opts.Query})
+	if err != nil || opts.Query == "" {
+		return nil
+	}
+	analyzer := &queryAnalyzer{schema: schema, limits: limits, variables: opts.Variables, fragments: map[string]*ast.FragmentDefinition{}}
+	return analyzer.check(document, opts.OperationName)
+}
+
+// requestOptions lê query, variáveis e operação, devolvendo o corpo intacto ao handler.
+func requestOptions(r *http.Request) *handler.RequestOptions {
+	if r.Body == nil {
+		return handler.NewRequestOptions(r)
+	}
+	body, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	opts := handler.NewRequestOptions(r)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return opts
+}
+
+// writeError responde sem executar a operação, no mesmo formato de erro do GraphQL.
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []gqlerrors.FormattedError{formatError(err)},
+	})
+}
+
+type queryAnalyzer struct {
+	schema    *graphql.Schema
+	limits    Limits
+	variables map[string]interface{}
+	defaults  map[string]ast.Value // Valores padrão das variáveis da operação.
+	fragments map[string]*ast.FragmentDefinition
+}
+
+func (a *queryAnalyzer) check(document *ast.Document, operationName string) *apperror.Error {
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch def := definition.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		}
+	}
+	if operation == nil {
+		return nil
+	}
+	a.defaults = map[string]ast.Value{}
+	for _, definition := range operation.VariableDefinitions {
+		if definition.DefaultValue != nil {
+			a.defaults[definition.Variable.Name.Value] = definition.DefaultValue
+		}
+	}
+
+	root := a.schema.QueryType()
+	switch operation.Operation {
+	case ast.OperationTypeMutation:
+		root = a.schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = a.schema.SubscriptionType()
+	}
+	cost, depth, err := a.selectionSet(root, operation.SelectionSet, 1, map[string]bool{})
+	if err != nil {
+		return err
+	}
+	if depth > a.limits.MaxDepth {
+		appErr := apperror.New(apperror.CodeQueryTooComplex, apperror.MsgQueryTooDeep, depth, a.limits.MaxDepth)
+		appErr.Details = map[string]interface{}{"depth": depth, "maxDepth": a.limits.MaxDepth}
+		return appErr
+	}
+	if cost > a.limits.MaxCost {
+		appErr := apperror.New(apperror.CodeQueryTooComplex, apperror.MsgQueryTooCostly, cost, a.limits.MaxCost)
+		appErr.Details = map[string]interface{}{"cost": cost, "maxCost": a.limits.MaxCost}
+		return appErr
+	}
+	return nil
+}
+
+// selectionSet soma o custo e mede a profundidade das seleções feitas em parent.
+// visiting evita ciclos entre fragmentos (que a validação do graphql-go recusaria depois).
+func (a *queryAnalyzer) selectionSet(parent graphql.Type, set *ast.SelectionSet, level int, visiting map[string]bool) (int, int, *apperror.Error) {
+	if set == nil {
+		return 0, level - 1, nil
+	}
+	object, _ := parent.(*graphql.Object)
+	cost, depth := 0, level-1
+
+	add := func(c, d int) {
+		cost += c
+		if d > depth {
+			depth = d
+		}
+	}
+	for _, selection := range set.Selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			c, d, err := a.field(object, sel, level, visiting)
+			if err != nil {
+				return 0, 0, err
+			}
+			add(c, d)
+		case *ast.InlineFragment:
+			target := parent
+			if sel.TypeCondition != nil {
+				target = a.schema.Type(sel.TypeCondition.Name.Value)
+			}
+			c, d, err := a.selectionSet(target, sel.SelectionSet, level, visiting)
+			if err != nil {
+				return 0, 0, err
+			}
+			add(c, d)
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			fragment, ok := a.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			c, d, err := a.selectionSet(a.schema.Type(fragment.TypeCondition.Name.Value), fragment.SelectionSet, level, visiting)
+			delete(visiting, name)
+			if err != nil {
+				return 0, 0, err
+			}
+			add(c, d)
+		}
+	}
+	return cost, depth, nil
+}
+
+func (a *queryAnalyzer) field(parent *graphql.Object, field *ast.Field, level int, visiting map[string]bool) (int, int, *apperror.Error) {
+	name := field.Name.Value
+	if name == "__schema" || name == "__type" {
+		if !a.limits.Introspection {
+			return 0, 0, apperror.New(apperror.CodeForbidden, apperror.MsgIntrospectionDisabled)
+		}
+		// A consulta de introspecção das ferramentas é profunda por natureza; não entra na conta.
+		return 0, level, nil
+	}
+	if parent == nil || strings.HasPrefix(name, "__") {
+		return 0, level, nil
+	}
+	definition, ok := parent.Fields()[name]
+	if !ok {
+		return 0, level, nil
+	}
+
+	fieldType, isList := unwrapType(definition.Type)
+	childCost, depth, err := a.selectionSet(fieldType, field.SelectionSet, level+1, visiting)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cost, ok := fieldCosts[parent.Name()+"."+name]
+	if !ok && field.SelectionSet != nil {
+		cost = 1 // Objetos custam 1; escalares são de graça.
+	}
+	multiplier := 1
+	if size, ok, err := a.pageSize(field, fieldType); err != nil {
+		return 0, 0, err
+	} else if ok {
+		multiplier = size
+	} else if isList && !strings.HasSuffix(parent.Name(), "Connection") {
+		// As arestas de uma conexão já foram multiplicadas pelo first/last da conexão.
+		multiplier = a.limits.DefaultListSize
+	}
+	return cost + multiplier*childCost, depth, nil
+}
+
+// pageSize retorna o first/last pedido no campo, ou o tamanho padrão se o campo for paginado.
+// Tamanhos negativos são recusados; os demais ficam entre 1 e o máximo da paginação, para
+// que o multiplicador nunca reduza o custo da consulta.
+func (a *queryAnalyzer) pageSize(field *ast.Field, fieldType graphql.Type) (int, bool, *apperror.Error) {
+	paginated := fieldType != nil && strings.HasSuffix(fieldType.Name(), "Connection")
+	for _, arg := range field.Arguments {
+		switch arg.Name.Value {
+		case "first", "last":
+			size, ok := a.intValue(arg.Value)
+			if !ok {
+				paginated = true
+				continue
+			}
+			if size < 0 {
+				return 0, false, apperror.Invalid(arg.Name.Value, apperror.MsgPageSize, pagination.MaxSize())
+			}
+			return min(max(size, 1), pagination.MaxSize()), true, nil
+		case "after", "before":
+			paginated = true
+		}
+	}
+	if paginated {
+		return pagination.DefaultSize(), true, nil
+	}
+	return 0, false, nil
+}
+
+// intValue lê um inteiro literal ou de variável. Variáveis ausentes usam o valor padrão
+// declarado na operação; variáveis de tipo inesperado contam como o tamanho máximo.
+func (a *queryAnalyzer) intValue(value ast.Value) (int, bool) {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		if err != nil {
+			return pagination.MaxSize(), true
+		}
+		return n, true
+	case *ast.Variable:
+		raw, ok := a.variables[v.Name.Value]
+		if !ok || raw == nil {
+			if def, ok := a.defaults[v.Name.Value]; ok {
+				return a.intValue(def)
+			}
+			return 0, false
+		}
+		switch n := raw.(type) {
+		case float64:
+			// Converte só depois de limitar, para valores enormes não estourarem o int.
+			return int(min(n, float64(pagination.MaxSize()))), true
+		case int:
+			return n, true
+		case int32:
+			return int(n), true
+		case int64:
+			return int(n), true
+		case json.Number:
+			if i, err := n.Int64(); err == nil {
+				return int(i), true
+			}
+		}
+		return pagination.MaxSize(), true
+	}
+	return 0, false
+}
+
+// unwrapType remove NonNull e List, informando se havia uma lista.
+func unwrapType(t graphql.Type) (graphql.Type, bool) {
+	isList := false
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			isList = true
+			t = wrapped.OfType
+		default:
+			return t, isList
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/limits_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/handler"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+)
+
+// limitsSchema tem uma conexão paginada de itens, cada um com uma lista de tags.
+func limitsSchema(t *testing.T) *graphql.Schema {
+	t.Helper()
+	tag := graphql.NewObject(graphql.ObjectConfig{Name: "Tag", Fields: graphql.Fields{
+		"name": &graphql.Field{Type: graphql.String},
+	}})
+	item := graphql.NewObject(graphql.ObjectConfig{Name: "Item", Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.ID},
+		"tags": &graphql.Field{Type: graphql.NewList(tag)},
+	}})
+	edge := graphql.NewObject(graphql.ObjectConfig{Name: "ItemEdge", Fields: graphql.Fields{
+		"node": &graphql.Field{Type: item},
+	}})
+	connection := graphql.NewObject(graphql.ObjectConfig{Name: "ItemConnection", Fields: graphql.Fields{
+		"edges": &graphql.Field{Type: graphql.NewList(edge)},
+	}})
+	pageArgs := graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{Type: graphql.Int},
+		"last":  &graphql.ArgumentConfig{Type: graphql.Int},
+	}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: graphql.NewObject(graphql.ObjectConfig{
+		Name: "RootQuery",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{Type: connection, Args: pageArgs},
+			"other": &graphql.Field{Type: connection, Args: pageArgs},
+		},
+	})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func TestCheckLimitsPageSize(t *testing.T) {
+	if pagination.MaxSize() != 100 {
+		t.Fatalf("os casos assumem o máximo padrão de 100, veio %d", pagination.MaxSize())
+	}
+	schema := limitsSchema(t)
+	limits := Limits{MaxDepth: 10, MaxCost: 500, DefaultListSize: 10}
+	expensive := `items(first: 100) { edges { node { tags { name } } } }`
+
+	cases := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		code      string
+	}{
+		{"dentro do orçamento", `{ items(first: 10) { edges { node { id } } } }`, nil, ""},
+		{"cara demais", `{ a: ` + expensive + ` b: ` + expensive + ` }`, nil, apperror.CodeQueryTooComplex},
+		{"first negativo é recusado", `{ ` + expensive + ` other(first: -100000) { edges { node { id } } } }`, nil, apperror.CodeValidation},
+		{"last negativo em variável é recusado", `query($n: Int) { other(last: $n) { edges { node { id } } } }`, map[string]interface{}{"n": float64(-5)}, apperror.CodeValidation},
+		{"first zero conta como um", `{ items(first: 0) { edges { node { id } } } }`, nil, ""},
+		{"first acima do máximo é limitado", `{ a: items(first: 1000000) { edges { node { tags { name } } } } }`, nil, ""},
+		{"variável int64", `query($n: Int) { a: items(first: $n) { edges { node { tags { name } } } } b: ` + expensive + ` }`, map[string]interface{}{"n": int64(100)}, apperror.CodeQueryTooComplex},
+		{"variável json.Number", `query($n: Int) { a: items(first: $n) { edges { node { tags { name } } } } b: ` + expensive + ` }`, map[string]interface{}{"n": json.Number("100")}, apperror.CodeQueryTooComplex},
+		{"valor padrão da variável", `query($n: Int = 100) { a: items(first: $n) { edges { node { tags { name } } } } b: ` + expensive + ` }`, nil, apperror.CodeQueryTooComplex},
+		{"variável de tipo inesperado conta como o máximo", `query($n: Int) { a: items(first: $n) { edges { node { tags { name } } } } b: ` + expensive + ` }`, map[string]interface{}{"n": "1"}, apperror.CodeQueryTooComplex},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkLimits(schema, limits, &handler.RequestOptions{Query: tc.query, Variables: tc.variables})
+			switch {
+			case tc.code == "" && err != nil:
+				t.Fatalf("esperava passar, veio %v", err)
+			case tc.code != "" && (err == nil || err.Code != tc.code):
+				t.Fatalf("esperava %s, veio %v", tc.code, err)
+			}
+		})
+	}
+}
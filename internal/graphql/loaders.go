@@ -0,0 +1,214 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/loaders.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/dataloader"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/agent"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/category"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/product"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/user"
+)
+
+// ownedKey identifica um registro pelo ID junto com o agente do objeto pai, para que um
+// relacionamento nunca traga registros de outro agente.
+type ownedKey struct {
+	AgentID uint
+	ID      uint
+}
+
+// productPageKey identifica uma página de produtos de uma categoria. Categorias do mesmo
+// agente pedidas com os mesmos first/after são buscadas juntas.
+type productPageKey struct {
+	AgentID    uint
+	CategoryID uint
+	First      int
+	AfterID    uint
+}
+
+// loaders são os batchers de uma requisição, usados pelos campos de relacionamento.
+type loaders struct {
+	agentByID              *dataloader.Loader[uint, *agent.Agent]
+	categoryByID           *dataloader.Loader[ownedKey, *category.Category]
+	productsByCategory     *dataloader.Loader[productPageKey, []product.Product]
+	productCountByCategory *dataloader.Loader[ownedKey, int64]
+	categoriesByAgent      *dataloader.Loader[uint, []category.Category]
+	usersByAgent           *dataloader.Loader[uint, []user.User]
+	assetByID              *dataloader.Loader[uint, *asset.Asset]
+	imagesByProduct        *dataloader.Loader[uint, []product.ProductImage]
+	optionGroupsByProduct  *dataloader.Loader[uint, []product.OptionGroup]
+	variantsByProduct      *dataloader.Loader[uint, []product.Variant]
+}
+
+func newLoaders(services SchemaServices) *loaders {
+	return &loaders{
+		agentByID: dataloader.New(func(ids []uint) (map[uint]*agent.Agent, error) {
+			agents, err := services.AgentSvc.GetAgentsByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint]*agent.Agent, len(agents))
+			for i := range agents {
+				result[agents[i].ID] = &agents[i]
+			}
+			return result, nil
+		}),
+		categoryByID: dataloader.New(func(keys []ownedKey) (map[ownedKey]*category.Category, error) {
+			ids := make([]uint, len(keys))
+			for i, key := range keys {
+				ids[i] = key.ID
+			}
+			categories, err := services.CategorySvc.GetCategoriesByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			// Só entram as categorias do agente pedido na chave.
+			result := make(map[ownedKey]*category.Category, len(categories))
+			for i := range categories {
+				result[ownedKey{AgentID: categories[i].AgentID, ID: categories[i].ID}] = &categories[i]
+			}
+			return result, nil
+		}),
+		productsByCategory: dataloader.New(func(keys []productPageKey) (map[productPageKey][]product.Product, error) {
+			// Uma consulta por combinação de first/after; na prática todas as chaves
+			// de um mesmo campo da query compartilham os argumentos.
+			groups := map[[3]uint][]uint{}
+			for _, key := range keys {
+				args := [3]uint{key.AgentID, uint(key.First), key.AfterID}
+				groups[args] = append(groups[args], key.CategoryID)
+			}
+			result := make(map[productPageKey][]product.Product, len(keys))
+			for args, categoryIDs := range groups {
+				products, err := services.ProductSvc.GetProductsByCategoryIDs(args[0], categoryIDs, int(args[1]), args[2])
+				if err != nil {
+					return nil, err
+				}
+				for _, p := range products {
+					key := productPageKey{AgentID: args[0], CategoryID: p.CategoryID, First: int(args[1]), AfterID: args[2]}
+					result[key] = append(result[key], p)
+				}
+			}
+			return result, nil
+		}),
+		productCountByCategory: dataloader.New(func(keys []ownedKey) (map[ownedKey]int64, error) {
+			groups := map[uint][]uint{}
+			for _, key := range keys {
+				groups[key.AgentID] = append(groups[key.AgentID], key.ID)
+			}
+			result := make(map[ownedKey]int64, len(keys))
+			for agentID, categoryIDs := range groups {
+				counts, err := services.ProductSvc.CountProductsByCategoryIDs(agentID, categoryIDs)
+				if err != nil {
+					return nil, err
+				}
+				for categoryID, count := range counts {
+					result[ownedKey{AgentID: agentID, ID: categoryID}] = count
+				}
+			}
+			return result, nil
+		}),
+		categoriesByAgent: dataloader.New(func(agentIDs []uint) (map[uint][]category.Category, error) {
+			categories, err := services.CategorySvc.GetCategoriesByAgentIDs(agentIDs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint][]category.Category, len(agentIDs))
+			for _, c := range categories {
+				result[c.AgentID] = append(result[c.AgentID], c)
+			}
+			return result, nil
+		}),
+		usersByAgent: dataloader.New(func(agentIDs []uint) (map[uint][]user.User, error) {
+			users, err := services.UserSvc.GetUsersByAgentIDs(agentIDs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint][]user.User, len(agentIDs))
+			for _, u := range users {
+				result[u.AgentID] = append(result[u.AgentID], u)
+			}
+			return result, nil
+		}),
+		assetByID: dataloader.New(func(ids []uint) (map[uint]*asset.Asset, error) {
+			assets, err := services.AssetSvc.GetAssetsByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint]*asset.Asset, len(assets))
+			for i := range assets {
+				result[assets[i].ID] = &assets[i]
+			}
+			return result, nil
+		}),
+		imagesByProduct: dataloader.New(func(productIDs []uint) (map[uint][]product.ProductImage, error) {
+			images, err := services.ProductSvc.GetImagesByProductIDs(productIDs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint][]product.ProductImage, len(productIDs))
+			for _, image := range images {
+				result[image.ProductID] = append(result[image.ProductID], image)
+			}
+			return result, nil
+		}),
+		optionGroupsByProduct: dataloader.New(func(productIDs []uint) (map[uint][]product.OptionGroup, error) {
+			groups, err := services.ProductSvc.GetOptionGroupsByProductIDs(productIDs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint][]product.OptionGroup, len(productIDs))
+			for _, group := range groups {
+				result[group.ProductID] = append(result[group.ProductID], group)
+			}
+			return result, nil
+		}),
+		variantsByProduct: dataloader.New(func(productIDs []uint) (map[uint][]product.Variant, error) {
+			variants, err := services.ProductSvc.GetVariantsByProductIDs(productIDs)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[uint][]product.Variant, len(productIDs))
+			for _, variant := range variants {
+				result[variant.ProductID] = append(result[variant.ProductID], variant)
+			}
+			return result, nil
+		}),
+	}
+}
+
+type loadersKey struct{}
+
+// LoadersMiddleware cria batchers novos a cada requisição, para o cache não vazar entre elas.
+func LoadersMiddleware(services SchemaServices) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), loadersKey{}, newLoaders(services))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// loadersFrom retorna os batchers da requisição. Fora do middleware (ex.: execução
+// direta do schema) cria um batcher descartável por campo, sem agrupar as consultas.
+func loadersFrom(ctx context.Context, services SchemaServices) *loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return l
+	}
+	return newLoaders(services)
+}
+
+// thunk adapta o retorno de um Loader para o formato de resolver concorrente do graphql-go.
+func thunk[V any](load func() (V, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		return load()
+	}
+}
@@ -0,0 +1,77 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/middleware.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
+
+// RawVariablesMiddleware guarda no contexto as variáveis originais da requisição, antes
+// da coerção do graphql-go, para as mutations de atualização parcial distinguirem um
+// campo omitido de um null explícito.
+func RawVariablesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if variables := readRawVariables(r); variables != nil {
+			r = r.WithContext(patch.WithRawVariables(r.Context(), variables))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func readRawVariables(r *http.Request) map[string]interface{} {
+	if v := r.URL.Query().Get("variables"); v != "" {
+		return decodeVariables([]byte(v))
+	}
+	if r.Method != http.MethodPost || r.Body == nil {
+		return nil
+	}
+	contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+	if contentType != "" && contentType != "application/json" {
+		return nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	// Devolve o corpo intacto para o handler do GraphQL.
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	var payload struct {
+		Variables json.RawMessage `json:"variables"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	return decodeVariables(payload.Variables)
+}
+
+// decodeVariables aceita as variáveis como objeto JSON ou como string contendo o objeto.
+func decodeVariables(data []byte) map[string]interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	var variables map[string]interface{}
+	if err := json.Unmarshal(data, &variables); err == nil {
+		return variables
+	}
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(encoded), &variables); err != nil {
+		return nil
+	}
+	return variables
+}
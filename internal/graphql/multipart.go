@@ -0,0 +1,133 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/multipart.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/upload"
+)
+
+// multipartMemory é quanto de cada requisição multipart fica em memória; o resto vai
+// para arquivos temporários.
+const multipartMemory = 8 << 20
+
+// MultipartMiddleware implementa a especificação de requisições multipart do GraphQL:
+// lê os campos "operations" e "map", guarda os arquivos no contexto e entrega ao resto
+// da cadeia uma requisição JSON comum, com marcadores no lugar dos arquivos.
+func MultipartMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+			if r.Method != http.MethodPost || contentType != "multipart/form-data" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			if err := r.ParseMultipartForm(multipartMemory); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, http.StatusRequestEntityTooLarge, clientError(r.Context(), apperror.Invalid("file", apperror.MsgUploadTooLarge, maxBytes)))
+					return
+				}
+				writeError(w, http.StatusBadRequest, clientError(r.Context(), apperror.Invalid("operations", apperror.MsgInvalidMultipart)))
+				return
+			}
+			defer r.MultipartForm.RemoveAll()
+
+			body, files, err := readOperations(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, clientError(r.Context(), err))
+				return
+			}
+			r.Header.Set("Content-Type", "application/json")
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			next.ServeHTTP(w, r.WithContext(upload.WithFiles(r.Context(), files)))
+		})
+	}
+}
+
+// readOperations coloca, em cada caminho do "map", o marcador do arquivo correspondente.
+func readOperations(r *http.Request) ([]byte, map[string]upload.File, error) {
+	invalid := apperror.Invalid("operations", apperror.MsgInvalidMultipart)
+
+	var operations map[string]interface{}
+	if err := json.Unmarshal([]byte(r.FormValue("operations")), &operations); err != nil {
+		// Lotes de operações (um array) não são suportados pelo handler.
+		return nil, nil, invalid
+	}
+	var paths map[string][]string
+	if err := json.Unmarshal([]byte(r.FormValue("map")), &paths); err != nil {
+		return nil, nil, invalid
+	}
+
+	files := make(map[string]upload.File, len(paths))
+	for name, targets := range paths {
+		headers := r.MultipartForm.File[name]
+		if len(headers) == 0 {
+			return nil, nil, invalid
+		}
+		files[name] = upload.NewFile(headers[0])
+		for _, target := range targets {
+			if !setPath(operations, strings.Split(target, "."), upload.Placeholder(name)) {
+				return nil, nil, invalid
+			}
+		}
+	}
+	body, err := json.Marshal(operations)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, files, nil
+}
+
+// setPath troca o valor em path (ex.: variables.files.0), que deve existir e ser null.
+func setPath(node interface{}, path []string, value interface{}) bool {
+	if len(path) == 0 {
+		return false
+	}
+	last := len(path) == 1
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[path[0]]
+		if !ok {
+			return false
+		}
+		if last {
+			if child != nil {
+				return false
+			}
+			container[path[0]] = value
+			return true
+		}
+		return setPath(child, path[1:], value)
+	case []interface{}:
+		index, err := strconv.Atoi(path[0])
+		if err != nil || index < 0 || index >= len(container) {
+			return false
+		}
+		if last {
+			if container[index] != nil {
+				return false
+			}
+			container[index] = value
+			return true
+		}
+		return setPath(container[index], path[1:], value)
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/persisted.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/handler"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/persisted"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+// persistedRequest é a parte da requisição usada pelo protocolo de Automatic Persisted
+// Queries: o texto da operação pode faltar quando o hash é enviado.
+type persistedRequest struct {
+	Query         string
+	OperationName string
+	Hash          string
+	Version       int
+
+	setQuery func(query string) // Reescreve a requisição com o texto encontrado.
+}
+
+// PersistedQueriesMiddleware resolve as operações enviadas só pelo hash e registra as que
+// chegam com texto e hash. Em modo estrito, recusa qualquer operação fora do manifesto.
+func PersistedQueriesMiddleware(svc persisted.Service, schema *graphql.Schema, limits Limits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := readPersistedRequest(r)
+			if req == nil || (req.Hash == "" && req.Query == "") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			query, status, err := resolveOperation(svc, req)
+			if err != nil {
+				writeError(w, status, clientError(r.Context(), err))
+				return
+			}
+			if query != req.Query {
+				req.setQuery(query)
+			}
+			registerOperation(r.Context(), svc, schema, limits, req)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// resolveOperation aplica o protocolo APQ e o modo estrito, retornando o texto da operação
+// a executar. Em caso de erro, status é o código HTTP da resposta.
+func resolveOperation(svc persisted.Service, req *persistedRequest) (string, int, error) {
+	if req.Hash != "" && req.Version != 1 {
+		return "", http.StatusBadRequest, apperror.Invalid("extensions.persistedQuery.version", apperror.MsgPersistedQueryVersion)
+	}
+	if req.Hash != "" && req.Query != "" && persisted.Hash(req.Query) != strings.ToLower(req.Hash) {
+		return "", http.StatusBadRequest, apperror.Invalid("extensions.persistedQuery.sha256Hash", apperror.MsgPersistedQueryHash)
+	}
+
+	hash := req.Hash
+	if hash == "" {
+		hash = persisted.Hash(req.Query)
+	}
+	if req.Query != "" && !svc.Strict() {
+		return req.Query, 0, nil
+	}
+
+	stored, found, err := svc.Lookup(hash)
+	switch {
+	case err != nil:
+		return "", http.StatusInternalServerError, err
+	case !found && svc.Strict():
+		return "", http.StatusForbidden, apperror.New(apperror.CodeForbidden, apperror.MsgOperationNotAllowed)
+	case !found:
+		// Os clientes APQ reenviam a operação completa ao receber este código.
+		return "", http.StatusOK, apperror.New(apperror.CodePersistedQueryNotFound, apperror.MsgPersistedQueryNotFound)
+	}
+	if req.Query != "" {
+		return req.Query, 0, nil
+	}
+	return stored.Query, 0, nil
+}
+
+// registerOperation guarda a operação APQ que chegou com texto e hash. Só registra para
+// usuários autenticados e só operações que cabem em persisted.MaxQueryLength, são válidas e
+// respeitam os limites de profundidade e custo. As demais executam normalmente, sem ficar
+// gravadas, e o cliente volta a enviar o texto completo.
+func registerOperation(ctx context.Context, svc persisted.Service, schema *graphql.Schema, limits Limits, req *persistedRequest) {
+	if req.Hash == "" || req.Query == "" || svc.Strict() || len(req.Query) > persisted.MaxQueryLength {
+		return
+	}
+	if _, ok := session.FromContext(ctx); !ok {
+		return
+	}
+	if _, err := parser.Parse(parser.ParseParams{Source: req.Query}); err != nil {
+		return
+	}
+	if checkLimits(schema, limits, &handler.RequestOptions{Query: req.Query, OperationName: req.OperationName}) != nil {
+		return
+	}
+	if err := svc.Register(req.Hash, req.Query, req.OperationName); err != nil {
+		log.Printf("Falha ao registrar a operação persistida %s: %v", req.Hash, err)
+	}
+}
+
+// readPersistedRequest lê a operação de um GET ou de um POST em JSON. Outros formatos não
+// carregam extensions; deles só o texto é lido, para a checagem do modo estrito.
+func readPersistedRequest(r *http.Request) *persistedRequest {
+	if r.Method == http.MethodGet {
+		values := r.URL.Query()
+		req := &persistedRequest{Query: values.Get("query"), OperationName: values.Get("operationName")}
+		req.readExtensions([]byte(values.Get("extensions")))
+		req.setQuery = func(query string) {
+			values.Set("query", query)
+			r.URL.RawQuery = values.Encode()
+		}
+		return req
+	}
+	contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+	if r.Method != http.MethodPost || r.Body == nil {
+		return nil
+	}
+	if contentType != "" && contentType != "application/json" {
+		return &persistedRequest{Query: requestOptions(r).Query}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	req := &persistedRequest{}
+	json.Unmarshal(payload["query"], &req.Query)
+	json.Unmarshal(payload["operationName"], &req.OperationName)
+	req.readExtensions(payload["extensions"])
+	req.setQuery = func(query string) {
+		payload["query"], _ = json.Marshal(query)
+		rewritten, _ := json.Marshal(payload)
+		r.Body = io.NopCloser(bytes.NewReader(rewritten))
+		r.ContentLength = int64(len(rewritten))
+	}
+	return req
+}
+
+func (req *persistedRequest) readExtensions(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	var extensions struct {
+		PersistedQuery *struct {
+			Version    int    `json:"version"`
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	}
+	if err := json.Unmarshal(data, &extensions); err != nil || extensions.PersistedQuery == nil {
+		return
+	}
+	req.Hash = extensions.PersistedQuery.Sha256Hash
+	req.Version = extensions.PersistedQuery.Version
+}
@@ -0,0 +1,64 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/persisted_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/persisted"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+// recordingService guarda os hashes registrados, sem banco.
+type recordingService struct {
+	registered []string
+}
+
+func (s *recordingService) Lookup(string) (persisted.Query, bool, error) {
+	return persisted.Query{}, false, nil
+}
+
+func (s *recordingService) Register(hash, query, operationName string) error {
+	s.registered = append(s.registered, hash)
+	return nil
+}
+
+func (s *recordingService) LoadManifest(string) (int, error) { return 0, nil }
+func (s *recordingService) Strict() bool                     { return false }
+
+func TestRegisterOperation(t *testing.T) {
+	schema := limitsSchema(t)
+	limits := Limits{MaxDepth: 10, MaxCost: 500, DefaultListSize: 10}
+	authenticated := session.WithIdentity(context.Background(), session.Identity{UserID: 1, AgentID: 1})
+	expensive := `items(first: 100) { edges { node { tags { name } } } }`
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		query    string
+		register bool
+	}{
+		{"autenticado", authenticated, `{ items(first: 10) { edges { node { id } } } }`, true},
+		{"anônimo não registra", context.Background(), `{ items(first: 10) { edges { node { id } } } }`, false},
+		{"grande demais", authenticated, `{ items { edges { node { id } } } }` + strings.Repeat(" ", persisted.MaxQueryLength), false},
+		{"cara demais", authenticated, `{ a: ` + expensive + ` b: ` + expensive + ` }`, false},
+		{"sintaxe inválida", authenticated, `{ items(`, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &recordingService{}
+			req := &persistedRequest{Query: tc.query, Hash: persisted.Hash(tc.query), Version: 1}
+			registerOperation(tc.ctx, svc, schema, limits, req)
+			if registered := len(svc.registered) == 1; registered != tc.register {
+				t.Fatalf("esperava registrar=%v, registrou %v", tc.register, svc.registered)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/relations.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/agent"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/category"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/product"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/user"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/pagination"
+)
+
+// addRelationFields liga os tipos dos domínios entre si. Fica aqui, e não nos pacotes de
+// domínio, porque product e category importarem um ao outro criaria um ciclo. Todos os
+// campos usam os batchers da requisição, então uma lista de N itens gera uma consulta
+// por relacionamento, e não N.
+func addRelationFields(services SchemaServices) {
+	product.ProductType.AddFieldConfig("category", &graphql.Field{
+		Type:        category.CategoryType,
+		Description: "Categoria do produto.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Product](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return thunk(loadersFrom(p.Context, services).categoryByID.Load(ownedKey{AgentID: source.AgentID, ID: source.CategoryID})), nil
+		},
+	})
+
+	product.ProductType.AddFieldConfig("price", &graphql.Field{
+		Type:        graphql.NewNonNull(money.MoneyType),
+		Description: "Preço do produto, formatado na localidade do agente.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Product](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return localizedMoney(p, services, source.AgentID, source.Price), nil
+		},
+	})
+
+	product.ProductType.AddFieldConfig("image_asset", &graphql.Field{
+		Type:        asset.AssetType,
+		Description: "Asset da biblioteca de mídia da capa do produto.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Product](p.Source)
+			if !ok || source.ImageAssetID == nil {
+				return nil, nil
+			}
+			return thunk(loadersFrom(p.Context, services).assetByID.Load(*source.ImageAssetID)), nil
+		},
+	})
+
+	product.ProductType.AddFieldConfig("images", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(product.ProductImageType))),
+		Description: "Galeria de imagens do produto, na ordem de exibição.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Product](p.Source)
+			if !ok {
+				return []product.ProductImage{}, nil
+			}
+			load := loadersFrom(p.Context, services).imagesByProduct.Load(source.ID)
+			return func() (interface{}, error) {
+				images, err := load()
+				if images == nil {
+					images = []product.ProductImage{}
+				}
+				return images, err
+			}, nil
+		},
+	})
+
+	product.ProductType.AddFieldConfig("coverImage", &graphql.Field{
+		Type:        product.ProductImageType,
+		Description: "Capa da galeria, de onde vem image_url.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Product](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			load := loadersFrom(p.Context, services).imagesByProduct.Load(source.ID)
+			return func() (interface{}, error) {
+				images, err := load()
+				for _, image := range images {
+					if image.IsCover {
+						return image, err
+					}
+				}
+				return nil, err
+			}, nil
+		},
+	})
+
+	product.ProductType.AddFieldConfig("optionGroups", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(product.ProductOptionGroupType))),
+		Description: "Grupos de opções do produto (tamanhos, sabores, adicionais), na ordem de exibição.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Product](p.Source)
+			if !ok {
+				return []product.OptionGroup{}, nil
+			}
+			load := loadersFrom(p.Context, services).optionGroupsByProduct.Load(source.ID)
+			return func() (interface{}, error) {
+				groups, err := load()
+				if groups == nil {
+					groups = []product.OptionGroup{}
+				}
+				return groups, err
+			}, nil
+		},
+	})
+
+	product.ProductType.AddFieldConfig("variants", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(product.ProductVariantType))),
+		Description: "Variações do produto, na ordem de exibição.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Product](p.Source)
+			if !ok {
+				return []product.Variant{}, nil
+			}
+			load := loadersFrom(p.Context, services).variantsByProduct.Load(source.ID)
+			return func() (interface{}, error) {
+				variants, err := load()
+				if variants == nil {
+					variants = []product.Variant{}
+				}
+				return variants, err
+			}, nil
+		},
+	})
+
+	product.ProductOptionType.AddFieldConfig("price_delta", &graphql.Field{
+		Type:        graphql.NewNonNull(money.MoneyType),
+		Description: "Valor somado ao preço quando a opção é escolhida.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Option](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return localizedMoney(p, services, source.AgentID, source.PriceDelta), nil
+		},
+	})
+
+	product.ProductVariantType.AddFieldConfig("price", &graphql.Field{
+		Type:        graphql.NewNonNull(money.MoneyType),
+		Description: "Preço da variação, que substitui o do produto.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.Variant](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return localizedMoney(p, services, source.AgentID, source.Price), nil
+		},
+	})
+
+	quoteFields := map[string]struct {
+		description string
+		value       func(product.PriceQuote) money.Money
+	}{
+		"base":    {"Preço da variação ou, sem ela, do produto.", func(q product.PriceQuote) money.Money { return q.Base }},
+		"options": {"Soma dos acréscimos das opções.", func(q product.PriceQuote) money.Money { return q.Options }},
+		"unit":    {"Preço de uma unidade: base mais opções.", func(q product.PriceQuote) money.Money { return q.Unit }},
+		"total":   {"Preço unitário vezes a quantidade.", func(q product.PriceQuote) money.Money { return q.Total }},
+	}
+	for name, field := range quoteFields {
+		value := field.value
+		product.ProductPriceQuoteType.AddFieldConfig(name, &graphql.Field{
+			Type:        graphql.NewNonNull(money.MoneyType),
+			Description: field.description,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				source, ok := sourceAs[product.PriceQuote](p.Source)
+				if !ok {
+					return nil, nil
+				}
+				return localizedMoney(p, services, source.AgentID, value(source)), nil
+			},
+		})
+	}
+
+	product.ProductImageType.AddFieldConfig("asset", &graphql.Field{
+		Type:        asset.AssetType,
+		Description: "Asset da biblioteca de mídia da imagem.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[product.ProductImage](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return thunk(loadersFrom(p.Context, services).assetByID.Load(source.AssetID)), nil
+		},
+	})
+
+	category.CategoryType.AddFieldConfig("image_asset", &graphql.Field{
+		Type:        asset.AssetType,
+		Description: "Asset da biblioteca de mídia usado como imagem da categoria.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[category.Category](p.Source)
+			if !ok || source.ImageAssetID == nil {
+				return nil, nil
+			}
+			return thunk(loadersFrom(p.Context, services).assetByID.Load(*source.ImageAssetID)), nil
+		},
+	})
+
+	category.CategoryType.AddFieldConfig("products", &graphql.Field{
+		Type:        product.ProductConnectionType,
+		Description: "Produtos da categoria, ordenados por ID.",
+		Args: graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int},
+			"after": &graphql.ArgumentConfig{Type: graphql.String},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[category.Category](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			var first *int
+			if v, ok := p.Args["first"].(int); ok {
+				first = &v
+			}
+			after, _ := p.Args["after"].(string)
+			page, err := pagination.NewPage(first, nil, after, "")
+			if err != nil {
+				return nil, err
+			}
+			if err := page.Check(pagination.SortByID); err != nil {
+				return nil, err
+			}
+			key := productPageKey{AgentID: source.AgentID, CategoryID: source.ID, First: page.Limit + 1}
+			if page.After != nil {
+				key.AfterID = page.After.ID
+			}
+
+			loaders := loadersFrom(p.Context, services)
+			load := loaders.productsByCategory.Load(key)
+			// A contagem entra no lote agora, mas só é buscada se totalCount for pedido.
+			count := loaders.productCountByCategory.Load(ownedKey{AgentID: source.AgentID, ID: source.ID})
+			return func() (interface{}, error) {
+				products, err := load()
+				if err != nil {
+					return nil, err
+				}
+				conn := pagination.NewConnection(products, page, func(item product.Product) pagination.Cursor {
+					return pagination.SortByID.Cursor(item.ID)
+				})
+				return conn.WithCount(count), nil
+			}, nil
+		},
+	})
+
+	category.CategoryType.AddFieldConfig("productCount", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.Int),
+		Description: "Quantidade de produtos da categoria.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[category.Category](p.Source)
+			if !ok {
+				return 0, nil
+			}
+			return thunk(loadersFrom(p.Context, services).productCountByCategory.Load(ownedKey{AgentID: source.AgentID, ID: source.ID})), nil
+		},
+	})
+
+	agent.AgentType.AddFieldConfig("categories", &graphql.Field{
+		Type:        graphql.NewList(category.CategoryType),
+		Description: "Categorias do agente.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[agent.Agent](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return thunk(loadersFrom(p.Context, services).categoriesByAgent.Load(source.ID)), nil
+		},
+	})
+
+	agent.AgentType.AddFieldConfig("users", &graphql.Field{
+		Type:        graphql.NewList(user.UserType),
+		Description: "Usuários do agente.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			source, ok := sourceAs[agent.Agent](p.Source)
+			if !ok {
+				return nil, nil
+			}
+			return thunk(loadersFrom(p.Context, services).usersByAgent.Load(source.ID)), nil
+		},
+	})
+}
+
+// localizedMoney devolve o valor com a localidade do agente dono, para Money.formatted.
+func localizedMoney(p graphql.ResolveParams, services SchemaServices, agentID uint, value money.Money) func() (interface{}, error) {
+	load := loadersFrom(p.Context, services).agentByID.Load(agentID)
+	return func() (interface{}, error) {
+		owner, err := load()
+		if err != nil {
+			return nil, err
+		}
+		locale := money.DefaultLocale
+		if owner != nil && owner.Locale != "" {
+			locale = owner.Locale
+		}
+		return money.Localized{Money: value, Locale: locale}, nil
+	}
+}
+
+// sourceAs lê o objeto pai do resolver, que pode chegar por valor ou por ponteiro.
+func sourceAs[T any](source interface{}) (T, bool) {
+	switch value := source.(type) {
+	case T:
+		return value, true
+	case *T:
+		if value != nil {
+			return *value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
@@ -10,10 +10,14 @@ package graphql
 import (
 	"github.com/graphql-go/graphql"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/auth"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/backup"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/agent"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/asset"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/category"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/plan"
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/product" // <-- ADICIONADO
 	"github.com/raimundocoelho-ti/sabiosystem-api/internal/domain/user"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/events"
 )
 
 // SchemaServices contém todos os serviços necessários para construir o schema.
@@ -23,15 +27,25 @@ type SchemaServices struct {
 	UserSvc     user.Service
 	AgentSvc    agent.Service
 	AuthSvc     auth.Service
+	PlanSvc     plan.Service
+	BackupSvc   backup.Service
+	AssetSvc    asset.Service
+	Events      *events.Bus // Alimenta as subscriptions.
 }
 
 func NewSchema(services SchemaServices) (graphql.Schema, error) {
+	// Campos que atravessam domínios (Product.category, Category.products, ...)
+	addRelationFields(services)
+
 	// Juntando os campos de Query de todos os módulos (auth não tem queries)
 	queryFields := mergeFields(
 		category.GetQueryFields(services.CategorySvc),
 		product.GetQueryFields(services.ProductSvc), // <-- ADICIONADO
 		user.GetQueryFields(services.UserSvc),
 		agent.GetQueryFields(services.AgentSvc),
+		plan.GetQueryFields(services.PlanSvc),
+		backup.GetQueryFields(services.BackupSvc),
+		asset.GetQueryFields(services.AssetSvc),
 	)
 
 	rootQuery := graphql.NewObject(graphql.ObjectConfig{
@@ -45,6 +59,9 @@ func NewSchema(services SchemaServices) (graphql.Schema, error) {
 		product.GetMutationFields(services.ProductSvc), // <-- ADICIONADO
 		user.GetMutationFields(services.UserSvc),
 		agent.GetMutationFields(services.AgentSvc),
+		plan.GetMutationFields(services.PlanSvc),
+		backup.GetMutationFields(services.BackupSvc),
+		asset.GetMutationFields(services.AssetSvc),
 		auth.GetMutationFields(services.AuthSvc, services.UserSvc, services.AgentSvc),
 	)
 
@@ -53,12 +70,27 @@ func NewSchema(services SchemaServices) (graphql.Schema, error) {
 		Fields: mutationFields,
 	})
 
+	// Subscriptions, entregues pelo WebSocket
+	rootSubscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RootSubscription",
+		Fields: mergeFields(
+			product.GetSubscriptionFields(services.Events),
+			category.GetSubscriptionFields(services.Events),
+		),
+	})
+
 	schemaConfig := graphql.SchemaConfig{
-		Query:    rootQuery,
-		Mutation: rootMutation,
+		Query:        rootQuery,
+		Mutation:     rootMutation,
+		Subscription: rootSubscription,
 	}
 
-	return graphql.NewSchema(schemaConfig)
+	schema, err := graphql.NewSchema(schemaConfig)
+	if err != nil {
+		return schema, err
+	}
+	handleErrors(schema)
+	return schema, nil
 }
 
 // mergeFields é uma função utilitária para juntar múltiplos mapas de campos.
@@ -0,0 +1,348 @@
+/*
+|------------------------------------------------
+| File: internal/graphql/subscriptions.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/handler"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/auth"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/persisted"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/session"
+)
+
+// Protocolo graphql-ws (subprotocolo "graphql-transport-ws").
+const (
+	wsProtocol    = "graphql-transport-ws"
+	wsInitTimeout = 10 * time.Second
+
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// Códigos de fechamento definidos pelo protocolo.
+const (
+	closeBadRequest       = 4400
+	closeUnauthorized     = 4401
+	closeForbidden        = 4403
+	closeInitTimeout      = 4408
+	closeSubscriberExists = 4409
+	closeTooManyInits     = 4429
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsSubscribePayload struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    json.RawMessage        `json:"extensions"`
+}
+
+// SubscriptionsHandler atende o upgrade para WebSocket em /graphql com o protocolo
+// graphql-ws. Requisições que não são upgrade seguem para o próximo handler da rota.
+// O JWT vai no payload do connection_init, no campo "Authorization" ou "token".
+func SubscriptionsHandler(schema *graphql.Schema, limits Limits, queries persisted.Service) fiber.Handler {
+	upgrade := websocket.New(func(conn *websocket.Conn) {
+		ws := &wsConnection{
+			conn:       conn,
+			schema:     schema,
+			limits:     limits,
+			queries:    queries,
+			operations: map[string]context.CancelFunc{},
+			language:   apperror.ParseAcceptLanguage(conn.Headers("Accept-Language")),
+			requestID:  newRequestID(),
+		}
+		ws.serve()
+	}, websocket.Config{Subprotocols: []string{wsProtocol}})
+
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return upgrade(c)
+	}
+}
+
+type wsConnection struct {
+	conn      *websocket.Conn
+	schema    *graphql.Schema
+	limits    Limits
+	queries   persisted.Service
+	language  apperror.Language
+	requestID string
+
+	writeMu    sync.Mutex
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc
+	running    sync.WaitGroup
+	ctx        context.Context
+}
+
+func (ws *wsConnection) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		// A conexão é reaproveitada pelo Fiber quando serve retorna; nenhuma operação
+		// pode continuar escrevendo nela.
+		cancel()
+		ws.running.Wait()
+	}()
+	ctx = context.WithValue(ctx, requestIDKey{}, ws.requestID)
+	ws.ctx = apperror.WithLanguage(ctx, ws.language)
+
+	if ws.conn.Subprotocol() != wsProtocol {
+		ws.close(websocket.CloseProtocolError, "Subprotocolo não suportado; use "+wsProtocol)
+		return
+	}
+
+	ws.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+	acknowledged := false
+	for {
+		var msg wsMessage
+		if err := ws.conn.ReadJSON(&msg); err != nil {
+			var netErr net.Error
+			if !acknowledged && errors.As(err, &netErr) && netErr.Timeout() {
+				ws.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case msgConnectionInit:
+			if acknowledged {
+				ws.close(closeTooManyInits, "Too many initialisation requests")
+				return
+			}
+			if !ws.authenticate(msg.Payload) {
+				ws.close(closeForbidden, "Forbidden")
+				return
+			}
+			acknowledged = true
+			ws.conn.SetReadDeadline(time.Time{})
+			ws.write(wsMessage{Type: msgConnectionAck})
+		case msgPing:
+			ws.write(wsMessage{Type: msgPong})
+		case msgPong:
+		case msgSubscribe:
+			if !acknowledged {
+				ws.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var payload wsSubscribePayload
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil {
+				ws.close(closeBadRequest, "Invalid subscribe message")
+				return
+			}
+			if !ws.start(msg.ID, payload) {
+				ws.close(closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+				return
+			}
+		case msgComplete:
+			ws.stop(msg.ID)
+		default:
+			ws.close(closeBadRequest, "Invalid message type")
+			return
+		}
+	}
+}
+
+// authenticate lê o JWT do payload do connection_init. Sem token a conexão segue anônima
+// e cada subscription decide se exige autenticação; um token inválido encerra a conexão.
+func (ws *wsConnection) authenticate(payload json.RawMessage) bool {
+	var params map[string]interface{}
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return false
+		}
+	}
+	header := ""
+	for key, value := range params {
+		text, _ := value.(string)
+		switch strings.ToLower(key) {
+		case "authorization":
+			header = text
+		case "token":
+			header = "Bearer " + text
+		}
+	}
+	if header == "" {
+		return true
+	}
+	identity, ok := auth.IdentityFromHeader(header)
+	if ok {
+		ws.ctx = session.WithIdentity(ws.ctx, identity)
+	}
+	return ok
+}
+
+// start executa a operação em segundo plano. Retorna false se o ID já está em uso.
+func (ws *wsConnection) start(id string, payload wsSubscribePayload) bool {
+	ws.mu.Lock()
+	if _, exists := ws.operations[id]; exists {
+		ws.mu.Unlock()
+		return false
+	}
+	ctx, cancel := context.WithCancel(ws.ctx)
+	ws.operations[id] = cancel
+	ws.mu.Unlock()
+
+	ws.running.Add(1)
+	go func() {
+		defer ws.running.Done()
+		defer ws.finish(id)
+		opts, err := ws.prepare(payload)
+		if err != nil {
+			ws.writeErrors(id, []gqlerrors.FormattedError{formatError(clientError(ctx, err))})
+			return
+		}
+		params := graphql.Params{
+			Schema:         *ws.schema,
+			RequestString:  opts.Query,
+			VariableValues: opts.Variables,
+			OperationName:  opts.OperationName,
+			Context:        ctx,
+		}
+		if !isSubscription(opts) {
+			ws.next(id, graphql.Do(params))
+			ws.write(wsMessage{ID: id, Type: msgComplete})
+			return
+		}
+		results := graphql.Subscribe(params)
+		for result := range results {
+			if result.Data == nil && len(result.Errors) > 0 {
+				// Falha sem dados (ex.: acesso negado) encerra a operação com um error.
+				ws.writeErrors(id, result.Errors)
+				cancel()
+				for range results {
+				}
+				return
+			}
+			ws.next(id, result)
+		}
+		// Se o cliente cancelou, o protocolo não pede um complete de volta.
+		if ctx.Err() == nil {
+			ws.write(wsMessage{ID: id, Type: msgComplete})
+		}
+	}()
+	return true
+}
+
+// prepare aplica às operações do WebSocket as mesmas regras do endpoint HTTP: operações
+// persistidas, modo estrito e limites de profundidade e custo.
+func (ws *wsConnection) prepare(payload wsSubscribePayload) (*handler.RequestOptions, error) {
+	req := &persistedRequest{Query: payload.Query, OperationName: payload.OperationName}
+	req.readExtensions(payload.Extensions)
+	query, _, err := resolveOperation(ws.queries, req)
+	if err != nil {
+		return nil, err
+	}
+	opts := &handler.RequestOptions{Query: query, Variables: payload.Variables, OperationName: payload.OperationName}
+	if appErr := checkLimits(ws.schema, ws.limits, opts); appErr != nil {
+		return nil, appErr
+	}
+	registerOperation(ws.ctx, ws.queries, ws.schema, ws.limits, req)
+	return opts, nil
+}
+
+func (ws *wsConnection) stop(id string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if cancel, ok := ws.operations[id]; ok {
+		cancel()
+	}
+}
+
+func (ws *wsConnection) finish(id string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if cancel, ok := ws.operations[id]; ok {
+		cancel()
+		delete(ws.operations, id)
+	}
+}
+
+func (ws *wsConnection) next(id string, result *graphql.Result) {
+	for i := range result.Errors {
+		result.Errors[i] = withExtensions(result.Errors[i])
+	}
+	payload, _ := json.Marshal(result)
+	ws.write(wsMessage{ID: id, Type: msgNext, Payload: payload})
+}
+
+func (ws *wsConnection) writeErrors(id string, errs []gqlerrors.FormattedError) {
+	for i := range errs {
+		errs[i] = withExtensions(errs[i])
+	}
+	payload, _ := json.Marshal(errs)
+	ws.write(wsMessage{ID: id, Type: msgError, Payload: payload})
+}
+
+func (ws *wsConnection) write(msg wsMessage) {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	ws.conn.WriteJSON(msg)
+}
+
+func (ws *wsConnection) close(code int, reason string) {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	ws.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+}
+
+// isSubscription informa se a operação escolhida do documento é uma subscription.
+func isSubscription(opts *handler.RequestOptions) bool {
+	document, err := parser.Parse(parser.ParseParams{Source: opts.Query})
+	if err != nil {
+		return false
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if opts.OperationName == "" || (operation.Name != nil && operation.Name.Value == opts.OperationName) {
+			return operation.Operation == ast.OperationTypeSubscription
+		}
+	}
+	return false
+}
+
+// withExtensions recupera as extensions dos erros do Subscribe, que o graphql-go formata
+// sem elas.
+func withExtensions(formatted gqlerrors.FormattedError) gqlerrors.FormattedError {
+	if formatted.Extensions == nil {
+		if extended, ok := formatted.OriginalError().(gqlerrors.ExtendedError); ok {
+			formatted.Extensions = extended.Extensions()
+		}
+	}
+	return formatted
+}
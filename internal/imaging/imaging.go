@@ -0,0 +1,205 @@
+/*
+|------------------------------------------------
+| File: internal/imaging/imaging.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Registra o decodificador de WebP.
+)
+
+// Limites das imagens aceitas, ajustáveis na inicialização por SetLimits.
+var (
+	maxBytes     int64 = 10 << 20
+	maxDimension       = 8000
+	maxPixels          = 40_000_000
+)
+
+// SetLimits define o tamanho máximo do arquivo e da maior dimensão da imagem, em pixels.
+func SetLimits(maxFileBytes int64, maxSide int) {
+	if maxFileBytes > 0 {
+		maxBytes = maxFileBytes
+	}
+	if maxSide > 0 {
+		maxDimension = maxSide
+	}
+}
+
+// VariantSpec descreve um tamanho gerado a partir do original.
+type VariantSpec struct {
+	Name   string
+	Width  int
+	Height int
+	Crop   bool // Recorta ao centro para preencher exatamente Width x Height.
+}
+
+// Variants são os tamanhos padrão gerados em cada upload, além do original.
+var Variants = []VariantSpec{
+	{Name: "thumb", Width: 200, Height: 200, Crop: true},
+	{Name: "card", Width: 600, Height: 600},
+	{Name: "full", Width: 1600, Height: 1600},
+}
+
+// Formatos aceitos, identificados pelo conteúdo e não pelo nome ou Content-Type do cliente.
+var accepted = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// Image é uma versão da imagem pronta para gravar.
+type Image struct {
+	Name        string // "original" ou o nome da variante.
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Process valida a imagem e gera o original normalizado e as variantes. O original é
+// regravado já na orientação correta e sem os metadados EXIF (GPS, câmera etc.).
+// Imagens com transparência são gravadas em PNG; as demais, em JPEG.
+func Process(r io.Reader) ([]Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, apperror.Invalid("file", apperror.MsgUploadTooLarge, maxBytes)
+	}
+	contentType := http.DetectContentType(data)
+	if !accepted[contentType] {
+		return nil, apperror.Invalid("file", apperror.MsgUnsupportedImage)
+	}
+
+	// Confere as dimensões pelo cabeçalho antes de decodificar, para uma imagem pequena em
+	// bytes e enorme em pixels não esgotar a memória.
+	if err := checkDimensions(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperror.Invalid("file", apperror.MsgUnsupportedImage)
+	}
+
+	img := toNRGBA(decoded)
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	original, err := encode("original", img, 92)
+	if err != nil {
+		return nil, err
+	}
+	images := []Image{original}
+	for _, spec := range Variants {
+		variant, err := encode(spec.Name, resize(img, spec), 85)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, variant)
+	}
+	return images, nil
+}
+
+// Inspect confere o formato e as dimensões da imagem lendo só o começo do arquivo, sem
+// decodificá-la. Retorna o tipo identificado pelo conteúdo.
+func Inspect(r io.Reader) (string, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(512)
+	contentType := http.DetectContentType(head)
+	if !accepted[contentType] {
+		return "", apperror.Invalid("file", apperror.MsgUnsupportedImage)
+	}
+	if err := checkDimensions(buffered); err != nil {
+		return "", err
+	}
+	return contentType, nil
+}
+
+// checkDimensions lê as dimensões pelo cabeçalho e recusa imagens grandes demais.
+func checkDimensions(r io.Reader) error {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return apperror.Invalid("file", apperror.MsgUnsupportedImage)
+	}
+	if config.Width > maxDimension || config.Height > maxDimension || config.Width*config.Height > maxPixels {
+		return apperror.Invalid("file", apperror.MsgImageDimensions, maxDimension, maxDimension)
+	}
+	return nil
+}
+
+func toNRGBA(src image.Image) *image.NRGBA {
+	if img, ok := src.(*image.NRGBA); ok {
+		return img
+	}
+	bounds := src.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(img, img.Bounds(), src, bounds.Min, draw.Src)
+	return img
+}
+
+// resize reduz a imagem para caber na variante. Imagens menores não são ampliadas.
+func resize(src *image.NRGBA, spec VariantSpec) *image.NRGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	crop := bounds
+	var dw, dh int
+	if spec.Crop {
+		// Recorta ao centro na proporção da variante e depois reduz.
+		if w*spec.Height > h*spec.Width {
+			cw := h * spec.Width / spec.Height
+			crop = image.Rect(bounds.Min.X+(w-cw)/2, bounds.Min.Y, bounds.Min.X+(w-cw)/2+cw, bounds.Max.Y)
+		} else {
+			ch := w * spec.Height / spec.Width
+			crop = image.Rect(bounds.Min.X, bounds.Min.Y+(h-ch)/2, bounds.Max.X, bounds.Min.Y+(h-ch)/2+ch)
+		}
+		dw, dh = min(spec.Width, crop.Dx()), min(spec.Height, crop.Dy())
+	} else {
+		dw, dh = w, h
+		if dw > spec.Width {
+			dw, dh = spec.Width, max(1, h*spec.Width/w)
+		}
+		if dh > spec.Height {
+			dw, dh = max(1, w*spec.Height/h), spec.Height
+		}
+	}
+	if dw == crop.Dx() && dh == crop.Dy() {
+		return src.SubImage(crop).(*image.NRGBA)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	return dst
+}
+
+func encode(name string, img *image.NRGBA, quality int) (Image, error) {
+	var buf bytes.Buffer
+	result := Image{Name: name, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if img.Opaque() {
+		result.ContentType, result.Ext = "image/jpeg", ".jpg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return Image{}, err
+		}
+	} else {
+		result.ContentType, result.Ext = "image/png", ".png"
+		if err := png.Encode(&buf, img); err != nil {
+			return Image{}, err
+		}
+	}
+	result.Data = buf.Bytes()
+	return result, nil
+}
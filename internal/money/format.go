@@ -0,0 +1,99 @@
+/*
+|------------------------------------------------
+| File: internal/money/format.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package money
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultLocale é a localidade dos agentes que não escolheram outra.
+const DefaultLocale = "pt-BR"
+
+// localeFormat diz como uma localidade escreve valores: separadores, posição do símbolo
+// e os símbolos que ela usa de forma diferente do padrão (o dólar é "$" nos EUA).
+type localeFormat struct {
+	group       string
+	decimal     string
+	symbolAfter bool
+	space       bool
+	symbols     map[string]string
+}
+
+var locales = map[string]localeFormat{
+	"pt-BR": {group: ".", decimal: ",", space: true},
+	"pt-PT": {group: ".", decimal: ",", symbolAfter: true, space: true},
+	"en-US": {group: ",", decimal: ".", symbols: map[string]string{"USD": "$"}},
+	"en-GB": {group: ",", decimal: "."},
+	"es-ES": {group: ".", decimal: ",", symbolAfter: true, space: true},
+	"es-AR": {group: ".", decimal: ",", space: true, symbols: map[string]string{"ARS": "$"}},
+}
+
+// symbols são os símbolos das moedas; as demais aparecem pelo código.
+var symbols = map[string]string{
+	"BRL": "R$",
+	"USD": "US$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+}
+
+// Locales lista as localidades aceitas em Format, em ordem alfabética.
+func Locales() []string {
+	codes := make([]string, 0, len(locales))
+	for code := range locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Format escreve o valor para exibição na localidade, como "R$ 1.234,56" em pt-BR ou
+// "$1,234.56" em en-US. Localidades desconhecidas usam DefaultLocale.
+func (m Money) Format(locale string) string {
+	lf, ok := locales[locale]
+	if !ok {
+		lf = locales[DefaultLocale]
+	}
+
+	decimal := m.Decimal()
+	negative := strings.HasPrefix(decimal, "-")
+	decimal = strings.TrimPrefix(decimal, "-")
+	whole, fraction, _ := strings.Cut(decimal, ".")
+	var number strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			number.WriteString(lf.group)
+		}
+		number.WriteRune(digit)
+	}
+	if fraction != "" {
+		number.WriteString(lf.decimal)
+		number.WriteString(fraction)
+	}
+
+	symbol, ok := lf.symbols[m.Currency]
+	if !ok {
+		symbol, ok = symbols[m.Currency]
+	}
+	if !ok {
+		symbol = m.Currency
+	}
+	separator := ""
+	if lf.space || symbol == m.Currency {
+		separator = " "
+	}
+	formatted := symbol + separator + number.String()
+	if lf.symbolAfter {
+		formatted = number.String() + separator + symbol
+	}
+	if negative {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
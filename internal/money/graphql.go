@@ -0,0 +1,94 @@
+/*
+|------------------------------------------------
+| File: internal/money/graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package money
+
+import "github.com/graphql-go/graphql"
+
+// Localized é um valor acompanhado da localidade usada em formatted, normalmente a do
+// agente dono do valor. Um Money sem localidade é formatado em DefaultLocale.
+type Localized struct {
+	Money
+	Locale string
+}
+
+// MoneyType expõe um valor monetário. O valor vai como texto decimal, e não Float, para
+// os clientes não perderem precisão.
+var MoneyType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Money",
+		Fields: graphql.Fields{
+			"amount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Valor decimal com ponto, exato, como \"1234.56\".",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					value, _ := localizedOf(p.Source)
+					return value.Decimal(), nil
+				},
+			},
+			"currency": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Código ISO 4217 da moeda.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					value, _ := localizedOf(p.Source)
+					return value.Currency, nil
+				},
+			},
+			"formatted": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Valor pronto para exibição na localidade do agente, como \"R$ 1.234,56\".",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					value, _ := localizedOf(p.Source)
+					return value.Format(value.Locale), nil
+				},
+			},
+		},
+	},
+)
+
+// MoneyInputType recebe um valor monetário. O decimal vem como texto para ser lido sem
+// arredondamento; mais casas do que a moeda admite são recusadas.
+var MoneyInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "MoneyInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"amount":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "Decimal com ponto, como \"10.90\"."},
+			"currency": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), Description: "Código ISO 4217, como \"BRL\"."},
+		},
+	},
+)
+
+// InputFromArgs lê um MoneyInput já coerido pelo graphql-go.
+func InputFromArgs(value interface{}) (Input, bool) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return Input{}, false
+	}
+	amount, _ := fields["amount"].(string)
+	currency, _ := fields["currency"].(string)
+	return Input{Amount: amount, Currency: currency}, true
+}
+
+func localizedOf(source interface{}) (Localized, bool) {
+	switch v := source.(type) {
+	case Localized:
+		return v, true
+	case *Localized:
+		if v == nil {
+			return Localized{}, false
+		}
+		return *v, true
+	case Money:
+		return Localized{Money: v, Locale: DefaultLocale}, true
+	case *Money:
+		if v == nil {
+			return Localized{}, false
+		}
+		return Localized{Money: *v, Locale: DefaultLocale}, true
+	}
+	return Localized{}, false
+}
@@ -0,0 +1,192 @@
+/*
+|------------------------------------------------
+| File: internal/money/money.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency é a moeda dos valores gravados antes de existir o campo de moeda.
+const DefaultCurrency = "BRL"
+
+// Money é um valor monetário exato: Amount em unidades menores da moeda (centavos, no
+// real) e Currency no código ISO 4217. Nunca passa por float, então R$ 0,10 + R$ 0,20
+// dá exatamente R$ 0,30.
+type Money struct {
+	Amount   int64  `gorm:"not null;default:0" json:"amount"`
+	Currency string `gorm:"size:3;not null;default:'BRL'" json:"currency"`
+}
+
+// currencies são as moedas aceitas e quantas casas decimais cada uma tem.
+var currencies = map[string]int{
+	"BRL": 2,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"ARS": 2,
+	"UYU": 2,
+	"MXN": 2,
+	"COP": 2,
+	"PEN": 2,
+	"BOB": 2,
+	"CLP": 0,
+	"PYG": 0,
+	"JPY": 0,
+	"KWD": 3,
+	"BHD": 3,
+}
+
+var (
+	ErrInvalidAmount   = errors.New("valor monetário inválido")
+	ErrUnknownCurrency = errors.New("moeda não suportada")
+)
+
+// DecimalsError indica um valor com mais casas decimais do que a moeda admite.
+type DecimalsError struct {
+	Currency string
+	Max      int
+}
+
+func (e *DecimalsError) Error() string {
+	return fmt.Sprintf("a moeda %s admite no máximo %d casas decimais", e.Currency, e.Max)
+}
+
+// Decimals retorna quantas casas decimais a moeda tem.
+func Decimals(currency string) (int, bool) {
+	decimals, ok := currencies[currency]
+	return decimals, ok
+}
+
+// Currencies lista os códigos das moedas aceitas, em ordem alfabética.
+func Currencies() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Input é um valor como enviado pelo cliente: o decimal em texto ("1234.56") e a moeda.
+type Input struct {
+	Amount   string
+	Currency string
+}
+
+// Parse converte o Input em Money.
+func (i Input) Parse() (Money, error) {
+	return Parse(i.Amount, i.Currency)
+}
+
+// Parse lê um decimal com ponto, como "1234.56", na moeda informada. Zeros à direita
+// são ignorados, mas um valor com mais casas do que a moeda admite é recusado com
+// *DecimalsError, em vez de arredondado.
+func Parse(amount, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	decimals, ok := currencies[currency]
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(fraction)) {
+		return Money{}, ErrInvalidAmount
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > decimals {
+		return Money{}, &DecimalsError{Currency: currency, Max: decimals}
+	}
+	fraction += strings.Repeat("0", decimals-len(fraction))
+
+	value, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		value = -value
+	}
+	return Money{Amount: value, Currency: currency}, nil
+}
+
+// FromFloat converte um valor antigo, guardado como float, arredondando para as casas
+// da moeda. Só serve para dados legados; valores novos devem vir por Parse.
+//
+// O arredondamento é feito sobre o decimal que o float representa ("1.005"), metade para
+// longe do zero, como o round(price::numeric * 100) da migração do preço: multiplicar o
+// float daria 100.49999... e perderia o centavo.
+func FromFloat(value float64, currency string) (Money, error) {
+	decimals, ok := currencies[currency]
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Money{}, ErrInvalidAmount
+	}
+	whole, fraction, _ := strings.Cut(strconv.FormatFloat(math.Abs(value), 'f', -1, 64), ".")
+	roundUp := len(fraction) > decimals && fraction[decimals] >= '5'
+	if len(fraction) > decimals {
+		fraction = fraction[:decimals]
+	}
+	fraction += strings.Repeat("0", decimals-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if roundUp {
+		if amount == math.MaxInt64 {
+			return Money{}, ErrInvalidAmount
+		}
+		amount++
+	}
+	if value < 0 {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Decimal devolve o valor como decimal com ponto, no formato aceito por Parse.
+func (m Money) Decimal() string {
+	decimals := currencies[m.Currency]
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if decimals == 0 {
+		return sign + digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	point := len(digits) - decimals
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
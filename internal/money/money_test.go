@@ -0,0 +1,148 @@
+/*
+|------------------------------------------------
+| File: internal/money/money_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// moneyCase é um caso das tabelas: o valor esperado, ou o erro esperado.
+type moneyCase[T any] struct {
+	name  string
+	value T
+	want  Money
+	err   error
+}
+
+// checkMoney compara o resultado com o caso. Para *DecimalsError basta o tipo.
+func checkMoney[T any](t *testing.T, tc moneyCase[T], got Money, err error) {
+	t.Helper()
+	var decimals *DecimalsError
+	switch {
+	case tc.err == nil && err != nil:
+		t.Fatalf("esperava %+v, veio o erro %v", tc.want, err)
+	case tc.err != nil && err == nil:
+		t.Fatalf("esperava o erro %v, veio %+v", tc.err, got)
+	case errors.As(tc.err, &decimals):
+		var gotDecimals *DecimalsError
+		if !errors.As(err, &gotDecimals) || *gotDecimals != *decimals {
+			t.Fatalf("esperava %v, veio %v", tc.err, err)
+		}
+	case tc.err != nil && !errors.Is(err, tc.err):
+		t.Fatalf("esperava o erro %v, veio %v", tc.err, err)
+	case tc.err == nil && got != tc.want:
+		t.Fatalf("esperava %+v, veio %+v", tc.want, got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	type input struct{ amount, currency string }
+	cases := []moneyCase[input]{
+		{"reais com centavos", input{"1234.56", "BRL"}, Money{123456, "BRL"}, nil},
+		{"sem casas", input{"10", "BRL"}, Money{1000, "BRL"}, nil},
+		{"uma casa", input{"0.5", "BRL"}, Money{50, "BRL"}, nil},
+		{"zero", input{"0", "BRL"}, Money{0, "BRL"}, nil},
+		{"negativo", input{"-12.34", "BRL"}, Money{-1234, "BRL"}, nil},
+		{"sinal de mais", input{"+1.00", "BRL"}, Money{100, "BRL"}, nil},
+		{"espaços em volta", input{" 3.10 ", " brl "}, Money{310, "BRL"}, nil},
+		{"zeros à direita são ignorados", input{"1.500000", "BRL"}, Money{150, "BRL"}, nil},
+		{"casas demais", input{"1.005", "BRL"}, Money{}, &DecimalsError{Currency: "BRL", Max: 2}},
+		{"CLP sem casas", input{"1500", "CLP"}, Money{1500, "CLP"}, nil},
+		{"CLP com zeros à direita", input{"1500.00", "CLP"}, Money{1500, "CLP"}, nil},
+		{"CLP com centavos", input{"1500.5", "CLP"}, Money{}, &DecimalsError{Currency: "CLP", Max: 0}},
+		{"JPY sem casas", input{"980", "JPY"}, Money{980, "JPY"}, nil},
+		{"JPY com centavos", input{"980.1", "JPY"}, Money{}, &DecimalsError{Currency: "JPY", Max: 0}},
+		{"KWD com três casas", input{"1.234", "KWD"}, Money{1234, "KWD"}, nil},
+		{"KWD com duas casas", input{"1.23", "KWD"}, Money{1230, "KWD"}, nil},
+		{"KWD com quatro casas", input{"1.2345", "KWD"}, Money{}, &DecimalsError{Currency: "KWD", Max: 3}},
+		{"maior int64", input{"92233720368547758.07", "BRL"}, Money{math.MaxInt64, "BRL"}, nil},
+		{"estouro do int64", input{"92233720368547758.08", "BRL"}, Money{}, ErrInvalidAmount},
+		{"estouro do int64 sem casas", input{"99999999999999999999", "JPY"}, Money{}, ErrInvalidAmount},
+		{"vírgula decimal", input{"1,50", "BRL"}, Money{}, ErrInvalidAmount},
+		{"vazio", input{"", "BRL"}, Money{}, ErrInvalidAmount},
+		{"só o sinal", input{"-", "BRL"}, Money{}, ErrInvalidAmount},
+		{"ponto sem casas", input{"1.", "BRL"}, Money{}, ErrInvalidAmount},
+		{"ponto sem inteiro", input{".5", "BRL"}, Money{}, ErrInvalidAmount},
+		{"dois sinais", input{"--1", "BRL"}, Money{}, ErrInvalidAmount},
+		{"expoente", input{"1e3", "BRL"}, Money{}, ErrInvalidAmount},
+		{"moeda desconhecida", input{"1.00", "XYZ"}, Money{}, ErrUnknownCurrency},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.value.amount, tc.value.currency)
+			checkMoney(t, tc, got, err)
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	cases := []struct {
+		name  string
+		value Money
+		want  string
+	}{
+		{"reais com centavos", Money{123456, "BRL"}, "1234.56"},
+		{"só centavos", Money{5, "BRL"}, "0.05"},
+		{"zero", Money{0, "BRL"}, "0.00"},
+		{"negativo", Money{-1234, "BRL"}, "-12.34"},
+		{"negativo abaixo de um real", Money{-5, "BRL"}, "-0.05"},
+		{"CLP sem casas", Money{1500, "CLP"}, "1500"},
+		{"JPY negativo", Money{-980, "JPY"}, "-980"},
+		{"KWD com três casas", Money{1234, "KWD"}, "1.234"},
+		{"KWD abaixo de um", Money{7, "KWD"}, "0.007"},
+		{"maior int64", Money{math.MaxInt64, "BRL"}, "92233720368547758.07"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.value.Decimal()
+			if got != tc.want {
+				t.Fatalf("esperava %q, veio %q", tc.want, got)
+			}
+			// O texto de Decimal volta para o mesmo valor por Parse.
+			back, err := Parse(got, tc.value.Currency)
+			if err != nil || back != tc.value {
+				t.Fatalf("Parse(%q) deveria voltar %+v, veio %+v (%v)", got, tc.value, back, err)
+			}
+		})
+	}
+}
+
+// Os casos em reais são os mesmos que a migração do preço (product.PriceMigrations)
+// converte com round(price::numeric * 100); os dois caminhos devem dar o mesmo valor.
+func TestFromFloat(t *testing.T) {
+	type input struct {
+		value    float64
+		currency string
+	}
+	cases := []moneyCase[input]{
+		{"reais com centavos", input{49.9, "BRL"}, Money{4990, "BRL"}, nil},
+		{"sem casas", input{10, "BRL"}, Money{1000, "BRL"}, nil},
+		{"zero", input{0, "BRL"}, Money{0, "BRL"}, nil},
+		{"soma de floats", input{0.1 + 0.2, "BRL"}, Money{30, "BRL"}, nil},
+		{"meio centavo arredonda para cima", input{1.005, "BRL"}, Money{101, "BRL"}, nil},
+		{"meio centavo abaixo de um real", input{0.285, "BRL"}, Money{29, "BRL"}, nil},
+		{"abaixo do meio centavo", input{2.344, "BRL"}, Money{234, "BRL"}, nil},
+		{"arredondamento que sobe o real", input{9.995, "BRL"}, Money{1000, "BRL"}, nil},
+		{"negativo arredonda para longe do zero", input{-1.005, "BRL"}, Money{-101, "BRL"}, nil},
+		{"CLP arredonda para o inteiro", input{1500.5, "CLP"}, Money{1501, "CLP"}, nil},
+		{"JPY abaixo do meio", input{980.4, "JPY"}, Money{980, "JPY"}, nil},
+		{"KWD com três casas", input{1.2345, "KWD"}, Money{1235, "KWD"}, nil},
+		{"estouro do int64", input{1e18, "BRL"}, Money{}, ErrInvalidAmount},
+		{"NaN", input{math.NaN(), "BRL"}, Money{}, ErrInvalidAmount},
+		{"infinito", input{math.Inf(1), "BRL"}, Money{}, ErrInvalidAmount},
+		{"moeda desconhecida", input{1, "XYZ"}, Money{}, ErrUnknownCurrency},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FromFloat(tc.value.value, tc.value.currency)
+			checkMoney(t, tc, got, err)
+		})
+	}
+}
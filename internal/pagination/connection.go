@@ -0,0 +1,88 @@
+/*
+|------------------------------------------------
+| File: internal/pagination/connection.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package pagination
+
+// PageInfo segue a especificação de conexões do Relay.
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+type Edge[T any] struct {
+	Cursor string `json:"cursor"`
+	Node   T      `json:"node"`
+}
+
+// Connection é uma página de resultados. O total só é calculado se o campo
+// totalCount for pedido na consulta.
+type Connection[T any] struct {
+	Edges    []Edge[T] `json:"edges"`
+	PageInfo PageInfo  `json:"pageInfo"`
+	count    func() (int64, error)
+}
+
+// Counter é implementado por toda Connection; usado pelo resolver de totalCount.
+type Counter interface {
+	TotalCount() (int64, error)
+}
+
+// NewConnection monta a página a partir dos registros lidos com Page.Scope (até
+// Limit+1, na ordem da leitura). cursorOf gera o cursor de cada registro.
+func NewConnection[T any](items []T, page Page, cursorOf func(T) Cursor) Connection[T] {
+	hasMore := len(items) > page.Limit
+	if hasMore {
+		items = items[:page.Limit]
+	}
+	if page.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	conn := Connection[T]{Edges: make([]Edge[T], 0, len(items))}
+	for _, item := range items {
+		conn.Edges = append(conn.Edges, Edge[T]{Cursor: cursorOf(item).Encode(), Node: item})
+	}
+	if page.Backward {
+		conn.PageInfo.HasPreviousPage = hasMore
+		conn.PageInfo.HasNextPage = page.Before != nil
+	} else {
+		conn.PageInfo.HasNextPage = hasMore
+		conn.PageInfo.HasPreviousPage = page.After != nil
+	}
+	if len(conn.Edges) > 0 {
+		start, end := conn.Edges[0].Cursor, conn.Edges[len(conn.Edges)-1].Cursor
+		conn.PageInfo.StartCursor = &start
+		conn.PageInfo.EndCursor = &end
+	}
+	return conn
+}
+
+// WithCount define como calcular o total de registros da lista.
+func (c Connection[T]) WithCount(count func() (int64, error)) Connection[T] {
+	c.count = count
+	return c
+}
+
+func (c Connection[T]) TotalCount() (int64, error) {
+	if c.count == nil {
+		return int64(len(c.Edges)), nil
+	}
+	return c.count()
+}
+
+// Nodes retorna apenas os registros da página.
+func (c Connection[T]) Nodes() []T {
+	nodes := make([]T, 0, len(c.Edges))
+	for _, edge := range c.Edges {
+		nodes = append(nodes, edge.Node)
+	}
+	return nodes
+}
@@ -0,0 +1,52 @@
+/*
+|------------------------------------------------
+| File: internal/pagination/cursor.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+)
+
+// ErrInvalidCursor é retornado quando o cliente envia um cursor que não foi gerado pela API.
+var ErrInvalidCursor = apperror.New(apperror.CodeValidation, apperror.MsgInvalidCursor)
+
+// Cursor é a posição de um registro na ordenação da lista: o valor da coluna ordenada
+// e o ID, que desempata. Guarda também a ordenação em que foi gerado, para recusar o
+// cursor numa lista com outra ordenação. Para o cliente ele é uma string opaca.
+type Cursor struct {
+	ID     uint        `json:"id"`
+	Value  interface{} `json:"v,omitempty"`
+	Column string      `json:"s"`
+	Desc   bool        `json:"d,omitempty"`
+}
+
+// Matches informa se o cursor foi gerado com a ordenação sort.
+func (c Cursor) Matches(sort Sort) bool {
+	return c.Column == sort.Column && c.Desc == sort.Desc
+}
+
+// Encode serializa o cursor em base64.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor lê um cursor gerado por Encode.
+func DecodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,50 @@
+/*
+|------------------------------------------------
+| File: internal/pagination/filter.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package pagination
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TimeRange filtra uma coluna de data. Os limites são inclusivos e opcionais.
+type TimeRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// Scope aplica o intervalo à coluna. A coluna vem sempre do código, nunca do cliente.
+func (r TimeRange) Scope(column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if r.From != nil {
+			db = db.Where(column+" >= ?", *r.From)
+		}
+		if r.To != nil {
+			db = db.Where(column+" <= ?", *r.To)
+		}
+		return db
+	}
+}
+
+// ContainsPattern monta o padrão de ILIKE para "contém text", escapando os curingas.
+func ContainsPattern(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(strings.TrimSpace(text)) + "%"
+}
+
+// Allowed retorna a ordenação se a coluna estiver entre as permitidas, ou SortByID.
+func (s Sort) Allowed(columns ...string) Sort {
+	for _, column := range columns {
+		if s.Column == column {
+			return s
+		}
+	}
+	return Sort{Column: "id", Desc: s.Desc}
+}
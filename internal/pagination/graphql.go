@@ -0,0 +1,150 @@
+/*
+|------------------------------------------------
+| File: internal/pagination/graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package pagination
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+)
+
+var pageInfoType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"hasPreviousPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"startCursor":     &graphql.Field{Type: graphql.String},
+			"endCursor":       &graphql.Field{Type: graphql.String},
+		},
+	},
+)
+
+// ConnectionType cria os tipos <name>Connection e <name>Edge para o tipo node.
+func ConnectionType(name string, node graphql.Output) *graphql.Object {
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Edge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: node},
+		},
+	})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Connection",
+		Fields: graphql.Fields{
+			"edges":    &graphql.Field{Type: graphql.NewList(edgeType)},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Total de registros da lista. Só é calculado quando pedido.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if counter, ok := p.Source.(Counter); ok {
+						return counter.TotalCount()
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+}
+
+// Args acrescenta a args os argumentos de paginação no estilo Relay.
+func Args(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	if args == nil {
+		args = graphql.FieldConfigArgument{}
+	}
+	args["first"] = &graphql.ArgumentConfig{Type: graphql.Int, Description: "Quantidade de registros a partir do início (ou de after)."}
+	args["after"] = &graphql.ArgumentConfig{Type: graphql.String}
+	args["last"] = &graphql.ArgumentConfig{Type: graphql.Int, Description: "Quantidade de registros a partir do fim (ou de before)."}
+	args["before"] = &graphql.ArgumentConfig{Type: graphql.String}
+	return args
+}
+
+// PageFromArgs lê os argumentos criados por Args.
+func PageFromArgs(args map[string]interface{}) (Page, error) {
+	var first, last *int
+	if v, ok := args["first"].(int); ok {
+		first = &v
+	}
+	if v, ok := args["last"].(int); ok {
+		last = &v
+	}
+	after, _ := args["after"].(string)
+	before, _ := args["before"].(string)
+	return NewPage(first, last, after, before)
+}
+
+var sortDirectionEnum = graphql.NewEnum(
+	graphql.EnumConfig{
+		Name: "SortDirection",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  &graphql.EnumValueConfig{Value: "ASC"},
+			"DESC": &graphql.EnumValueConfig{Value: "DESC"},
+		},
+	},
+)
+
+// TimeRangeInput filtra por intervalo de datas (RFC 3339).
+var TimeRangeInput = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "TimeRangeInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"from": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"to":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	},
+)
+
+// SortInput cria o input <name>Sort. Cada valor de columns é o nome de uma coluna do
+// banco; como o campo é um enum, o cliente só consegue escolher entre elas.
+func SortInput(name string, columns graphql.EnumValueConfigMap) *graphql.InputObject {
+	fieldEnum := graphql.NewEnum(graphql.EnumConfig{Name: name + "SortField", Values: columns})
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: name + "Sort",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(fieldEnum)},
+			"direction": &graphql.InputObjectFieldConfig{Type: sortDirectionEnum, DefaultValue: "ASC"},
+		},
+	})
+}
+
+// SortFromArgs lê o argumento sort criado com SortInput. Sem ele, ordena por ID.
+func SortFromArgs(args map[string]interface{}) Sort {
+	input, ok := args["sort"].(map[string]interface{})
+	if !ok {
+		return SortByID
+	}
+	column, _ := input["field"].(string)
+	if column == "" {
+		column = "id"
+	}
+	direction, _ := input["direction"].(string)
+	return Sort{Column: column, Desc: direction == "DESC"}
+}
+
+// TimeRangeFromArgs lê um TimeRangeInput.
+func TimeRangeFromArgs(value interface{}) (TimeRange, error) {
+	var r TimeRange
+	input, ok := value.(map[string]interface{})
+	if !ok {
+		return r, nil
+	}
+	for key, target := range map[string]**time.Time{"from": &r.From, "to": &r.To} {
+		text, ok := input[key].(string)
+		if !ok || text == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return TimeRange{}, apperror.Invalid(key, apperror.MsgInvalidDate)
+		}
+		*target = &t
+	}
+	return r, nil
+}
@@ -0,0 +1,161 @@
+/*
+|------------------------------------------------
+| File: internal/pagination/page.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package pagination
+
+import (
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"gorm.io/gorm"
+)
+
+// Limites do tamanho de página, ajustáveis na inicialização por SetLimits.
+var (
+	defaultSize = 8
+	maxSize     = 100
+)
+
+// SetLimits define o tamanho de página usado quando o cliente não informa first/last
+// e o máximo aceito.
+func SetLimits(defaultPageSize, maxPageSize int) {
+	if maxPageSize > 0 {
+		maxSize = maxPageSize
+	}
+	if defaultPageSize > 0 {
+		defaultSize = defaultPageSize
+	}
+	if defaultSize > maxSize {
+		defaultSize = maxSize
+	}
+}
+
+// Page é uma requisição de página no estilo Relay, já validada.
+type Page struct {
+	Limit    int
+	After    *Cursor
+	Before   *Cursor
+	Backward bool // Pedido com last: a página é contada a partir do fim.
+}
+
+// NewPage valida first/after/last/before. first e last não podem ser usados juntos.
+func NewPage(first, last *int, after, before string) (Page, error) {
+	if first != nil && last != nil {
+		return Page{}, apperror.Invalid("last", apperror.MsgFirstAndLast)
+	}
+	page := Page{Limit: defaultSize}
+	if first != nil {
+		page.Limit = *first
+	}
+	if last != nil {
+		page.Limit = *last
+		page.Backward = true
+	}
+	if page.Limit <= 0 || page.Limit > maxSize {
+		field := "first"
+		if page.Backward {
+			field = "last"
+		}
+		return Page{}, apperror.Invalid(field, apperror.MsgPageSize, maxSize)
+	}
+
+	var err error
+	if after != "" {
+		if page.After, err = DecodeCursor(after); err != nil {
+			return Page{}, apperror.Invalid("after", apperror.MsgInvalidCursor)
+		}
+	}
+	if before != "" {
+		if page.Before, err = DecodeCursor(before); err != nil {
+			return Page{}, apperror.Invalid("before", apperror.MsgInvalidCursor)
+		}
+	}
+	return page, nil
+}
+
+// First cria uma página simples com os primeiros limit registros.
+func First(limit int) Page {
+	return Page{Limit: limit}
+}
+
+// Sort é a ordenação da lista. O ID é sempre usado como desempate.
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// SortByID é a ordenação padrão das listas.
+var SortByID = Sort{Column: "id"}
+
+// Cursor cria o cursor do registro id nesta ordenação. O valor da coluna ordenada, se
+// não for o ID, fica a cargo de quem chama.
+func (s Sort) Cursor(id uint) Cursor {
+	return Cursor{ID: id, Column: s.Column, Desc: s.Desc}
+}
+
+// Check recusa cursores gerados com outra ordenação: o valor guardado neles não seria
+// da coluna ordenada agora.
+func (p Page) Check(sort Sort) error {
+	if p.After != nil && !p.After.Matches(sort) {
+		return apperror.Invalid("after", apperror.MsgInvalidCursor)
+	}
+	if p.Before != nil && !p.Before.Matches(sort) {
+		return apperror.Invalid("before", apperror.MsgInvalidCursor)
+	}
+	return nil
+}
+
+// Scope aplica a ordenação, o filtro de keyset e o limite da página. Busca um registro
+// a mais que Limit, para NewConnection saber se existe outra página. Um cursor de outra
+// ordenação vira o erro da consulta (ver Check).
+func (p Page) Scope(sort Sort) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if err := p.Check(sort); err != nil {
+			db.AddError(err)
+			return db
+		}
+		if p.After != nil {
+			db = keyset(db, sort, *p.After, !sort.Desc)
+		}
+		if p.Before != nil {
+			db = keyset(db, sort, *p.Before, sort.Desc)
+		}
+		// Pedidos com last leem na ordem inversa; NewConnection desfaz a inversão.
+		desc := sort.Desc != p.Backward
+		direction := "ASC"
+		if desc {
+			direction = "DESC"
+		}
+		if sort.Column != "id" {
+			db = db.Order(sort.Column + " " + direction)
+		}
+		return db.Order("id " + direction).Limit(p.Limit + 1)
+	}
+}
+
+// keyset restringe a lista aos registros depois (greater=true) ou antes do cursor.
+func keyset(db *gorm.DB, sort Sort, cursor Cursor, greater bool) *gorm.DB {
+	op := "<"
+	if greater {
+		op = ">"
+	}
+	if sort.Column == "id" || cursor.Value == nil {
+		return db.Where("id "+op+" ?", cursor.ID)
+	}
+	return db.Where(
+		"("+sort.Column+" "+op+" ? OR ("+sort.Column+" = ? AND id "+op+" ?))",
+		cursor.Value, cursor.Value, cursor.ID,
+	)
+}
+
+// DefaultSize é o tamanho de página usado quando o cliente não informa first/last.
+func DefaultSize() int {
+	return defaultSize
+}
+
+// MaxSize é o maior first/last aceito.
+func MaxSize() int {
+	return maxSize
+}
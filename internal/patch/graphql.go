@@ -0,0 +1,108 @@
+/*
+|------------------------------------------------
+| File: internal/patch/graphql.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package patch
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// O graphql-go descarta argumentos nulos antes de chamar o resolver, então não dá para
+// diferenciar um campo omitido de um null explícito olhando só p.Args. Por isso o handler
+// HTTP guarda as variáveis originais da requisição no contexto, e Input cruza essas
+// variáveis com o AST da consulta.
+
+type rawVariablesKey struct{}
+
+// WithRawVariables guarda no contexto as variáveis exatamente como o cliente as enviou.
+func WithRawVariables(ctx context.Context, variables map[string]interface{}) context.Context {
+	return context.WithValue(ctx, rawVariablesKey{}, variables)
+}
+
+func rawVariables(ctx context.Context) (map[string]interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	variables, ok := ctx.Value(rawVariablesKey{}).(map[string]interface{})
+	return variables, ok
+}
+
+// Input são os campos de um argumento do tipo input object, sabendo quais foram enviados.
+type Input struct {
+	values  map[string]interface{}
+	present map[string]bool
+}
+
+// InputFromArgs lê o argumento argName (um input object) do resolver.
+func InputFromArgs(p graphql.ResolveParams, argName string) Input {
+	in := Input{present: map[string]bool{}}
+	in.values, _ = p.Args[argName].(map[string]interface{})
+	if in.values == nil {
+		in.values = map[string]interface{}{}
+	}
+
+	raw, hasRaw := rawVariables(p.Context)
+	argValue := findArgument(p, argName)
+
+	switch value := argValue.(type) {
+	case *ast.Variable:
+		// input: $input — as chaves do JSON original dizem o que foi enviado.
+		if object, ok := raw[value.Name.Value].(map[string]interface{}); hasRaw && ok {
+			for name := range object {
+				in.present[name] = true
+			}
+			return in
+		}
+	case *ast.ObjectValue:
+		// input: { name: "x", description: $desc } — cada campo pode ser uma variável.
+		for _, field := range value.Fields {
+			if field == nil || field.Name == nil {
+				continue
+			}
+			if variable, ok := field.Value.(*ast.Variable); ok && hasRaw {
+				if _, sent := raw[variable.Name.Value]; !sent {
+					continue
+				}
+			}
+			in.present[field.Name.Value] = true
+		}
+		return in
+	}
+
+	// Sem as variáveis originais, só é possível saber o que chegou com valor.
+	for name := range in.values {
+		in.present[name] = true
+	}
+	return in
+}
+
+func findArgument(p graphql.ResolveParams, argName string) ast.Value {
+	if len(p.Info.FieldASTs) == 0 {
+		return nil
+	}
+	for _, arg := range p.Info.FieldASTs[0].Arguments {
+		if arg != nil && arg.Name != nil && arg.Name.Value == argName {
+			return arg.Value
+		}
+	}
+	return nil
+}
+
+// Get monta o Field de um campo do input. Um campo enviado sem valor é um null explícito.
+func Get[T any](in Input, name string) Field[T] {
+	if !in.present[name] {
+		return Field[T]{}
+	}
+	value, ok := in.values[name].(T)
+	if !ok {
+		return Field[T]{Set: true, Null: true}
+	}
+	return Field[T]{Set: true, Value: value}
+}
@@ -0,0 +1,57 @@
+/*
+|------------------------------------------------
+| File: internal/patch/patch.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package patch
+
+import "github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+
+// Field é um campo de uma atualização parcial. Set indica que o cliente enviou o campo;
+// Null, que ele enviou null explicitamente. Campos com Set falso não são alterados.
+type Field[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// Of cria um Field preenchido com value.
+func Of[T any](value T) Field[T] {
+	return Field[T]{Set: true, Value: value}
+}
+
+// Changes são as colunas alteradas por uma atualização parcial, prontas para o Updates do GORM.
+type Changes map[string]interface{}
+
+// Nullable registra a coluna se o campo foi enviado. Null limpa a coluna (valor zero do tipo).
+func Nullable[T any](changes Changes, column string, field Field[T]) {
+	if !field.Set {
+		return
+	}
+	if field.Null {
+		var zero T
+		changes[column] = zero
+		return
+	}
+	changes[column] = field.Value
+}
+
+// Required registra a coluna se o campo foi enviado e recusa null, pois a coluna é obrigatória.
+func Required[T any](changes Changes, column string, field Field[T]) error {
+	if field.Set && field.Null {
+		return apperror.Invalid(column, apperror.MsgFieldNotNull)
+	}
+	Nullable(changes, column, field)
+	return nil
+}
+
+// Map converte o valor de um Field, mantendo Set e Null.
+func Map[T, U any](field Field[T], convert func(T) U) Field[U] {
+	mapped := Field[U]{Set: field.Set, Null: field.Null}
+	if field.Set && !field.Null {
+		mapped.Value = convert(field.Value)
+	}
+	return mapped
+}
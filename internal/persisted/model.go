@@ -0,0 +1,29 @@
+/*
+|------------------------------------------------
+| File: internal/persisted/model.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package persisted
+
+import "time"
+
+const (
+	SourceAPQ      = "apq"      // Registrada pelo cliente no protocolo de Automatic Persisted Queries.
+	SourceManifest = "manifest" // Operação publicada no manifesto dos aplicativos.
+)
+
+// Query é uma operação GraphQL identificada pelo SHA-256 do seu texto.
+type Query struct {
+	Hash          string    `gorm:"primaryKey;size:64" json:"hash"`
+	Query         string    `gorm:"type:text;not null" json:"query"`
+	OperationName string    `json:"operation_name"`
+	Source        string    `gorm:"not null;index" json:"source"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName evita o nome genérico "queries".
+func (Query) TableName() string {
+	return "persisted_queries"
+}
@@ -0,0 +1,45 @@
+/*
+|------------------------------------------------
+| File: internal/persisted/repository.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package persisted
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	FindByHash(hash string) (Query, error)
+	Save(query Query) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) FindByHash(hash string) (Query, error) {
+	var query Query
+	err := r.db.Where("hash = ?", hash).First(&query).Error
+	return query, err
+}
+
+// Save grava a operação. Uma operação do manifesto substitui um registro APQ com o mesmo
+// hash; o contrário não acontece, para um cliente não rebaixar uma operação publicada.
+func (r *repository) Save(query Query) error {
+	update := clause.OnConflict{DoNothing: true}
+	if query.Source == SourceManifest {
+		update = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"operation_name", "source"}),
+		}
+	}
+	return r.db.Clauses(update).Create(&query).Error
+}
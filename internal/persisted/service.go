@@ -0,0 +1,163 @@
+/*
+|------------------------------------------------
+| File: internal/persisted/service.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package persisted
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	// Lookup retorna a operação registrada com o hash. Em modo estrito só as operações
+	// do manifesto são encontradas.
+	Lookup(hash string) (Query, bool, error)
+	// Register guarda uma operação enviada pelo cliente junto com o hash.
+	Register(hash, query, operationName string) error
+	// LoadManifest registra as operações de um arquivo de manifesto e retorna quantas eram.
+	LoadManifest(path string) (int, error)
+	// Strict informa se só as operações do manifesto podem ser executadas.
+	Strict() bool
+}
+
+// MaxQueryLength é o maior texto de operação, em bytes, aceito num registro APQ.
+const MaxQueryLength = 16 << 10
+
+// maxCached limita as operações APQ mantidas em memória; as demais são lidas do Postgres.
+const maxCached = 10000
+
+type service struct {
+	repo   Repository
+	strict bool
+
+	mu    sync.RWMutex
+	cache map[string]Query
+}
+
+// NewService cria o serviço de operações persistidas. Com strict, o endpoint só executa
+// operações do manifesto e deixa de aceitar registros automáticos.
+func NewService(repo Repository, strict bool) Service {
+	return &service{repo: repo, strict: strict, cache: map[string]Query{}}
+}
+
+// Hash retorna o SHA-256 em hexadecimal do texto da operação, como no protocolo APQ.
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *service) Strict() bool {
+	return s.strict
+}
+
+func (s *service) Lookup(hash string) (Query, bool, error) {
+	hash = strings.ToLower(hash)
+	s.mu.RLock()
+	query, ok := s.cache[hash]
+	s.mu.RUnlock()
+	if !ok {
+		found, err := s.repo.FindByHash(hash)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Query{}, false, nil
+		}
+		if err != nil {
+			return Query{}, false, err
+		}
+		query = found
+		s.remember(query)
+	}
+	if s.strict && query.Source != SourceManifest {
+		return Query{}, false, nil
+	}
+	return query, true, nil
+}
+
+func (s *service) Register(hash, query, operationName string) error {
+	if s.strict {
+		return errors.New("registro automático desativado no modo estrito")
+	}
+	if len(query) > MaxQueryLength {
+		return fmt.Errorf("a operação tem %d bytes; o máximo para registro é %d", len(query), MaxQueryLength)
+	}
+	if Hash(query) != strings.ToLower(hash) {
+		return fmt.Errorf("o hash %s não corresponde ao texto da operação", hash)
+	}
+	record := Query{Hash: Hash(query), Query: query, OperationName: operationName, Source: SourceAPQ}
+	if err := s.repo.Save(record); err != nil {
+		return err
+	}
+	s.remember(record)
+	return nil
+}
+
+// manifestFile aceita o manifesto no formato gerado pelo Apollo
+// ({"operations": [{"id", "name", "body"}]}) ou um objeto simples hash -> operação.
+type manifestFile struct {
+	Operations []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// LoadManifest lê o arquivo e grava as operações no Postgres, para que as instâncias
+// iniciadas sem o arquivo também as conheçam. O hash de cada entrada é conferido.
+func (s *service) LoadManifest(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var queries []Query
+	var manifest manifestFile
+	if err := json.Unmarshal(data, &manifest); err == nil && manifest.Operations != nil {
+		for _, op := range manifest.Operations {
+			queries = append(queries, Query{Hash: op.ID, Query: op.Body, OperationName: op.Name})
+		}
+	} else {
+		var byHash map[string]string
+		if err := json.Unmarshal(data, &byHash); err != nil {
+			return 0, fmt.Errorf("manifesto inválido: %w", err)
+		}
+		for hash, body := range byHash {
+			queries = append(queries, Query{Hash: hash, Query: body})
+		}
+	}
+
+	for _, query := range queries {
+		if Hash(query.Query) != strings.ToLower(query.Hash) {
+			return 0, fmt.Errorf("manifesto inválido: o hash %s não corresponde à operação %q", query.Hash, query.OperationName)
+		}
+		query.Hash = strings.ToLower(query.Hash)
+		query.Source = SourceManifest
+		if err := s.repo.Save(query); err != nil {
+			return 0, err
+		}
+		s.remember(query)
+	}
+	return len(queries), nil
+}
+
+func (s *service) remember(query Query) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Uma operação do manifesto nunca é substituída por um registro APQ.
+	if cached, ok := s.cache[query.Hash]; ok && cached.Source == SourceManifest {
+		return
+	}
+	if query.Source == SourceAPQ && len(s.cache) >= maxCached {
+		return
+	}
+	s.cache[query.Hash] = query
+}
@@ -0,0 +1,87 @@
+/*
+|------------------------------------------------
+| File: internal/session/session.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package session
+
+import (
+	"context"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+)
+
+// Papéis de usuário. RoleAdmin é o dos administradores da plataforma, que atuam sobre qualquer agente.
+const (
+	RoleMember = "member"
+	RoleAdmin  = "admin"
+)
+
+var (
+	ErrUnauthenticated = apperror.New(apperror.CodeUnauthenticated, apperror.MsgUnauthenticated)
+	ErrForbidden       = apperror.New(apperror.CodeForbidden, apperror.MsgForbidden)
+)
+
+// Identity é o usuário autenticado da requisição.
+type Identity struct {
+	UserID  uint
+	AgentID uint
+	Role    string
+}
+
+// IsAdmin informa se a identidade é de um administrador da plataforma.
+func (i Identity) IsAdmin() bool {
+	return i.Role == RoleAdmin
+}
+
+type contextKey struct{}
+
+// WithIdentity retorna um contexto carregando a identidade autenticada.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext retorna a identidade autenticada, se houver.
+func FromContext(ctx context.Context) (Identity, bool) {
+	if ctx == nil {
+		return Identity{}, false
+	}
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
+
+// RequireAuth retorna a identidade autenticada ou ErrUnauthenticated.
+func RequireAuth(ctx context.Context) (Identity, error) {
+	identity, ok := FromContext(ctx)
+	if !ok {
+		return Identity{}, ErrUnauthenticated
+	}
+	return identity, nil
+}
+
+// RequireAdmin garante que a requisição foi feita por um administrador da plataforma.
+func RequireAdmin(ctx context.Context) (Identity, error) {
+	identity, err := RequireAuth(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	if !identity.IsAdmin() {
+		return Identity{}, ErrForbidden
+	}
+	return identity, nil
+}
+
+// RequireAgent garante que a requisição foi feita por um usuário do agente informado ou
+// por um administrador da plataforma.
+func RequireAgent(ctx context.Context, agentID uint) (Identity, error) {
+	identity, err := RequireAuth(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	if identity.AgentID != agentID && !identity.IsAdmin() {
+		return Identity{}, ErrForbidden
+	}
+	return identity, nil
+}
@@ -0,0 +1,214 @@
+/*
+|------------------------------------------------
+| File: internal/storage/local.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalRoute é a rota em que a API serve os arquivos do store local.
+const LocalRoute = "/files"
+
+// Local guarda os arquivos num diretório do servidor, para desenvolvimento e testes.
+type Local struct {
+	dir       string
+	publicURL string
+	secret    []byte // Assina as URLs de PresignPut.
+}
+
+// NewLocal cria o store em dir. publicURL é o endereço da rota estática que serve dir.
+// Sem secret, as URLs assinadas usam uma chave aleatória e deixam de valer ao reiniciar.
+func NewLocal(dir, publicURL, secret string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &Local{dir: dir, publicURL: publicURL, secret: key}, nil
+}
+
+// Dir é o diretório a ser servido em LocalRoute.
+func (l *Local) Dir() string {
+	return l.dir
+}
+
+func (l *Local) Put(key, contentType string, body io.Reader) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Grava num temporário e renomeia, para ninguém ler um arquivo pela metade.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *Local) Get(key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (l *Local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	return joinURL(l.publicURL, key)
+}
+
+func (l *Local) Exists(key string) (bool, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *Local) Stat(key string) (ObjectInfo, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	// O disco não guarda o Content-Type; a extensão é o que o servidor estático usa.
+	return ObjectInfo{Size: info.Size(), ContentType: mime.TypeByExtension(filepath.Ext(path))}, nil
+}
+
+// PresignPut gera uma URL de PUT na própria rota LocalRoute, assinada com HMAC, que
+// UploadHandler aceita. Equivale à URL assinada do S3 para testar o envio direto sem
+// nenhum serviço externo.
+func (l *Local) PresignPut(key, contentType string, size int64, expires time.Duration) (PresignedPut, error) {
+	if _, err := l.path(key); err != nil {
+		return PresignedPut{}, err
+	}
+	expiresAt := time.Now().Add(expires)
+	query := url.Values{}
+	query.Set("size", strconv.FormatInt(size, 10))
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", l.sign(key, contentType, size, expiresAt.Unix()))
+	return PresignedPut{
+		URL:       l.URL(key) + "?" + query.Encode(),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (l *Local) sign(key, contentType string, size, expires int64) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "PUT\n%s\n%s\n%d\n%d", key, contentType, size, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// UploadHandler recebe os PUT das URLs geradas por PresignPut. Deve ser servido em
+// LocalRoute/*, a mesma rota dos arquivos.
+func (l *Local) UploadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, LocalRoute+"/")
+		query := r.URL.Query()
+		size, sizeErr := strconv.ParseInt(query.Get("size"), 10, 64)
+		expires, expiresErr := strconv.ParseInt(query.Get("expires"), 10, 64)
+		contentType := r.Header.Get("Content-Type")
+		if sizeErr != nil || expiresErr != nil || r.Method != http.MethodPut ||
+			!hmac.Equal([]byte(query.Get("signature")), []byte(l.sign(key, contentType, size, expires))) {
+			http.Error(w, "assinatura inválida", http.StatusForbidden)
+			return
+		}
+		if time.Now().Unix() > expires {
+			http.Error(w, "URL expirada", http.StatusForbidden)
+			return
+		}
+		if r.ContentLength >= 0 && r.ContentLength != size {
+			http.Error(w, "tamanho diferente do assinado", http.StatusBadRequest)
+			return
+		}
+
+		// Lê um byte além do assinado para perceber corpos maiores sem Content-Length.
+		data, err := io.ReadAll(io.LimitReader(r.Body, size+1))
+		if err != nil {
+			http.Error(w, "falha ao ler o corpo", http.StatusBadRequest)
+			return
+		}
+		if int64(len(data)) != size {
+			http.Error(w, "tamanho diferente do assinado", http.StatusBadRequest)
+			return
+		}
+		if err := l.Put(key, contentType, bytes.NewReader(data)); err != nil {
+			http.Error(w, "falha ao gravar", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// path converte a chave num caminho dentro de dir, recusando chaves que escapariam dele.
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash("/" + key))
+	if key == "" || strings.HasSuffix(key, "/") || clean == string(filepath.Separator) {
+		return "", fmt.Errorf("chave inválida: %q", key)
+	}
+	return filepath.Join(l.dir, clean), nil
+}
@@ -0,0 +1,84 @@
+/*
+|------------------------------------------------
+| File: internal/storage/memory.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package storage
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Memory guarda os arquivos em memória. Serve para testes e para rodar a API sem
+// nenhum armazenamento externo; o conteúdo se perde ao reiniciar.
+type Memory struct {
+	publicURL string
+
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+}
+
+func NewMemory(publicURL string) *Memory {
+	if publicURL == "" {
+		publicURL = "memory://"
+	}
+	return &Memory{publicURL: publicURL, objects: map[string]memoryObject{}}
+}
+
+func (m *Memory) Put(key, contentType string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = memoryObject{data: data, contentType: contentType}
+	return nil
+}
+
+func (m *Memory) Get(key string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *Memory) URL(key string) string {
+	return joinURL(m.publicURL, key)
+}
+
+func (m *Memory) Exists(key string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+func (m *Memory) Stat(key string) (ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return ObjectInfo{}, ErrNotFound
+	}
+	return ObjectInfo{Size: int64(len(object.data)), ContentType: object.contentType}, nil
+}
@@ -0,0 +1,25 @@
+/*
+|------------------------------------------------
+| File: internal/storage/presign.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package storage
+
+import "time"
+
+// Presigner é implementado pelos stores que aceitam que o cliente envie o arquivo
+// direto, sem passar pela API.
+type Presigner interface {
+	// PresignPut gera uma URL que aceita, até expires, um único tipo de conteúdo e tamanho.
+	PresignPut(key, contentType string, size int64, expires time.Duration) (PresignedPut, error)
+}
+
+// PresignedPut é a requisição que o cliente deve fazer para enviar o arquivo.
+type PresignedPut struct {
+	URL       string
+	Method    string
+	Headers   map[string]string // Devem ser enviados exatamente como estão.
+	ExpiresAt time.Time
+}
@@ -0,0 +1,157 @@
+/*
+|------------------------------------------------
+| File: internal/storage/s3.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Config configura o bucket. As credenciais vêm da cadeia padrão da AWS (variáveis
+// AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, perfil ou papel da instância).
+type S3Config struct {
+	Bucket         string
+	Region         string
+	Endpoint       string // Serviço compatível com S3 (MinIO, R2...). Vazio usa a AWS.
+	PublicURL      string // Base das URLs públicas, ex.: um CDN. Vazio usa o endereço do bucket.
+	ForcePathStyle bool   // bucket no caminho (endpoint/bucket/chave), exigido pelo MinIO.
+}
+
+type s3Store struct {
+	config   S3Config
+	client   *s3.S3
+	uploader *s3manager.Uploader
+}
+
+func NewS3(config S3Config) (Store, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("AWS_S3_BUCKET não configurado")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	awsConfig := &aws.Config{
+		Region:           aws.String(config.Region),
+		S3ForcePathStyle: aws.Bool(config.ForcePathStyle),
+	}
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{config: config, client: s3.New(sess), uploader: s3manager.NewUploader(sess)}, nil
+}
+
+func (s *s3Store) Put(key, contentType string, body io.Reader) error {
+	_, err := s.uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+func (s *s3Store) Get(key string) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (s *s3Store) Delete(key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *s3Store) URL(key string) string {
+	switch {
+	case s.config.PublicURL != "":
+		return joinURL(s.config.PublicURL, key)
+	case s.config.Endpoint != "":
+		return joinURL(s.config.Endpoint, s.config.Bucket+"/"+key)
+	default:
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.config.Bucket, s.config.Region, key)
+	}
+}
+
+func (s *s3Store) Exists(key string) (bool, error) {
+	_, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *s3Store) Stat(key string) (ObjectInfo, error) {
+	output, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Size: aws.Int64Value(output.ContentLength), ContentType: aws.StringValue(output.ContentType)}, nil
+}
+
+// PresignPut assina um PutObject com o tipo e o tamanho fixados: o S3 recusa o envio se
+// o cliente mandar outro Content-Type ou outro número de bytes.
+func (s *s3Store) PresignPut(key, contentType string, size int64, expires time.Duration) (PresignedPut, error) {
+	request, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:        aws.String(s.config.Bucket),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	})
+	url, signed, err := request.PresignRequest(expires)
+	if err != nil {
+		return PresignedPut{}, err
+	}
+	headers := map[string]string{}
+	for name := range signed {
+		if name != "Host" {
+			headers[name] = signed.Get(name)
+		}
+	}
+	return PresignedPut{URL: url, Method: http.MethodPut, Headers: headers, ExpiresAt: time.Now().Add(expires)}, nil
+}
+
+func isNotFound(err error) bool {
+	var requestErr awserr.RequestFailure
+	if errors.As(err, &requestErr) {
+		return requestErr.StatusCode() == http.StatusNotFound
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+/*
+|------------------------------------------------
+| File: internal/storage/storage.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound é retornado por Get e Stat quando não há objeto na chave.
+var ErrNotFound = errors.New("objeto não encontrado")
+
+// Store guarda os arquivos enviados (imagens de produtos, etc.). As chaves usam "/" como
+// separador, independentemente do backend.
+type Store interface {
+	Put(key, contentType string, body io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// URL é o endereço público do objeto.
+	URL(key string) string
+	Exists(key string) (bool, error)
+	Stat(key string) (ObjectInfo, error)
+}
+
+// ObjectInfo são os metadados de um objeto gravado.
+type ObjectInfo struct {
+	Size        int64
+	ContentType string // Informado por quem gravou; não é conferido pelo store.
+}
+
+// KeyFromURL devolve a chave de uma URL gerada pelo store. URLs externas (imagens
+// cadastradas à mão, outro bucket) não pertencem ao store.
+func KeyFromURL(store Store, url string) (string, bool) {
+	base := store.URL("")
+	if url == "" || !strings.HasPrefix(url, base) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, base), true
+}
+
+// FromEnv cria o store escolhido em STORAGE_DRIVER: "s3" (padrão), "local" ou "memory".
+func FromEnv() (Store, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "s3":
+		forcePathStyle, _ := strconv.ParseBool(os.Getenv("AWS_S3_FORCE_PATH_STYLE"))
+		return NewS3(S3Config{
+			Bucket:         os.Getenv("AWS_S3_BUCKET"),
+			Region:         os.Getenv("AWS_REGION"),
+			Endpoint:       os.Getenv("AWS_S3_ENDPOINT"),
+			PublicURL:      os.Getenv("STORAGE_PUBLIC_URL"),
+			ForcePathStyle: forcePathStyle,
+		})
+	case "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		publicURL := os.Getenv("STORAGE_PUBLIC_URL")
+		if publicURL == "" {
+			port := os.Getenv("API_PORT")
+			if port == "" {
+				port = "8080"
+			}
+			publicURL = "http://localhost:" + port + LocalRoute
+		}
+		local, err := NewLocal(dir, publicURL, os.Getenv("STORAGE_LOCAL_SECRET"))
+		if err != nil {
+			return nil, err
+		}
+		return local, nil
+	case "memory":
+		return NewMemory(os.Getenv("STORAGE_PUBLIC_URL")), nil
+	default:
+		return nil, fmt.Errorf("STORAGE_DRIVER desconhecido: %q", driver)
+	}
+}
+
+// joinURL junta a URL base e a chave com uma única barra.
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
@@ -0,0 +1,46 @@
+/*
+|------------------------------------------------
+| File: internal/trash/purge.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package trash
+
+import (
+	"log"
+	"time"
+)
+
+// Purger é implementado pelos serviços que mantêm registros na lixeira.
+type Purger interface {
+	PurgeTrash(before time.Time) (int64, error)
+}
+
+// StartPurgeJob remove definitivamente, a cada interval, os registros que estão
+// na lixeira há mais de retention. A primeira execução acontece imediatamente.
+func StartPurgeJob(retention, interval time.Duration, purgers map[string]Purger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			Purge(retention, purgers)
+			<-ticker.C
+		}
+	}()
+}
+
+// Purge executa uma única limpeza da lixeira.
+func Purge(retention time.Duration, purgers map[string]Purger) {
+	before := time.Now().Add(-retention)
+	for name, purger := range purgers {
+		purged, err := purger.PurgeTrash(before)
+		if err != nil {
+			log.Printf("Falha ao limpar a lixeira de %s: %v", name, err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Lixeira de %s: %d registros removidos definitivamente", name, purged)
+		}
+	}
+}
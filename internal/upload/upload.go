@@ -0,0 +1,94 @@
+/*
+|------------------------------------------------
+| File: internal/upload/upload.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package upload
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+)
+
+// placeholderPrefix marca, nas variáveis reescritas pelo middleware multipart, a posição
+// de um arquivo. O arquivo em si fica no contexto da requisição.
+const placeholderPrefix = "upload:"
+
+// File é um arquivo recebido numa requisição multipart.
+type File struct {
+	Filename    string
+	ContentType string // Informado pelo cliente; não é confiável.
+	Size        int64
+	header      *multipart.FileHeader
+}
+
+// Open abre o conteúdo do arquivo.
+func (f File) Open() (io.ReadCloser, error) {
+	return f.header.Open()
+}
+
+// NewFile embrulha um arquivo do formulário multipart.
+func NewFile(header *multipart.FileHeader) File {
+	return File{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        header.Size,
+		header:      header,
+	}
+}
+
+// Placeholder é o valor colocado nas variáveis no lugar do arquivo enviado no campo name.
+func Placeholder(name string) string {
+	return placeholderPrefix + name
+}
+
+type reference string
+
+type filesKey struct{}
+
+// WithFiles retorna um contexto com os arquivos da requisição, indexados pelo nome do
+// campo multipart.
+func WithFiles(ctx context.Context, files map[string]File) context.Context {
+	return context.WithValue(ctx, filesKey{}, files)
+}
+
+// FromArgs retorna o arquivo referenciado pelo argumento Upload de um resolver.
+func FromArgs(ctx context.Context, field string, value interface{}) (File, error) {
+	ref, ok := value.(reference)
+	if !ok {
+		return File{}, apperror.Invalid(field, apperror.MsgRequired)
+	}
+	files, _ := ctx.Value(filesKey{}).(map[string]File)
+	file, ok := files[string(ref)]
+	if !ok {
+		return File{}, apperror.Invalid(field, apperror.MsgRequired)
+	}
+	return file, nil
+}
+
+// Scalar é o tipo Upload da especificação de requisições multipart do GraphQL. Só pode
+// ser enviado por variável.
+var Scalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Upload",
+	Description: "Arquivo enviado numa requisição multipart (graphql-multipart-request-spec).",
+	Serialize: func(value interface{}) interface{} {
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if text, ok := value.(string); ok && strings.HasPrefix(text, placeholderPrefix) {
+			return reference(strings.TrimPrefix(text, placeholderPrefix))
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
@@ -0,0 +1,205 @@
+/*
+|------------------------------------------------
+| File: internal/validate/rules.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package validate
+
+import (
+	"errors"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+)
+
+// Required recusa textos vazios ou só com espaços.
+func Required(value string) *Failure {
+	if strings.TrimSpace(value) == "" {
+		return &Failure{Key: apperror.MsgRequired}
+	}
+	return nil
+}
+
+// MaxLength limita o texto a max caracteres (não bytes).
+func MaxLength(max int) Rule[string] {
+	return func(value string) *Failure {
+		if utf8.RuneCountInString(value) > max {
+			return &Failure{Key: apperror.MsgMaxLength, Args: []interface{}{max}}
+		}
+		return nil
+	}
+}
+
+// MinLength exige pelo menos min caracteres.
+func MinLength(min int) Rule[string] {
+	return func(value string) *Failure {
+		if utf8.RuneCountInString(value) < min {
+			return &Failure{Key: apperror.MsgMinLength, Args: []interface{}{min}}
+		}
+		return nil
+	}
+}
+
+// URL aceita endereços http(s) absolutos. Texto vazio é aceito; combine com Required
+// se o campo for obrigatório.
+func URL(value string) *Failure {
+	if value == "" {
+		return nil
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return &Failure{Key: apperror.MsgInvalidURL}
+	}
+	return nil
+}
+
+// Email aceita apenas o endereço, sem nome ("Fulano <a@b.com>" é recusado).
+func Email(value string) *Failure {
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value || !strings.Contains(value[strings.LastIndex(value, "@"):], ".") {
+		return &Failure{Key: apperror.MsgInvalidEmail}
+	}
+	return nil
+}
+
+var domainPattern = regexp.MustCompile(`^(?i)([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// Domain aceita nomes de domínio como "loja.com.br", sem esquema, porta ou caminho.
+func Domain(value string) *Failure {
+	if len(value) > 253 || !domainPattern.MatchString(value) {
+		return &Failure{Key: apperror.MsgInvalidDomain}
+	}
+	return nil
+}
+
+// Money exige um decimal válido numa moeda suportada, sem mais casas decimais do que
+// a moeda admite (10.999 em reais é recusado, e não arredondado).
+func Money(value money.Input) *Failure {
+	_, err := value.Parse()
+	var decimals *money.DecimalsError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, money.ErrUnknownCurrency):
+		return &Failure{Key: apperror.MsgUnknownCurrency, Args: []interface{}{strings.Join(money.Currencies(), ", ")}}
+	case errors.As(err, &decimals):
+		return &Failure{Key: apperror.MsgMoneyDecimals, Args: []interface{}{decimals.Currency, decimals.Max}}
+	default:
+		return &Failure{Key: apperror.MsgInvalidMoney}
+	}
+}
+
+// PositiveMoney exige um valor monetário maior que zero. Valores que não podem ser
+// lidos passam; combine com Money.
+func PositiveMoney(value money.Input) *Failure {
+	if parsed, err := value.Parse(); err == nil && parsed.Amount <= 0 {
+		return &Failure{Key: apperror.MsgPositiveAmount}
+	}
+	return nil
+}
+
+// GTIN aceita vazio ou um código de barras EAN/GTIN (GTIN-8, UPC-A, EAN-13 ou GTIN-14)
+// com o dígito verificador correto.
+func GTIN(value string) *Failure {
+	if value == "" {
+		return nil
+	}
+	switch len(value) {
+	case 8, 12, 13, 14:
+	default:
+		return &Failure{Key: apperror.MsgInvalidBarcode}
+	}
+	// Da direita para a esquerda, sem o verificador, os dígitos pesam 3, 1, 3, 1...
+	sum := 0
+	for i := len(value) - 2; i >= 0; i-- {
+		digit := int(value[i] - '0')
+		if digit < 0 || digit > 9 {
+			return &Failure{Key: apperror.MsgInvalidBarcode}
+		}
+		if (len(value)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	if check := int(value[len(value)-1] - '0'); check != (10-sum%10)%10 {
+		return &Failure{Key: apperror.MsgInvalidBarcode}
+	}
+	return nil
+}
+
+// NonNegative recusa números negativos.
+func NonNegative[T int | int64](value T) *Failure {
+	if value < 0 {
+		return &Failure{Key: apperror.MsgNonNegative}
+	}
+	return nil
+}
+
+// Positive exige um número maior que zero.
+func Positive[T int | int64](value T) *Failure {
+	if value <= 0 {
+		return &Failure{Key: apperror.MsgPositiveAmount}
+	}
+	return nil
+}
+
+// NotZero exige um ID preenchido.
+func NotZero(value uint) *Failure {
+	if value == 0 {
+		return &Failure{Key: apperror.MsgRequired}
+	}
+	return nil
+}
+
+// OneOf aceita apenas os valores listados.
+func OneOf(allowed ...string) Rule[string] {
+	return func(value string) *Failure {
+		for _, option := range allowed {
+			if value == option {
+				return nil
+			}
+		}
+		return &Failure{Key: apperror.MsgOneOf, Args: []interface{}{strings.Join(allowed, ", ")}}
+	}
+}
+
+// Each aplica as regras a cada item de uma lista.
+func Each(rules ...Rule[string]) Rule[[]string] {
+	return func(values []string) *Failure {
+		for _, value := range values {
+			for _, rule := range rules {
+				if failure := rule(value); failure != nil {
+					return failure
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// MaxItems limita a quantidade de itens de uma lista.
+func MaxItems[T any](max int) Rule[[]T] {
+	return func(values []T) *Failure {
+		if len(values) > max {
+			return &Failure{Key: apperror.MsgMaxItems, Args: []interface{}{max}}
+		}
+		return nil
+	}
+}
+
+// ExclusiveWith recusa o campo, quando enviado, se o campo other também foi.
+func ExclusiveWith(other string, otherSet bool) Rule[bool] {
+	return func(set bool) *Failure {
+		if set && otherSet {
+			return &Failure{Key: apperror.MsgExclusiveWith, Args: []interface{}{other}}
+		}
+		return nil
+	}
+}
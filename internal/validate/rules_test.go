@@ -0,0 +1,153 @@
+/*
+|------------------------------------------------
+| File: internal/validate/rules_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/money"
+)
+
+// ruleCase é um caso das tabelas: valor de entrada e a chave esperada ("" quando passa).
+type ruleCase[T any] struct {
+	name  string
+	value T
+	key   string
+}
+
+func runRule[T any](t *testing.T, rule Rule[T], cases []ruleCase[T]) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			failure := rule(tc.value)
+			switch {
+			case tc.key == "" && failure != nil:
+				t.Fatalf("esperava passar, falhou com %q", failure.Key)
+			case tc.key != "" && failure == nil:
+				t.Fatalf("esperava %q, passou", tc.key)
+			case tc.key != "" && failure.Key != tc.key:
+				t.Fatalf("esperava %q, veio %q", tc.key, failure.Key)
+			}
+		})
+	}
+}
+
+func TestRequired(t *testing.T) {
+	runRule(t, Required, []ruleCase[string]{
+		{"texto", "pizza", ""},
+		{"vazio", "", apperror.MsgRequired},
+		{"só espaços", " \t\n", apperror.MsgRequired},
+		{"espaço em volta", "  a  ", ""},
+	})
+}
+
+func TestMaxLength(t *testing.T) {
+	runRule(t, MaxLength(5), []ruleCase[string]{
+		{"vazio", "", ""},
+		{"no limite", "abcde", ""},
+		{"acima do limite", "abcdef", apperror.MsgMaxLength},
+		{"acentos contam como um caractere", "açaís", ""},
+		{"emoji conta como um caractere", "🍕🍕🍕🍕🍕", ""},
+		{"acentos acima do limite", "açaíss", apperror.MsgMaxLength},
+	})
+
+	if failure := MaxLength(3)("abcd"); failure == nil || len(failure.Args) != 1 || failure.Args[0] != 3 {
+		t.Fatalf("esperava o limite nos argumentos, veio %+v", failure)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	runRule(t, Email, []ruleCase[string]{
+		{"simples", "ana@loja.com", ""},
+		{"subdomínio", "ana.silva@mail.loja.com.br", ""},
+		{"com mais", "ana+pedidos@loja.com", ""},
+		{"vazio", "", apperror.MsgInvalidEmail},
+		{"sem arroba", "ana.loja.com", apperror.MsgInvalidEmail},
+		{"sem ponto no domínio", "ana@localhost", apperror.MsgInvalidEmail},
+		{"com nome", "Ana <ana@loja.com>", apperror.MsgInvalidEmail},
+		{"espaço", "ana @loja.com", apperror.MsgInvalidEmail},
+		{"dois arrobas", "ana@@loja.com", apperror.MsgInvalidEmail},
+	})
+}
+
+func TestDomain(t *testing.T) {
+	runRule(t, Domain, []ruleCase[string]{
+		{"simples", "loja.com", ""},
+		{"vários níveis", "pedidos.loja.com.br", ""},
+		{"maiúsculas", "Loja.COM", ""},
+		{"hífen no meio", "minha-loja.com", ""},
+		{"vazio", "", apperror.MsgInvalidDomain},
+		{"sem TLD", "loja", apperror.MsgInvalidDomain},
+		{"com esquema", "https://loja.com", apperror.MsgInvalidDomain},
+		{"com porta", "loja.com:8080", apperror.MsgInvalidDomain},
+		{"com caminho", "loja.com/menu", apperror.MsgInvalidDomain},
+		{"hífen no começo", "-loja.com", apperror.MsgInvalidDomain},
+		{"rótulo longo demais", strings.Repeat("a", 64) + ".com", apperror.MsgInvalidDomain},
+		{"nome longo demais", strings.Repeat("abcdefghi.", 26) + "com", apperror.MsgInvalidDomain},
+	})
+}
+
+func TestURL(t *testing.T) {
+	runRule(t, URL, []ruleCase[string]{
+		{"vazio passa", "", ""},
+		{"https", "https://cdn.loja.com/img/pizza.jpg", ""},
+		{"http com porta", "http://localhost:8080/a.png", ""},
+		{"sem esquema", "cdn.loja.com/pizza.jpg", apperror.MsgInvalidURL},
+		{"relativa", "/img/pizza.jpg", apperror.MsgInvalidURL},
+		{"ftp", "ftp://loja.com/a.png", apperror.MsgInvalidURL},
+		{"javascript", "javascript:alert(1)", apperror.MsgInvalidURL},
+		{"sem host", "https://", apperror.MsgInvalidURL},
+	})
+}
+
+func TestGTIN(t *testing.T) {
+	runRule(t, GTIN, []ruleCase[string]{
+		{"vazio passa", "", ""},
+		{"EAN-13", "4006381333931", ""},
+		{"EAN-13 brasileiro", "7891000315507", ""},
+		{"UPC-A", "036000291452", ""},
+		{"EAN-8", "96385074", ""},
+		{"GTIN-14", "10614141000415", ""},
+		{"dígito verificador zero", "0000000000000", ""},
+		{"EAN-13 com verificador errado", "4006381333932", apperror.MsgInvalidBarcode},
+		{"UPC-A com verificador errado", "036000291453", apperror.MsgInvalidBarcode},
+		{"EAN-8 com verificador errado", "96385075", apperror.MsgInvalidBarcode},
+		{"letra no meio", "40063813339a1", apperror.MsgInvalidBarcode},
+		{"letra no verificador", "400638133393x", apperror.MsgInvalidBarcode},
+		{"tamanho inválido", "12345", apperror.MsgInvalidBarcode},
+		{"11 dígitos", "03600029145", apperror.MsgInvalidBarcode},
+		{"espaços", "4006381 33393", apperror.MsgInvalidBarcode},
+	})
+}
+
+func TestPositiveMoney(t *testing.T) {
+	runRule(t, PositiveMoney, []ruleCase[money.Input]{
+		{"positivo", money.Input{Amount: "10.90", Currency: "BRL"}, ""},
+		{"um centavo", money.Input{Amount: "0.01", Currency: "BRL"}, ""},
+		{"inteiro sem casas", money.Input{Amount: "1500", Currency: "CLP"}, ""},
+		{"zero", money.Input{Amount: "0", Currency: "BRL"}, apperror.MsgPositiveAmount},
+		{"zero com casas", money.Input{Amount: "0.00", Currency: "USD"}, apperror.MsgPositiveAmount},
+		{"negativo", money.Input{Amount: "-5.00", Currency: "BRL"}, apperror.MsgPositiveAmount},
+		// Valores ilegíveis ficam para a regra Money.
+		{"ilegível passa", money.Input{Amount: "dez", Currency: "BRL"}, ""},
+		{"moeda desconhecida passa", money.Input{Amount: "-1", Currency: "XXX"}, ""},
+	})
+}
+
+func TestMoney(t *testing.T) {
+	runRule(t, Money, []ruleCase[money.Input]{
+		{"reais", money.Input{Amount: "10.90", Currency: "BRL"}, ""},
+		{"moeda desconhecida", money.Input{Amount: "10", Currency: "XXX"}, apperror.MsgUnknownCurrency},
+		{"casas demais", money.Input{Amount: "10.999", Currency: "BRL"}, apperror.MsgMoneyDecimals},
+		{"casas num iene", money.Input{Amount: "10.5", Currency: "JPY"}, apperror.MsgMoneyDecimals},
+		{"texto", money.Input{Amount: "dez", Currency: "BRL"}, apperror.MsgInvalidMoney},
+		{"vazio", money.Input{Amount: "", Currency: "BRL"}, apperror.MsgInvalidMoney},
+	})
+}
@@ -0,0 +1,63 @@
+/*
+|------------------------------------------------
+| File: internal/validate/validate.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package validate
+
+import (
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
+
+// Failure é o motivo de um valor ser recusado: a chave da mensagem no catálogo do
+// apperror e seus argumentos.
+type Failure struct {
+	Key  string
+	Args []interface{}
+}
+
+// Rule valida um valor, retornando nil se ele for aceito.
+type Rule[T any] func(value T) *Failure
+
+// Check é a validação de um campo, pronta para ser executada por All.
+type Check func() *apperror.FieldError
+
+// Field aplica as regras, na ordem, ao valor do campo. Só o primeiro erro do campo é
+// reportado.
+func Field[T any](name string, value T, rules ...Rule[T]) Check {
+	return func() *apperror.FieldError {
+		for _, rule := range rules {
+			if failure := rule(value); failure != nil {
+				return &apperror.FieldError{Field: name, Key: failure.Key, Args: failure.Args}
+			}
+		}
+		return nil
+	}
+}
+
+// Patch valida um campo de atualização parcial. Campos não enviados são ignorados;
+// null é validado como o valor zero, que é o que será gravado.
+func Patch[T any](name string, field patch.Field[T], rules ...Rule[T]) Check {
+	if !field.Set {
+		return func() *apperror.FieldError { return nil }
+	}
+	return Field(name, field.Value, rules...)
+}
+
+// All executa todas as validações e retorna um único erro VALIDATION com todos os
+// campos inválidos, ou nil.
+func All(checks ...Check) error {
+	var fields []apperror.FieldError
+	for _, check := range checks {
+		if fieldErr := check(); fieldErr != nil {
+			fields = append(fields, *fieldErr)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return apperror.Validation(fields...)
+}
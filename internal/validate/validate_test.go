@@ -0,0 +1,80 @@
+/*
+|------------------------------------------------
+| File: internal/validate/validate_test.go
+| Developer: Raimundo Coelho
+| GitHub: https://github.com/raimundocoelho-ti
+| ------------------------------------------------
+*/
+package validate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/apperror"
+	"github.com/raimundocoelho-ti/sabiosystem-api/internal/patch"
+)
+
+func TestAllCollectsEveryInvalidField(t *testing.T) {
+	err := All(
+		Field("name", "", Required),
+		Field("email", "ana@loja.com", Email),
+		Field("website", "loja.com", URL),
+		Field("position", -1, NonNegative[int]),
+	)
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperror.CodeValidation {
+		t.Fatalf("esperava um erro VALIDATION, veio %v", err)
+	}
+	want := []apperror.FieldError{
+		{Field: "name", Key: apperror.MsgRequired},
+		{Field: "website", Key: apperror.MsgInvalidURL},
+		{Field: "position", Key: apperror.MsgNonNegative},
+	}
+	if len(appErr.Fields) != len(want) {
+		t.Fatalf("esperava %d campos, vieram %+v", len(want), appErr.Fields)
+	}
+	for i, field := range want {
+		if appErr.Fields[i].Field != field.Field || appErr.Fields[i].Key != field.Key {
+			t.Errorf("campo %d: esperava %s/%s, veio %s/%s", i, field.Field, field.Key, appErr.Fields[i].Field, appErr.Fields[i].Key)
+		}
+	}
+}
+
+func TestAllWithoutFailures(t *testing.T) {
+	if err := All(Field("name", "Pizza", Required), Field("tags", []string{"a"}, MaxItems[string](2))); err != nil {
+		t.Fatalf("esperava nil, veio %v", err)
+	}
+}
+
+func TestFieldStopsAtFirstFailingRule(t *testing.T) {
+	// Vazio falha em Required e em Email; só a primeira aparece.
+	failure := Field("email", "", Required, Email)()
+	if failure == nil || failure.Key != apperror.MsgRequired {
+		t.Fatalf("esperava %q, veio %+v", apperror.MsgRequired, failure)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		field patch.Field[string]
+		key   string
+	}{
+		{"ausente não valida", patch.Field[string]{}, ""},
+		{"null valida o valor zero", patch.Field[string]{Set: true, Null: true}, apperror.MsgRequired},
+		{"valor válido", patch.Of("Pizza"), ""},
+		{"valor inválido", patch.Of("  "), apperror.MsgRequired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			failure := Patch("name", tc.field, Required)()
+			switch {
+			case tc.key == "" && failure != nil:
+				t.Fatalf("esperava passar, falhou com %q", failure.Key)
+			case tc.key != "" && (failure == nil || failure.Key != tc.key):
+				t.Fatalf("esperava %q, veio %+v", tc.key, failure)
+			}
+		})
+	}
+}